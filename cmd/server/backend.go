@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/auth"
+	"github.com/lucaspichi06/xepelin-bank/internal/config"
+	"github.com/lucaspichi06/xepelin-bank/internal/health"
+	"github.com/lucaspichi06/xepelin-bank/internal/idempotency"
+	"github.com/lucaspichi06/xepelin-bank/internal/ledger"
+	"github.com/lucaspichi06/xepelin-bank/internal/monitoring"
+	"github.com/lucaspichi06/xepelin-bank/internal/signing"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/transaction"
+)
+
+// backend is where the server stores its data, picked by the database driver
+type backend struct {
+	// db is nil when everything is kept in memory
+	db               *sql.DB
+	unitOfWork       transaction.UnitOfWork
+	accounts         account.Repository
+	accountEvents    account.EventStore
+	accountSnapshots account.SnapshotStore
+	auth             auth.Repository
+	ledger           ledger.Repository
+	idempotency      idempotency.Store
+	nonces           signing.NonceStore
+	monitoring       monitoring.Repository
+	// checks tell whether the database is ready
+	checks map[string]health.Check
+}
+
+func newBackend(cfg config.Database) (backend, error) {
+	if cfg.Driver == config.DriverMemory {
+		return newMemoryBackend(), nil
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return backend{}, err
+	}
+	migrator, err := newMigrator(db, cfg.Driver)
+	if err != nil {
+		db.Close()
+		return backend{}, err
+	}
+
+	// statements is where the repositories that run the same SQL everywhere run it
+	var statements store.DBTX = db
+	switch cfg.Driver {
+	case config.DriverPostgres:
+		statements = store.Postgres(db)
+	case config.DriverSQLite:
+		statements = store.SQLite(db)
+	}
+	b := backend{
+		db:               db,
+		unitOfWork:       transaction.NewUnitOfWork(db),
+		accounts:         account.NewRepository(db),
+		accountEvents:    account.NewEventStore(statements),
+		accountSnapshots: account.NewSnapshotStore(statements),
+		auth:             auth.NewRepository(statements),
+		ledger:           ledger.NewRepository(statements),
+		idempotency:      idempotency.NewStore(statements),
+		nonces:           signing.NewNonceStore(statements),
+		monitoring:       monitoring.NewRepository(statements),
+		checks: map[string]health.Check{
+			"database":   health.Database(db),
+			"migrations": migrator.Verify,
+		},
+	}
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		b.unitOfWork = transaction.NewSQLiteUnitOfWork(db)
+		b.accounts = account.NewSQLiteRepository(db)
+	case config.DriverPostgres:
+		b.unitOfWork = transaction.NewPostgresUnitOfWork(db)
+		b.accounts = account.NewPostgresRepository(db)
+	}
+	return b, nil
+}
+
+// newMemoryBackend keeps everything in memory, for development and tests. It has nothing
+// to check, so it is always ready
+func newMemoryBackend() backend {
+	b := backend{
+		accounts:         account.NewMemoryRepository(),
+		accountEvents:    account.NewMemoryEventStore(),
+		accountSnapshots: account.NewMemorySnapshotStore(),
+		auth:             auth.NewMemoryRepository(),
+		ledger:           ledger.NewMemoryRepository(),
+		idempotency:      idempotency.NewMemoryStore(),
+		nonces:           signing.NewMemoryNonceStore(),
+		checks:           map[string]health.Check{},
+	}
+	transactions := transaction.NewMemoryRepository()
+	b.monitoring = monitoring.NewMemoryRepository(transactions)
+	b.unitOfWork = transaction.NewMemoryUnitOfWork(store.NewMemory(), transaction.Tx{
+		Accounts:     account.NewService(b.accounts, b.accountEvents, b.accountSnapshots, account.DefaultSnapshotEvery),
+		Transactions: transactions,
+		Ledger:       ledger.NewService(b.ledger),
+		Auth:         auth.NewService(b.auth, ""),
+	})
+	return b
+}
+
+func (b backend) Close() error {
+	if b.db == nil {
+		return nil
+	}
+	return b.db.Close()
+}
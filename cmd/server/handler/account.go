@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -8,37 +9,52 @@ import (
 	acc "github.com/lucaspichi06/xepelin-bank/internal/account"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
 	"github.com/lucaspichi06/xepelin-bank/internal/events"
+	tran "github.com/lucaspichi06/xepelin-bank/internal/transaction"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/middleware"
 	"github.com/lucaspichi06/xepelin-bank/pkg/web"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type Accounts interface {
 	Create() gin.HandlerFunc
 	GetBalance() gin.HandlerFunc
+	Snapshot() gin.HandlerFunc
+	UpdateStatus() gin.HandlerFunc
+	List() gin.HandlerFunc
 }
 
 type account struct {
-	s acc.Service
+	s   acc.Service
+	uow tran.UnitOfWork
+	// tr sweeps the balance of the accounts closed with money left
+	tr tran.Service
 }
 
-func NewAccountHandler(s acc.Service) Accounts {
+func NewAccountHandler(s acc.Service, uow tran.UnitOfWork, tr tran.Service) Accounts {
 	return &account{
-		s: s,
+		s:   s,
+		uow: uow,
+		tr:  tr,
 	}
 }
 
 // Create	godoc
 // @Summary	Creates a new account
 // @Tags	Account
-// @Description	creates a new account with the received parameters
+// @Description	creates a new account with the received parameters. Accounts created by an account owner belong to it
 // @Accept	json
 // @Produce	json
 // @Param	token	header	string	true	"token"
 // @Param	transaction		body 	domain.AccountRequest	true	"Account to create"
 // @Success 201	{object}	web.Response
 // @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
 // @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
 // @Router	/accounts	[post]
 func (a account) Create() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -49,10 +65,22 @@ func (a account) Create() gin.HandlerFunc {
 			return
 		}
 
-		event := events.NewCreateAccountEvent(acc.Name, a.s)
+		p, _ := middleware.CurrentPrincipal(c)
 
-		newAcc, err := event.Process()
+		var newAcc domain.Account
+		err = a.uow.Do(c.Request.Context(), func(ctx context.Context, tx tran.Tx) error {
+			newAcc, err = events.Observed(events.NewCreateAccountEvent(acc.Name, strings.ToUpper(acc.Currency), tx.Accounts)).Process(ctx)
+			// the accounts of a bearer token are the ones in its claims, there is no principal to grant to
+			if err != nil || p.Role != domain.RoleAccountOwner || p.FromToken {
+				return err
+			}
+			return tx.Auth.Grant(ctx, p.ID, newAcc.ID)
+		})
 		if err != nil {
+			if errors.Is(err, custom_errors.ErrInvalidCurrency) {
+				web.Failure(c, http.StatusBadRequest, err)
+				return
+			}
 			web.Failure(c, http.StatusInternalServerError, err)
 			return
 		}
@@ -67,11 +95,15 @@ func (a account) Create() gin.HandlerFunc {
 // @Description	get the balance from an account
 // @Accept	json
 // @Produce	json
-// @Param	id		path	int		true	"Account ID"
+// @Param	token	header	string	true	"token"
+// @Param	id		path	string		true	"Account ID"
 // @Success	200	{object}	web.Response
 // @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
 // @Failure	404	{object}	web.ErrorResponse
 // @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
 // @Router	/accounts/{id}/balance	[get]
 func (a account) GetBalance() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -81,10 +113,13 @@ func (a account) GetBalance() gin.HandlerFunc {
 			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidID)
 			return
 		}
+		if !canRead(c, id) {
+			return
+		}
 
-		event := events.NewBalanceEvent(id, a.s)
+		event := events.Observed(events.NewBalanceEvent(id, a.s))
 
-		res, err := event.Process()
+		res, err := event.Process(c.Request.Context())
 		if err != nil {
 			if errors.Is(err, custom_errors.ErrNotFound) {
 				web.Failure(c, http.StatusNotFound, fmt.Errorf("account not found: %v", err))
@@ -96,3 +131,218 @@ func (a account) GetBalance() gin.HandlerFunc {
 		web.Success(c, http.StatusOK, res)
 	}
 }
+
+// Snapshot	godoc
+// @Summary	Takes a snapshot of an account
+// @Tags	Account
+// @Description	stores the current state of the account so loading it only replays the events recorded afterwards
+// @Accept	json
+// @Produce	json
+// @Param	token	header	string	true	"token"
+// @Param	id		path	string		true	"Account ID"
+// @Success	201	{object}	web.Response
+// @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	404	{object}	web.ErrorResponse
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/accounts/{id}/snapshot	[post]
+func (a account) Snapshot() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidID)
+			return
+		}
+		if !canOperate(c, id) {
+			return
+		}
+
+		// the account is locked while its snapshot is saved, so two requests never save
+		// the same version
+		var res domain.Account
+		err = a.uow.Do(c.Request.Context(), func(ctx context.Context, tx tran.Tx) error {
+			res, err = tx.Accounts.Snapshot(ctx, id)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, custom_errors.ErrNotFound) {
+				web.Failure(c, http.StatusNotFound, fmt.Errorf("account not found: %v", err))
+				return
+			}
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+		web.Success(c, http.StatusCreated, res)
+	}
+}
+
+// UpdateStatus	godoc
+// @Summary	Changes the status of an account
+// @Tags	Account
+// @Description	freezes, closes or reopens an account. No money can move in or out of frozen and closed accounts. Closing an account requires a zero balance, or a sweep destination its balance is transferred to
+// @Accept	json
+// @Produce	json
+// @Param	token	header	string	true	"token"
+// @Param	id		path	string		true	"Account ID"
+// @Param	status	body	domain.AccountStatusRequest	true	"Status of the account"
+// @Success	200	{object}	web.Response
+// @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	404	{object}	web.ErrorResponse
+// @Failure	409	{object}	web.ErrorResponse
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/accounts/{id}/status	[patch]
+func (a account) UpdateStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidID)
+			return
+		}
+		var req domain.AccountStatusRequest
+		if err = c.ShouldBindJSON(&req); err != nil {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidJSON)
+			return
+		}
+		if !canOperate(c, id) {
+			return
+		}
+
+		res, err := a.tr.UpdateStatus(c.Request.Context(), id, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, custom_errors.ErrNotFound):
+				web.Failure(c, http.StatusNotFound, fmt.Errorf("account not found: %v", err))
+			case errors.Is(err, custom_errors.ErrInvalidAccountStatus),
+				errors.Is(err, custom_errors.ErrInvalidSweep),
+				isAmountError(err):
+				web.Failure(c, http.StatusBadRequest, err)
+			case errors.Is(err, custom_errors.ErrInvalidStatusTransition),
+				errors.Is(err, custom_errors.ErrAccountNotEmpty),
+				isInactiveError(err):
+				web.Failure(c, http.StatusConflict, err)
+			default:
+				web.Failure(c, http.StatusInternalServerError, err)
+			}
+			return
+		}
+		web.Success(c, http.StatusOK, res)
+	}
+}
+
+// List	godoc
+// @Summary	List the accounts
+// @Tags	Account
+// @Description	lists the accounts sorted by name or balance. The balance range is in the currency filtered, which it requires
+// @Produce	json
+// @Param	token	header	string	true	"token"
+// @Param	name	query	string	false	"Name prefix, ignoring the case"
+// @Param	status	query	string	false	"Comma separated statuses (active, frozen, closed)"
+// @Param	currency	query	string	false	"ISO-4217 currency"
+// @Param	min_balance	query	number	false	"Minimum balance"
+// @Param	max_balance	query	number	false	"Maximum balance"
+// @Param	sort	query	string	false	"Sort by name (default) or balance, which requires a currency"
+// @Param	order	query	string	false	"asc (default) or desc"
+// @Param	cursor	query	string	false	"Cursor returned by the previous page"
+// @Param	limit	query	int	false	"Page size (default 20, max 100)"
+// @Success 200	{object}	web.Response{data=domain.AccountPage}
+// @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/accounts	[get]
+func (a account) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseAccountFilter(c)
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, err)
+			return
+		}
+
+		page, err := a.s.List(c.Request.Context(), filter)
+		if err != nil {
+			if errors.Is(err, custom_errors.ErrInvalidCursor) {
+				web.Failure(c, http.StatusBadRequest, err)
+				return
+			}
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+		web.Success(c, http.StatusOK, page)
+	}
+}
+
+func parseAccountFilter(c *gin.Context) (domain.AccountFilter, error) {
+	filter := domain.AccountFilter{
+		NamePrefix: c.Query("name"),
+		Currency:   strings.ToUpper(c.Query("currency")),
+		Sort:       domain.AccountSort(c.DefaultQuery("sort", string(domain.SortByName))),
+		Cursor:     c.Query("cursor"),
+	}
+
+	if statuses := c.Query("status"); statuses != "" {
+		for _, value := range strings.Split(statuses, ",") {
+			status := domain.AccountStatus(strings.TrimSpace(value))
+			if status != domain.AccountActive && status != domain.AccountFrozen && status != domain.AccountClosed {
+				return filter, custom_errors.ErrInvalidAccountStatus
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	if filter.Currency != "" {
+		if _, ok := domain.CurrencyExponent(filter.Currency); !ok {
+			return filter, custom_errors.ErrInvalidCurrency
+		}
+	}
+	var err error
+	if filter.MinBalance, err = queryBalance(c, "min_balance", filter.Currency); err != nil {
+		return filter, err
+	}
+	if filter.MaxBalance, err = queryBalance(c, "max_balance", filter.Currency); err != nil {
+		return filter, err
+	}
+
+	if filter.Sort != domain.SortByName && filter.Sort != domain.SortByBalance {
+		return filter, fmt.Errorf("%w: sort must be name or balance", custom_errors.ErrInvalidQuery)
+	}
+	// the balances of different currencies can not be compared
+	if filter.Sort == domain.SortByBalance && filter.Currency == "" {
+		return filter, fmt.Errorf("%w: sort by balance requires the currency query param", custom_errors.ErrInvalidQuery)
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, fmt.Errorf("%w: order must be asc or desc", custom_errors.ErrInvalidQuery)
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 || filter.Limit > acc.MaxPageSize {
+			return filter, fmt.Errorf("%w: limit must be between 1 and %d", custom_errors.ErrInvalidQuery, acc.MaxPageSize)
+		}
+	}
+	return filter, nil
+}
+
+// queryBalance parses a balance of the query in the currency filtered, which is required
+func queryBalance(c *gin.Context, key, currency string) (*domain.Money, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		return nil, fmt.Errorf("%w %s: the currency query param is required", custom_errors.ErrInvalidQuery, key)
+	}
+	m, err := domain.ParseMoney(value, currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", custom_errors.ErrInvalidQuery, key, err)
+	}
+	return &m, nil
+}
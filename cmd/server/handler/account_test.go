@@ -2,33 +2,97 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/auth"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	tran "github.com/lucaspichi06/xepelin-bank/internal/transaction"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/middleware"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 )
 
 type accountServiceMock struct {
-	create func(account domain.Account) error
-	read   func(id uuid.UUID) (domain.Account, error)
-	update func(account domain.Account) error
+	create        func(account domain.Account) error
+	read          func(id uuid.UUID) (domain.Account, error)
+	readForUpdate func(id uuid.UUID) (domain.Account, error)
+	record        func(account domain.Account, event domain.AccountEvent) (domain.Account, error)
+	rebuild       func(id uuid.UUID) (domain.Account, error)
+	snapshot      func(id uuid.UUID) (domain.Account, error)
+	list          func(filter domain.AccountFilter) (domain.AccountPage, error)
 }
 
-func (a accountServiceMock) Create(account domain.Account) error {
+func (a accountServiceMock) Create(ctx context.Context, account domain.Account) error {
 	return a.create(account)
 }
-func (a accountServiceMock) Read(id uuid.UUID) (domain.Account, error) {
+func (a accountServiceMock) Read(ctx context.Context, id uuid.UUID) (domain.Account, error) {
 	return a.read(id)
 }
-func (a accountServiceMock) Update(account domain.Account) error {
-	return a.update(account)
+func (a accountServiceMock) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.readForUpdate(id)
+}
+func (a accountServiceMock) Record(ctx context.Context, account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+	return a.record(account, event)
+}
+func (a accountServiceMock) Rebuild(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.rebuild(id)
+}
+
+func (a accountServiceMock) Snapshot(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.snapshot(id)
+}
+
+func (a accountServiceMock) List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	return a.list(filter)
+}
+
+type authServiceMock struct {
+	authenticate func(key string) (domain.Principal, error)
+	issue        func(req domain.APIKeyRequest) (domain.APIKey, error)
+	grant        func(principalID, accountID uuid.UUID) error
+}
+
+func (a authServiceMock) Authenticate(ctx context.Context, key string) (domain.Principal, error) {
+	return a.authenticate(key)
+}
+
+func (a authServiceMock) Issue(ctx context.Context, req domain.APIKeyRequest) (domain.APIKey, error) {
+	return a.issue(req)
+}
+
+func (a authServiceMock) Grant(ctx context.Context, principalID, accountID uuid.UUID) error {
+	return a.grant(principalID, accountID)
+}
+
+type uowMock struct {
+	accounts accountServiceMock
+	auth     authServiceMock
+}
+
+func (u uowMock) Do(ctx context.Context, fn func(ctx context.Context, tx tran.Tx) error) error {
+	return fn(ctx, tran.Tx{
+		Accounts: u.accounts,
+		Auth:     u.auth,
+	})
+}
+
+var admin = domain.Principal{ID: uuid.New(), Name: "admin", Role: domain.RoleAdmin}
+
+// authenticated sets the principal of the requests, as the authentication middleware does
+func authenticated(p domain.Principal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(middleware.PrincipalKey, p)
+	}
 }
 
 func TestAccountCreate(t *testing.T) {
@@ -38,9 +102,10 @@ func TestAccountCreate(t *testing.T) {
 				return nil
 			},
 		}
-		a := NewAccountHandler(serviceMock)
+		a := NewAccountHandler(serviceMock, uowMock{accounts: serviceMock}, nil)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.POST("/test", a.Create())
 
 		body := []byte(`{"name":"test"}`)
@@ -64,11 +129,171 @@ func TestAccountCreate(t *testing.T) {
 		assert.Equal(t, http.StatusCreated, w.Code)
 		assert.Equal(t, "test", responseMap["data"].(map[string]interface{})["name"])
 		assert.Equal(t, float64(0), responseMap["data"].(map[string]interface{})["balance"])
+		assert.Equal(t, "USD", responseMap["data"].(map[string]interface{})["currency"])
+	})
+	t.Run("account create with currency", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			create: func(account domain.Account) error {
+				assert.Equal(t, "EUR", account.Currency)
+				return nil
+			},
+		}
+		a := NewAccountHandler(serviceMock, uowMock{accounts: serviceMock}, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test", a.Create())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"name":"test","currency":"eur"}`)))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		if err = json.Unmarshal(w.Body.Bytes(), &responseMap); err != nil {
+			t.Fail()
+		}
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "EUR", responseMap["data"].(map[string]interface{})["currency"])
+	})
+	t.Run("account create by an owner grants it the account", func(t *testing.T) {
+		owner := domain.Principal{ID: uuid.New(), Role: domain.RoleAccountOwner}
+		var granted uuid.UUID
+		serviceMock := accountServiceMock{
+			create: func(account domain.Account) error {
+				return nil
+			},
+		}
+		a := NewAccountHandler(serviceMock, uowMock{accounts: serviceMock, auth: authServiceMock{
+			grant: func(principalID, accountID uuid.UUID) error {
+				assert.Equal(t, owner.ID, principalID)
+				granted = accountID
+				return nil
+			},
+		}}, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(owner))
+		r.POST("/test", a.Create())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"name":"test"}`)))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		if err = json.Unmarshal(w.Body.Bytes(), &responseMap); err != nil {
+			t.Fail()
+		}
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, granted.String(), responseMap["data"].(map[string]interface{})["id"])
+	})
+	t.Run("account create by an owner through the unit of work", func(t *testing.T) {
+		db := storetest.SQLite(t)
+		keys := auth.NewService(auth.NewRepository(db), "")
+		key, err := keys.Issue(context.Background(), domain.APIKeyRequest{Name: "owner", Role: domain.RoleAccountOwner})
+		assert.NoError(t, err)
+
+		a := NewAccountHandler(nil, tran.NewSQLiteUnitOfWork(db), nil)
+
+		r := gin.Default()
+		r.Use(authenticated(key.Principal))
+		r.POST("/test", a.Create())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"name":"test"}`)))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		var res struct {
+			Data domain.Account `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, domain.AccountActive, res.Data.Status)
+
+		p, err := keys.Authenticate(context.Background(), key.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{res.Data.ID}, p.Accounts)
+	})
+	t.Run("account create by a token owner", func(t *testing.T) {
+		a := NewAccountHandler(nil, tran.NewSQLiteUnitOfWork(storetest.SQLite(t)), nil)
+		owner := auth.Claims{Subject: "user-1", Issuer: "https://issuer.test", Role: domain.RoleAccountOwner}.Principal()
+
+		r := gin.Default()
+		r.Use(authenticated(owner))
+		r.POST("/test", a.Create())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"name":"test"}`)))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		var res struct {
+			Data domain.Account `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "test", res.Data.Name)
+		assert.Equal(t, domain.AccountActive, res.Data.Status)
+	})
+	t.Run("account create rolled back when the grant fails", func(t *testing.T) {
+		db := storetest.SQLite(t)
+		uow := tran.NewSQLiteUnitOfWork(db)
+		a := NewAccountHandler(nil, uow, nil)
+
+		r := gin.Default()
+		// the principal is not stored, so the grant breaks its foreign key
+		r.Use(authenticated(domain.Principal{ID: uuid.New(), Role: domain.RoleAccountOwner}))
+		r.POST("/test", a.Create())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"name":"test"}`)))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		err = uow.Do(context.Background(), func(ctx context.Context, tx tran.Tx) error {
+			page, err := tx.Accounts.List(ctx, domain.AccountFilter{})
+			assert.Empty(t, page.Accounts)
+			return err
+		})
+		assert.NoError(t, err)
+	})
+	t.Run("account create invalid currency", func(t *testing.T) {
+		serviceMock := accountServiceMock{}
+		a := NewAccountHandler(serviceMock, uowMock{accounts: serviceMock}, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test", a.Create())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"name":"test","currency":"XXX"}`)))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 	t.Run("account create invalid JSON", func(t *testing.T) {
-		a := NewAccountHandler(nil)
+		a := NewAccountHandler(nil, nil, nil)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.POST("/test", a.Create())
 
 		body := []byte(`{"name": 10}`)
@@ -98,9 +323,10 @@ func TestAccountCreate(t *testing.T) {
 				return errors.New("test error")
 			},
 		}
-		aError := NewAccountHandler(serviceErrorMock)
+		aError := NewAccountHandler(serviceErrorMock, uowMock{accounts: serviceErrorMock}, nil)
 
 		rError := gin.Default()
+		rError.Use(authenticated(admin))
 		rError.POST("/test", aError.Create())
 		body := []byte(`{"name": "test"}`)
 
@@ -126,17 +352,49 @@ func TestAccountCreate(t *testing.T) {
 }
 
 func TestAccountGetBalance(t *testing.T) {
+	t.Run("account balance of an account not owned", func(t *testing.T) {
+		a := NewAccountHandler(accountServiceMock{}, nil, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(domain.Principal{ID: uuid.New(), Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{uuid.New()}}))
+		r.GET("/test/:id/balance", a.GetBalance())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test/7dab3e13-02c7-455e-845a-13cb8c70ae8c/balance", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	t.Run("account balance without principal", func(t *testing.T) {
+		a := NewAccountHandler(accountServiceMock{}, nil, nil)
+
+		r := gin.Default()
+		r.GET("/test/:id/balance", a.GetBalance())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test/7dab3e13-02c7-455e-845a-13cb8c70ae8c/balance", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
 	t.Run("account balance success", func(t *testing.T) {
 		serviceMock := accountServiceMock{
 			read: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					Balance: 1000.00,
+					Balance: domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
 		}
-		a := NewAccountHandler(serviceMock)
+		a := NewAccountHandler(serviceMock, uowMock{accounts: serviceMock}, nil)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.GET("/test/:id/balance", a.GetBalance())
 
 		w := httptest.NewRecorder()
@@ -159,9 +417,10 @@ func TestAccountGetBalance(t *testing.T) {
 		assert.Equal(t, 1000.00, responseMap["data"].(map[string]interface{})["balance"])
 	})
 	t.Run("account balance invalid ID", func(t *testing.T) {
-		a := NewAccountHandler(nil)
+		a := NewAccountHandler(nil, nil, nil)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.GET("/test/:id/balance", a.GetBalance())
 
 		body := []byte(`{"name": 10}`)
@@ -191,9 +450,10 @@ func TestAccountGetBalance(t *testing.T) {
 				return domain.Account{}, custom_errors.ErrNotFound
 			},
 		}
-		aError := NewAccountHandler(serviceErrorMock)
+		aError := NewAccountHandler(serviceErrorMock, uowMock{accounts: serviceErrorMock}, nil)
 
 		rError := gin.Default()
+		rError.Use(authenticated(admin))
 		rError.GET("/test/:id/balance", aError.GetBalance())
 		body := []byte(`{"name": "test"}`)
 
@@ -222,9 +482,10 @@ func TestAccountGetBalance(t *testing.T) {
 				return domain.Account{}, errors.New("test error")
 			},
 		}
-		aError := NewAccountHandler(serviceErrorMock)
+		aError := NewAccountHandler(serviceErrorMock, uowMock{accounts: serviceErrorMock}, nil)
 
 		rError := gin.Default()
+		rError.Use(authenticated(admin))
 		rError.GET("/test/:id/balance", aError.GetBalance())
 		body := []byte(`{"name": "test"}`)
 
@@ -248,3 +509,347 @@ func TestAccountGetBalance(t *testing.T) {
 		assert.Contains(t, responseMap["message"], "test error")
 	})
 }
+
+func TestAccountSnapshot(t *testing.T) {
+	t.Run("account snapshot success", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			snapshot: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{
+					ID:      id,
+					Balance: domain.NewMoney(100000, domain.DefaultCurrency),
+				}, nil
+			},
+		}
+		a := NewAccountHandler(nil, uowMock{accounts: serviceMock}, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test/:id/snapshot", a.Snapshot())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test/7dab3e13-02c7-455e-845a-13cb8c70ae8c/snapshot", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		responseBody, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fail()
+		}
+		if err = json.Unmarshal(responseBody, &responseMap); err != nil {
+			t.Fail()
+		}
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "7dab3e13-02c7-455e-845a-13cb8c70ae8c", responseMap["data"].(map[string]interface{})["id"])
+		assert.Equal(t, 1000.00, responseMap["data"].(map[string]interface{})["balance"])
+	})
+	t.Run("account snapshot requested concurrently through the unit of work", func(t *testing.T) {
+		db := storetest.SQLite(t)
+		uow := tran.NewSQLiteUnitOfWork(db)
+		id := uuid.New()
+		assert.NoError(t, uow.Do(context.Background(), func(ctx context.Context, tx tran.Tx) error {
+			return tx.Accounts.Create(ctx, domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(0, domain.DefaultCurrency)})
+		}))
+		a := NewAccountHandler(nil, uow, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test/:id/snapshot", a.Snapshot())
+
+		codes := make([]int, 5)
+		var wg sync.WaitGroup
+		for i := range codes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				req, err := http.NewRequest("POST", "/test/"+id.String()+"/snapshot", nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				r.ServeHTTP(w, req)
+				codes[i] = w.Code
+			}(i)
+		}
+		wg.Wait()
+
+		for _, code := range codes {
+			assert.Equal(t, http.StatusCreated, code)
+		}
+		var snapshots int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM account_snapshots WHERE account_id = ?;", id).Scan(&snapshots))
+		assert.Equal(t, 1, snapshots)
+	})
+	t.Run("account snapshot invalid ID", func(t *testing.T) {
+		a := NewAccountHandler(nil, nil, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test/:id/snapshot", a.Snapshot())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test/10/snapshot", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		responseBody, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fail()
+		}
+		if err = json.Unmarshal(responseBody, &responseMap); err != nil {
+			t.Fail()
+		}
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, responseMap["message"], "invalid param id")
+	})
+	t.Run("account snapshot not found error", func(t *testing.T) {
+		serviceErrorMock := accountServiceMock{
+			snapshot: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{}, custom_errors.ErrNotFound
+			},
+		}
+		a := NewAccountHandler(nil, uowMock{accounts: serviceErrorMock}, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test/:id/snapshot", a.Snapshot())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test/7dab3e13-02c7-455e-845a-13cb8c70ae8c/snapshot", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		responseBody, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fail()
+		}
+		if err = json.Unmarshal(responseBody, &responseMap); err != nil {
+			t.Fail()
+		}
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, responseMap["message"], custom_errors.ErrNotFound.Error())
+	})
+}
+
+func TestAccountUpdateStatus(t *testing.T) {
+	id := uuid.MustParse("7dab3e13-02c7-455e-845a-13cb8c70ae8c")
+	updateStatus := func(t *testing.T, tr transactionServiceMock, p domain.Principal, path, body string) (int, map[string]interface{}) {
+		a := NewAccountHandler(nil, nil, tr)
+
+		r := gin.Default()
+		r.Use(authenticated(p))
+		r.PATCH("/test/:id/status", a.UpdateStatus())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("PATCH", path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		responseBody, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fail()
+		}
+		if err = json.Unmarshal(responseBody, &responseMap); err != nil {
+			t.Fail()
+		}
+		return w.Code, responseMap
+	}
+	failing := func(err error) transactionServiceMock {
+		return transactionServiceMock{
+			updateStatus: func(id uuid.UUID, req domain.AccountStatusRequest) (domain.Account, error) {
+				return domain.Account{}, err
+			},
+		}
+	}
+
+	t.Run("account update status success", func(t *testing.T) {
+		destination := uuid.New()
+		var received domain.AccountStatusRequest
+		tr := transactionServiceMock{
+			updateStatus: func(accountID uuid.UUID, req domain.AccountStatusRequest) (domain.Account, error) {
+				assert.Equal(t, id, accountID)
+				received = req
+				return domain.Account{ID: accountID, Balance: domain.NewMoney(0, domain.DefaultCurrency), Status: req.Status}, nil
+			},
+		}
+
+		code, responseMap := updateStatus(t, tr, admin, "/test/"+id.String()+"/status", `{"status":"closed","sweep_destination_id":"`+destination.String()+`"}`)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "closed", responseMap["data"].(map[string]interface{})["status"])
+		assert.Equal(t, domain.AccountClosed, received.Status)
+		assert.Equal(t, &destination, received.SweepDestinationID)
+	})
+	t.Run("account update status invalid ID", func(t *testing.T) {
+		code, responseMap := updateStatus(t, transactionServiceMock{}, admin, "/test/10/status", `{"status":"frozen"}`)
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, responseMap["message"], "invalid param id")
+	})
+	t.Run("account update status invalid JSON", func(t *testing.T) {
+		code, responseMap := updateStatus(t, transactionServiceMock{}, admin, "/test/"+id.String()+"/status", `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, responseMap["message"], custom_errors.ErrInvalidJSON.Error())
+	})
+	t.Run("account update status forbidden", func(t *testing.T) {
+		owner := domain.Principal{ID: uuid.New(), Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{uuid.New()}}
+
+		code, _ := updateStatus(t, transactionServiceMock{}, owner, "/test/"+id.String()+"/status", `{"status":"frozen"}`)
+
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+	for _, tc := range []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"not found", custom_errors.ErrNotFound, http.StatusNotFound},
+		{"invalid status", custom_errors.ErrInvalidAccountStatus, http.StatusBadRequest},
+		{"invalid sweep", custom_errors.ErrInvalidSweep, http.StatusBadRequest},
+		{"invalid transition", fmt.Errorf("%w: the account is closed", custom_errors.ErrInvalidStatusTransition), http.StatusConflict},
+		{"not empty", custom_errors.ErrAccountNotEmpty, http.StatusConflict},
+		{"sweep into a frozen account", custom_errors.ErrAccountFrozen, http.StatusConflict},
+		{"internal error", errors.New("test error"), http.StatusInternalServerError},
+	} {
+		tc := tc
+		t.Run("account update status "+tc.name, func(t *testing.T) {
+			code, responseMap := updateStatus(t, failing(tc.err), admin, "/test/"+id.String()+"/status", `{"status":"closed"}`)
+
+			assert.Equal(t, tc.code, code)
+			assert.Contains(t, responseMap["message"], tc.err.Error())
+		})
+	}
+}
+
+func TestAccountList(t *testing.T) {
+	serve := func(serviceMock accountServiceMock, url string) (int, map[string]interface{}) {
+		a := NewAccountHandler(serviceMock, nil, nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.GET("/test", a.List())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		responseBody, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fail()
+		}
+		if err = json.Unmarshal(responseBody, &responseMap); err != nil {
+			t.Fail()
+		}
+		return w.Code, responseMap
+	}
+
+	t.Run("list success with filters", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			list: func(filter domain.AccountFilter) (domain.AccountPage, error) {
+				assert.Equal(t, "al", filter.NamePrefix)
+				assert.Equal(t, []domain.AccountStatus{domain.AccountActive, domain.AccountFrozen}, filter.Statuses)
+				assert.Equal(t, "CLP", filter.Currency)
+				assert.Equal(t, domain.NewMoney(1000, "CLP"), *filter.MinBalance)
+				assert.Equal(t, domain.NewMoney(50000, "CLP"), *filter.MaxBalance)
+				assert.Equal(t, domain.SortByBalance, filter.Sort)
+				assert.True(t, filter.Descending)
+				assert.Equal(t, "abc", filter.Cursor)
+				assert.Equal(t, 5, filter.Limit)
+				return domain.AccountPage{
+					Accounts:   []domain.Account{{ID: uuid.New(), Name: "alpha", Balance: domain.NewMoney(2000, "CLP"), Status: domain.AccountActive}},
+					NextCursor: "def",
+				}, nil
+			},
+		}
+
+		code, res := serve(serviceMock, "/test?name=al&status=active,frozen&currency=clp&min_balance=1000&max_balance=50000"+
+			"&sort=balance&order=desc&cursor=abc&limit=5")
+
+		assert.Equal(t, http.StatusOK, code)
+		data := res["data"].(map[string]interface{})
+		assert.Len(t, data["accounts"], 1)
+		assert.Equal(t, "def", data["next_cursor"])
+	})
+	t.Run("list sorted by name by default", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			list: func(filter domain.AccountFilter) (domain.AccountPage, error) {
+				assert.Equal(t, domain.AccountFilter{Sort: domain.SortByName}, filter)
+				return domain.AccountPage{Accounts: []domain.Account{}}, nil
+			},
+		}
+
+		code, _ := serve(serviceMock, "/test")
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+	t.Run("list invalid query params", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			list: func(filter domain.AccountFilter) (domain.AccountPage, error) {
+				t.Fatal("service called with an invalid filter")
+				return domain.AccountPage{}, nil
+			},
+		}
+
+		for _, query := range []string{"status=deleted", "currency=XXX", "min_balance=10", "currency=USD&max_balance=1.001",
+			"sort=id", "order=up", "limit=0", "limit=101"} {
+			code, _ := serve(serviceMock, "/test?"+query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+	t.Run("list sorted by balance without currency", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			list: func(filter domain.AccountFilter) (domain.AccountPage, error) {
+				t.Fatal("service called with an invalid filter")
+				return domain.AccountPage{}, nil
+			},
+		}
+
+		code, res := serve(serviceMock, "/test?sort=balance&order=desc")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, res["message"], "sort by balance requires the currency query param")
+	})
+	t.Run("list invalid cursor", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			list: func(filter domain.AccountFilter) (domain.AccountPage, error) {
+				return domain.AccountPage{}, custom_errors.ErrInvalidCursor
+			},
+		}
+
+		code, res := serve(serviceMock, "/test?cursor=bad")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, res["message"], custom_errors.ErrInvalidCursor.Error())
+	})
+	t.Run("list internal server error", func(t *testing.T) {
+		serviceMock := accountServiceMock{
+			list: func(filter domain.AccountFilter) (domain.AccountPage, error) {
+				return domain.AccountPage{}, errors.New("test error")
+			},
+		}
+
+		code, _ := serve(serviceMock, "/test")
+
+		assert.Equal(t, http.StatusInternalServerError, code)
+	})
+}
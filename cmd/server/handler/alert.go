@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/monitoring"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"net/http"
+	"strconv"
+)
+
+type Alerts interface {
+	List() gin.HandlerFunc
+}
+
+type alert struct {
+	s monitoring.Service
+}
+
+func NewAlertsHandler(s monitoring.Service) Alerts {
+	return &alert{
+		s: s,
+	}
+}
+
+// List	godoc
+// @Summary	List the monitoring alerts
+// @Tags	Monitoring
+// @Description	lists the alerts raised by the transaction monitoring rules, newest first
+// @Produce	json
+// @Param	token	header	string	true	"token"
+// @Param	account_id	query	string	false	"Account ID"
+// @Param	rule	query	string	false	"Rule name"
+// @Param	from	query	string	false	"Start date, inclusive (RFC 3339 or YYYY-MM-DD)"
+// @Param	to	query	string	false	"End date, exclusive (RFC 3339 or YYYY-MM-DD)"
+// @Param	cursor	query	string	false	"Cursor returned by the previous page"
+// @Param	limit	query	int	false	"Page size (default 50, max 100)"
+// @Success 200	{object}	web.Response{data=domain.AlertPage}
+// @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/alerts	[get]
+func (a alert) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := domain.AlertFilter{Rule: c.Query("rule"), Cursor: c.Query("cursor")}
+		if value := c.Query("account_id"); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidID)
+				return
+			}
+			filter.AccountID = &id
+		}
+
+		var err error
+		if filter.From, err = queryDate(c, "from"); err != nil {
+			web.Failure(c, http.StatusBadRequest, err)
+			return
+		}
+		if filter.To, err = queryDate(c, "to"); err != nil {
+			web.Failure(c, http.StatusBadRequest, err)
+			return
+		}
+		if limit := c.Query("limit"); limit != "" {
+			filter.Limit, err = strconv.Atoi(limit)
+			if err != nil || filter.Limit <= 0 || filter.Limit > monitoring.MaxPageSize {
+				web.Failure(c, http.StatusBadRequest, fmt.Errorf("%w: limit must be between 1 and %d", custom_errors.ErrInvalidQuery, monitoring.MaxPageSize))
+				return
+			}
+		}
+
+		page, err := a.s.List(c.Request.Context(), filter)
+		if err != nil {
+			if errors.Is(err, custom_errors.ErrInvalidCursor) {
+				web.Failure(c, http.StatusBadRequest, err)
+				return
+			}
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+		web.Success(c, http.StatusOK, page)
+	}
+}
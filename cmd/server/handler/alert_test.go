@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type monitoringServiceMock struct {
+	list func(filter domain.AlertFilter) (domain.AlertPage, error)
+}
+
+func (m monitoringServiceMock) Evaluate(ctx context.Context, tr domain.Transaction) ([]domain.Alert, error) {
+	return nil, nil
+}
+
+func (m monitoringServiceMock) List(ctx context.Context, filter domain.AlertFilter) (domain.AlertPage, error) {
+	return m.list(filter)
+}
+
+func TestAlertsList(t *testing.T) {
+	accountID := uuid.MustParse("d70d0a95-af7f-4098-8d81-caca1934e94d")
+	serve := func(serviceMock monitoringServiceMock, url string) *httptest.ResponseRecorder {
+		h := NewAlertsHandler(serviceMock)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.GET("/test", h.List())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("list success", func(t *testing.T) {
+		from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		serviceMock := monitoringServiceMock{
+			list: func(filter domain.AlertFilter) (domain.AlertPage, error) {
+				assert.Equal(t, accountID, *filter.AccountID)
+				assert.Equal(t, "large_deposit", filter.Rule)
+				assert.Equal(t, from, *filter.From)
+				assert.Nil(t, filter.To)
+				assert.Equal(t, "abc", filter.Cursor)
+				assert.Equal(t, 10, filter.Limit)
+				return domain.AlertPage{
+					Alerts:     []domain.Alert{{ID: uuid.New(), Rule: "large_deposit", AccountID: accountID, Amount: domain.NewMoney(2000000, "USD")}},
+					NextCursor: "def",
+				}, nil
+			},
+		}
+
+		w := serve(serviceMock, "/test?account_id="+accountID.String()+"&rule=large_deposit&from=2023-01-01&cursor=abc&limit=10")
+
+		responseMap := make(map[string]interface{})
+		if err := json.Unmarshal(w.Body.Bytes(), &responseMap); err != nil {
+			t.Fail()
+		}
+		assert.Equal(t, http.StatusOK, w.Code)
+		data := responseMap["data"].(map[string]interface{})
+		assert.Equal(t, 20000.0, data["alerts"].([]interface{})[0].(map[string]interface{})["amount"])
+		assert.Equal(t, "def", data["next_cursor"])
+	})
+	t.Run("list invalid cursor", func(t *testing.T) {
+		serviceMock := monitoringServiceMock{
+			list: func(filter domain.AlertFilter) (domain.AlertPage, error) {
+				return domain.AlertPage{}, custom_errors.ErrInvalidCursor
+			},
+		}
+
+		w := serve(serviceMock, "/test?cursor=bad")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("list invalid params", func(t *testing.T) {
+		for _, url := range []string{"/test?account_id=invalid", "/test?from=yesterday", "/test?to=tomorrow", "/test?limit=0", "/test?limit=101"} {
+			w := serve(monitoringServiceMock{}, url)
+			assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		}
+	})
+	t.Run("list internal server error", func(t *testing.T) {
+		serviceMock := monitoringServiceMock{
+			list: func(filter domain.AlertFilter) (domain.AlertPage, error) {
+				return domain.AlertPage{}, errors.New("test error")
+			},
+		}
+
+		w := serve(serviceMock, "/test")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
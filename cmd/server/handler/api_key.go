@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	tran "github.com/lucaspichi06/xepelin-bank/internal/transaction"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"net/http"
+)
+
+type APIKeys interface {
+	Create() gin.HandlerFunc
+}
+
+type apiKey struct {
+	uow tran.UnitOfWork
+}
+
+func NewAPIKeysHandler(uow tran.UnitOfWork) APIKeys {
+	return &apiKey{
+		uow: uow,
+	}
+}
+
+// Create	godoc
+// @Summary	Issues an API key
+// @Tags	Auth
+// @Description	creates a principal with a role and returns its API key, which is only shown once. Account owners can only operate on the accounts they own
+// @Accept	json
+// @Produce	json
+// @Param	token	header	string	true	"token"
+// @Param	key		body 	domain.APIKeyRequest	true	"Principal of the key"
+// @Success 201	{object}	web.Response{data=domain.APIKey}
+// @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/api-keys	[post]
+func (a apiKey) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req domain.APIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidJSON)
+			return
+		}
+		if !req.Role.Valid() {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidRole)
+			return
+		}
+
+		var key domain.APIKey
+		err := a.uow.Do(c.Request.Context(), func(ctx context.Context, tx tran.Tx) error {
+			for _, id := range req.Accounts {
+				if _, err := tx.Accounts.Read(ctx, id); err != nil {
+					return err
+				}
+			}
+			var err error
+			key, err = tx.Auth.Issue(ctx, req)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, custom_errors.ErrNotFound) {
+				web.Failure(c, http.StatusBadRequest, fmt.Errorf("account not found: %v", err))
+				return
+			}
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		web.Success(c, http.StatusCreated, key)
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyCreate(t *testing.T) {
+	accountID := uuid.MustParse("d70d0a95-af7f-4098-8d81-caca1934e94d")
+	serve := func(uow uowMock, body string) *httptest.ResponseRecorder {
+		h := NewAPIKeysHandler(uow)
+
+		r := gin.Default()
+		r.POST("/test", h.Create())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("api key create success", func(t *testing.T) {
+		uow := uowMock{
+			accounts: accountServiceMock{
+				read: func(id uuid.UUID) (domain.Account, error) {
+					return domain.Account{ID: id}, nil
+				},
+			},
+			auth: authServiceMock{
+				issue: func(req domain.APIKeyRequest) (domain.APIKey, error) {
+					assert.Equal(t, domain.RoleAccountOwner, req.Role)
+					assert.Equal(t, []uuid.UUID{accountID}, req.Accounts)
+					return domain.APIKey{Key: "xbk_test", Principal: domain.Principal{Name: req.Name, Role: req.Role, Accounts: req.Accounts}}, nil
+				},
+			},
+		}
+
+		w := serve(uow, `{"name":"test","role":"account_owner","accounts":["d70d0a95-af7f-4098-8d81-caca1934e94d"]}`)
+
+		var res struct {
+			Data domain.APIKey `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "xbk_test", res.Data.Key)
+		assert.Equal(t, "test", res.Data.Principal.Name)
+	})
+	t.Run("api key create invalid role", func(t *testing.T) {
+		w := serve(uowMock{}, `{"name":"test","role":"root"}`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), custom_errors.ErrInvalidRole.Error())
+	})
+	t.Run("api key create invalid JSON", func(t *testing.T) {
+		w := serve(uowMock{}, `{"name":10}`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("api key create account not found", func(t *testing.T) {
+		uow := uowMock{
+			accounts: accountServiceMock{
+				read: func(id uuid.UUID) (domain.Account, error) {
+					return domain.Account{}, custom_errors.ErrNotFound
+				},
+			},
+		}
+
+		w := serve(uow, `{"name":"test","role":"account_owner","accounts":["d70d0a95-af7f-4098-8d81-caca1934e94d"]}`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("api key create internal server error", func(t *testing.T) {
+		uow := uowMock{
+			auth: authServiceMock{
+				issue: func(req domain.APIKeyRequest) (domain.APIKey, error) {
+					return domain.APIKey{}, errors.New("test error")
+				},
+			},
+		}
+
+		w := serve(uow, `{"name":"test","role":"auditor"}`)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
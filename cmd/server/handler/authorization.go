@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/middleware"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"net/http"
+)
+
+// canRead checks the caller can read the account, answering forbidden when it can not
+func canRead(c *gin.Context, accountID uuid.UUID) bool {
+	return authorize(c, func(p domain.Principal) bool { return p.CanRead(accountID) })
+}
+
+// canOperate checks the caller can move money from the account, answering forbidden
+// when it can not
+func canOperate(c *gin.Context, accountID uuid.UUID) bool {
+	return authorize(c, func(p domain.Principal) bool { return p.CanOperate(accountID) })
+}
+
+func authorize(c *gin.Context, allowed func(p domain.Principal) bool) bool {
+	p, ok := middleware.CurrentPrincipal(c)
+	if !ok || !allowed(p) {
+		web.Failure(c, http.StatusForbidden, custom_errors.ErrForbidden)
+		return false
+	}
+	return true
+}
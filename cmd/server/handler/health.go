@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/health"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"net/http"
+)
+
+type Health interface {
+	Live() gin.HandlerFunc
+	Ready() gin.HandlerFunc
+}
+
+type healthHandler struct {
+	checker health.Checker
+}
+
+func NewHealthHandler(checker health.Checker) Health {
+	return &healthHandler{
+		checker: checker,
+	}
+}
+
+// Live	godoc
+// @Summary	Liveness probe
+// @Tags	Health
+// @Description	tells the server is running, without checking its dependencies
+// @Produce	json
+// @Success 200	{object}	web.Response{data=health.Report}
+// @Router	/healthz	[get]
+func (h healthHandler) Live() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		web.Success(c, http.StatusOK, health.Report{Status: health.StatusUp})
+	}
+}
+
+// Ready	godoc
+// @Summary	Readiness probe
+// @Tags	Health
+// @Description	checks the database can be reached and its migrations are applied, so the server can take requests
+// @Produce	json
+// @Success 200	{object}	web.Response{data=health.Report}
+// @Failure	503	{object}	web.Response{data=health.Report}
+// @Router	/readyz	[get]
+func (h healthHandler) Ready() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.checker.Ready(c.Request.Context())
+		if report.Status != health.StatusUp {
+			web.Success(c, http.StatusServiceUnavailable, report)
+			return
+		}
+		web.Success(c, http.StatusOK, report)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/health"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type checkerMock struct {
+	ready func() health.Report
+}
+
+func (m checkerMock) Ready(ctx context.Context) health.Report {
+	return m.ready()
+}
+
+func TestHealth(t *testing.T) {
+	serve := func(checker checkerMock, path string) *httptest.ResponseRecorder {
+		h := NewHealthHandler(checker)
+
+		r := gin.Default()
+		r.GET("/healthz", h.Live())
+		r.GET("/readyz", h.Ready())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("live", func(t *testing.T) {
+		w := serve(checkerMock{}, "/healthz")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"status":"up"}}`, w.Body.String())
+	})
+	t.Run("ready", func(t *testing.T) {
+		w := serve(checkerMock{
+			ready: func() health.Report {
+				return health.Report{Status: health.StatusUp, Checks: map[string]health.CheckReport{
+					"database": {Status: health.StatusUp, DurationMs: 1.5},
+				}}
+			},
+		}, "/readyz")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"status":"up","checks":{"database":{"status":"up","duration_ms":1.5}}}}`, w.Body.String())
+	})
+	t.Run("not ready", func(t *testing.T) {
+		w := serve(checkerMock{
+			ready: func() health.Report {
+				return health.Report{Status: health.StatusDown, Checks: map[string]health.CheckReport{
+					"database": {Status: health.StatusDown, Error: "connection refused", DurationMs: 2},
+				}}
+			},
+		}, "/readyz")
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.JSONEq(t, `{"data":{"status":"down","checks":{"database":{"status":"down","error":"connection refused","duration_ms":2}}}}`, w.Body.String())
+	})
+}
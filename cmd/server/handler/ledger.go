@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/ledger"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"net/http"
+)
+
+type Ledger interface {
+	Verify() gin.HandlerFunc
+}
+
+type ledgerHandler struct {
+	s ledger.Service
+}
+
+func NewLedgerHandler(s ledger.Service) Ledger {
+	return &ledgerHandler{
+		s: s,
+	}
+}
+
+// Verify	godoc
+// @Summary	Verify the ledger
+// @Tags	Ledger
+// @Description	checks that the postings of all the journal entries add up to zero in every currency
+// @Produce	json
+// @Param	token	header	string	true	"token"
+// @Success 200	{object}	web.Response{data=domain.LedgerVerification}
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	409	{object}	web.Response{data=domain.LedgerVerification}
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/ledger/verify	[get]
+func (l ledgerHandler) Verify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := l.s.Verify(c.Request.Context())
+		if errors.Is(err, custom_errors.ErrLedgerUnbalanced) {
+			web.Success(c, http.StatusConflict, res)
+			return
+		}
+		if err != nil {
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+		web.Success(c, http.StatusOK, res)
+	}
+}
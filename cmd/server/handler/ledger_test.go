@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type ledgerServiceMock struct {
+	verify func() (domain.LedgerVerification, error)
+}
+
+func (l ledgerServiceMock) Record(ctx context.Context, tr domain.Transaction) (domain.JournalEntry, error) {
+	return domain.JournalEntry{}, errors.New("not supported")
+}
+
+func (l ledgerServiceMock) Verify(ctx context.Context) (domain.LedgerVerification, error) {
+	return l.verify()
+}
+
+func TestLedgerVerify(t *testing.T) {
+	serve := func(serviceMock ledgerServiceMock) *httptest.ResponseRecorder {
+		h := NewLedgerHandler(serviceMock)
+
+		r := gin.Default()
+		r.GET("/test", h.Verify())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("verify balanced", func(t *testing.T) {
+		w := serve(ledgerServiceMock{
+			verify: func() (domain.LedgerVerification, error) {
+				return domain.LedgerVerification{Balanced: true}, nil
+			},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"balanced":true}}`, w.Body.String())
+	})
+	t.Run("verify unbalanced", func(t *testing.T) {
+		w := serve(ledgerServiceMock{
+			verify: func() (domain.LedgerVerification, error) {
+				return domain.LedgerVerification{
+					Unbalanced: map[string]domain.Money{"USD": domain.NewMoney(-100, "USD")},
+				}, custom_errors.ErrLedgerUnbalanced
+			},
+		})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var res struct {
+			Data domain.LedgerVerification `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.False(t, res.Data.Balanced)
+		assert.Contains(t, res.Data.Unbalanced, "USD")
+	})
+	t.Run("verify error", func(t *testing.T) {
+		w := serve(ledgerServiceMock{
+			verify: func() (domain.LedgerVerification, error) {
+				return domain.LedgerVerification{}, errors.New("test error")
+			},
+		})
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	st "github.com/lucaspichi06/xepelin-bank/internal/statement"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"net/http"
+	"time"
+)
+
+type Statements interface {
+	Export() gin.HandlerFunc
+}
+
+type statement struct {
+	s st.Service
+}
+
+func NewStatementsHandler(s st.Service) Statements {
+	return &statement{
+		s: s,
+	}
+}
+
+// Export	godoc
+// @Summary	Export the statement of an account
+// @Tags	Account
+// @Description	exports the opening balance, the transactions with the running balance and the closing balance of an account in a period
+// @Produce	json
+// @Produce	text/csv
+// @Produce	application/x-ofx
+// @Param	token	header	string	true	"token"
+// @Param	id	path	string	true	"Account ID"
+// @Param	from	query	string	false	"Start date, inclusive (RFC 3339 or YYYY-MM-DD). Defaults to the start of the current month"
+// @Param	to	query	string	false	"End date, exclusive (RFC 3339 or YYYY-MM-DD). Defaults to now"
+// @Param	format	query	string	false	"csv, ofx or json (default)"
+// @Success 200	{object}	web.Response{data=domain.Statement}
+// @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	404	{object}	web.ErrorResponse
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/accounts/{id}/statement	[get]
+func (s statement) Export() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidID)
+			return
+		}
+		if !canRead(c, id) {
+			return
+		}
+		format, err := st.ParseFormat(c.Query("format"))
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, err)
+			return
+		}
+
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to := now
+		if value, err := queryDate(c, "from"); err != nil {
+			web.Failure(c, http.StatusBadRequest, err)
+			return
+		} else if value != nil {
+			from = *value
+		}
+		if value, err := queryDate(c, "to"); err != nil {
+			web.Failure(c, http.StatusBadRequest, err)
+			return
+		} else if value != nil {
+			to = *value
+		}
+
+		res, err := s.s.Build(c.Request.Context(), id, from, to)
+		if err != nil {
+			if errors.Is(err, custom_errors.ErrNotFound) {
+				web.Failure(c, http.StatusNotFound, fmt.Errorf("account not found: %v", err))
+				return
+			}
+			if errors.Is(err, custom_errors.ErrInvalidStatementPeriod) {
+				web.Failure(c, http.StatusBadRequest, err)
+				return
+			}
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if format == st.JSON {
+			web.Success(c, http.StatusOK, res)
+			return
+		}
+
+		var body bytes.Buffer
+		if err = st.Write(&body, res, format); err != nil {
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+		filename := fmt.Sprintf("statement-%s-%s-%s.%s", id, res.From.Format("20060102"), res.To.Format("20060102"), format)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Data(http.StatusOK, format.ContentType(), body.Bytes())
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type statementServiceMock struct {
+	build func(accountID uuid.UUID, from, to time.Time) (domain.Statement, error)
+}
+
+func (s statementServiceMock) Build(ctx context.Context, accountID uuid.UUID, from, to time.Time) (domain.Statement, error) {
+	return s.build(accountID, from, to)
+}
+
+func TestStatementExport(t *testing.T) {
+	accountID := uuid.MustParse("d70d0a95-af7f-4098-8d81-caca1934e94d")
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	build := func(id uuid.UUID, start, end time.Time) (domain.Statement, error) {
+		return domain.NewStatement(domain.Account{ID: id}, start, end, domain.NewMoney(1000, "USD"), []domain.Transaction{
+			{ID: uuid.New(), AccountID: id, Type: domain.Deposit, Amount: domain.NewMoney(500, "USD"), Timestamp: start},
+		})
+	}
+	serve := func(serviceMock statementServiceMock, url string) *httptest.ResponseRecorder {
+		h := NewStatementsHandler(serviceMock)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.GET("/test/:id/statement", h.Export())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("export json", func(t *testing.T) {
+		serviceMock := statementServiceMock{
+			build: func(id uuid.UUID, start, end time.Time) (domain.Statement, error) {
+				assert.Equal(t, accountID, id)
+				assert.Equal(t, from, start)
+				assert.Equal(t, to, end)
+				return build(id, start, end)
+			},
+		}
+
+		w := serve(serviceMock, "/test/"+accountID.String()+"/statement?from=2023-01-01&to=2023-02-01")
+
+		responseMap := make(map[string]interface{})
+		if err := json.Unmarshal(w.Body.Bytes(), &responseMap); err != nil {
+			t.Fail()
+		}
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 15.0, responseMap["data"].(map[string]interface{})["closing_balance"])
+	})
+	t.Run("export csv", func(t *testing.T) {
+		w := serve(statementServiceMock{build: build}, "/test/"+accountID.String()+"/statement?from=2023-01-01&to=2023-02-01&format=csv")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="statement-`+accountID.String()+`-20230101-20230201.csv"`, w.Header().Get("Content-Disposition"))
+		assert.True(t, strings.HasPrefix(w.Body.String(), "date,transaction_id,type,counterparty_id,amount,balance\n"))
+	})
+	t.Run("export ofx", func(t *testing.T) {
+		w := serve(statementServiceMock{build: build}, "/test/"+accountID.String()+"/statement?format=ofx")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ofx", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "<STMTTRN>")
+	})
+	t.Run("export invalid params", func(t *testing.T) {
+		for _, url := range []string{"/test/invalid/statement", "/test/" + accountID.String() + "/statement?format=pdf",
+			"/test/" + accountID.String() + "/statement?from=yesterday"} {
+			w := serve(statementServiceMock{build: build}, url)
+			assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		}
+	})
+	t.Run("export invalid period", func(t *testing.T) {
+		serviceMock := statementServiceMock{
+			build: func(id uuid.UUID, start, end time.Time) (domain.Statement, error) {
+				return domain.Statement{}, custom_errors.ErrInvalidStatementPeriod
+			},
+		}
+
+		w := serve(serviceMock, "/test/"+accountID.String()+"/statement?from=2023-02-01&to=2023-01-01")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("export account not found", func(t *testing.T) {
+		serviceMock := statementServiceMock{
+			build: func(id uuid.UUID, start, end time.Time) (domain.Statement, error) {
+				return domain.Statement{}, custom_errors.ErrNotFound
+			},
+		}
+
+		w := serve(serviceMock, "/test/"+accountID.String()+"/statement")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+	t.Run("export by principal", func(t *testing.T) {
+		for _, tc := range []struct {
+			principal domain.Principal
+			status    int
+		}{
+			{domain.Principal{ID: uuid.New(), Role: domain.RoleAuditor}, http.StatusOK},
+			{domain.Principal{ID: uuid.New(), Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{accountID}}, http.StatusOK},
+			{domain.Principal{ID: uuid.New(), Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{uuid.New()}}, http.StatusForbidden},
+		} {
+			h := NewStatementsHandler(statementServiceMock{build: build})
+
+			r := gin.Default()
+			r.Use(authenticated(tc.principal))
+			r.GET("/test/:id/statement", h.Export())
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/test/"+accountID.String()+"/statement", nil)
+			if err != nil {
+				t.Fail()
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code, tc.principal.Role)
+		}
+	})
+	t.Run("export internal server error", func(t *testing.T) {
+		serviceMock := statementServiceMock{
+			build: func(id uuid.UUID, start, end time.Time) (domain.Statement, error) {
+				return domain.Statement{}, errors.New("test error")
+			},
+		}
+
+		w := serve(serviceMock, "/test/"+accountID.String()+"/statement")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
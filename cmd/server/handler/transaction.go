@@ -2,17 +2,24 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
 	tran "github.com/lucaspichi06/xepelin-bank/internal/transaction"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/middleware"
 	"github.com/lucaspichi06/xepelin-bank/pkg/web"
 
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Transactions interface {
 	Process() gin.HandlerFunc
+	List() gin.HandlerFunc
 }
 
 type transaction struct {
@@ -32,32 +39,190 @@ func NewTransactionsHandler(s tran.Service) Transactions {
 // @Accept	json
 // @Produce	json
 // @Param	token	header	string	true	"token"
+// @Param	Idempotency-Key	header	string	false	"Key to retry the request safely"
+// @Param	X-Signature-Key-Id	header	string	false	"Id of the signing key, required when request signing is enabled"
+// @Param	X-Signature-Timestamp	header	int	false	"Unix timestamp of the signature, required when request signing is enabled"
+// @Param	X-Signature-Nonce	header	string	false	"Unique value of the request, required when request signing is enabled"
+// @Param	X-Signature	header	string	false	"Hex HMAC-SHA256 of the request, required when request signing is enabled"
 // @Param	transaction		body 	domain.Transaction	true	"Transaction to process"
 // @Success 201	{object}	web.Response
 // @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	409	{object}	web.ErrorResponse
+// @Failure	422	{object}	web.ErrorResponse
 // @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
 // @Router	/transactions	[post]
 func (t transaction) Process() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var tr domain.Transaction
 		err := c.ShouldBindJSON(&tr)
 		if err != nil {
+			if isAmountError(err) {
+				web.Failure(c, http.StatusBadRequest, err)
+				return
+			}
 			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidJSON)
 			return
 		}
+		if !canOperate(c, tr.AccountID) {
+			return
+		}
 
-		c.Set("transaction_type", tr.Type)
-		c.Set("transaction_amount", tr.Amount)
-
-		if err = t.s.Create(&tr); err != nil {
+		if err = t.s.Create(c.Request.Context(), &tr); err != nil {
 			if errors.Is(err, custom_errors.ErrInvalidTransactionType) {
 				web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidTransactionType)
 				return
 			}
+			if isAmountError(err) {
+				web.Failure(c, http.StatusBadRequest, err)
+				return
+			}
+			if isInactiveError(err) {
+				web.Failure(c, http.StatusConflict, err)
+				return
+			}
 			web.Failure(c, http.StatusInternalServerError, err)
 			return
 		}
+		c.Set(middleware.TransactionKey, tr)
 
 		web.Success(c, http.StatusCreated, tr)
 	}
 }
+
+// List	godoc
+// @Summary	List the transactions of an account
+// @Tags	Transaction
+// @Description	lists the transactions of an account newest first, including the transfers it received
+// @Produce	json
+// @Param	token	header	string	true	"token"
+// @Param	id	path	string	true	"Account ID"
+// @Param	type	query	string	false	"Comma separated transaction types (deposit, withdraw, transfer)"
+// @Param	min_amount	query	number	false	"Minimum amount, in the account currency"
+// @Param	max_amount	query	number	false	"Maximum amount, in the account currency"
+// @Param	from	query	string	false	"Start date, inclusive (RFC 3339 or YYYY-MM-DD)"
+// @Param	to	query	string	false	"End date, exclusive (RFC 3339 or YYYY-MM-DD)"
+// @Param	cursor	query	string	false	"Cursor returned by the previous page"
+// @Param	limit	query	int	false	"Page size (default 20, max 100)"
+// @Success 200	{object}	web.Response{data=domain.TransactionPage}
+// @Failure	400	{object}	web.ErrorResponse
+// @Failure	401	{object}	web.ErrorResponse
+// @Failure	403	{object}	web.ErrorResponse
+// @Failure	404	{object}	web.ErrorResponse
+// @Failure	500	{object}	web.ErrorResponse
+// @Failure	504	{object}	web.ErrorResponse
+// @Router	/accounts/{id}/transactions	[get]
+func (t transaction) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidID)
+			return
+		}
+		if !canRead(c, id) {
+			return
+		}
+
+		filter, err := parseTransactionFilter(c)
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, err)
+			return
+		}
+		filter.AccountID = id
+
+		page, err := t.s.List(c.Request.Context(), filter)
+		if err != nil {
+			if errors.Is(err, custom_errors.ErrNotFound) {
+				web.Failure(c, http.StatusNotFound, fmt.Errorf("account not found: %v", err))
+				return
+			}
+			if errors.Is(err, custom_errors.ErrInvalidCursor) || isAmountError(err) {
+				web.Failure(c, http.StatusBadRequest, err)
+				return
+			}
+			web.Failure(c, http.StatusInternalServerError, err)
+			return
+		}
+		web.Success(c, http.StatusOK, page)
+	}
+}
+
+func parseTransactionFilter(c *gin.Context) (domain.TransactionFilter, error) {
+	filter := domain.TransactionFilter{Cursor: c.Query("cursor")}
+
+	if types := c.Query("type"); types != "" {
+		for _, value := range strings.Split(types, ",") {
+			tt := domain.EventType(strings.TrimSpace(value))
+			if tt != domain.Deposit && tt != domain.WithDraw && tt != domain.Transfer {
+				return filter, custom_errors.ErrInvalidTransactionType
+			}
+			filter.Types = append(filter.Types, tt)
+		}
+	}
+
+	var err error
+	if filter.MinValue, err = queryDecimal(c, "min_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxValue, err = queryDecimal(c, "max_amount"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = queryDate(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryDate(c, "to"); err != nil {
+		return filter, err
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 || filter.Limit > tran.MaxPageSize {
+			return filter, fmt.Errorf("%w: limit must be between 1 and %d", custom_errors.ErrInvalidQuery, tran.MaxPageSize)
+		}
+	}
+	return filter, nil
+}
+
+// queryDecimal reads an amount whose decimal places are checked later, against the account currency
+func queryDecimal(c *gin.Context, key string) (domain.Decimal, error) {
+	value := c.Query(key)
+	if value == "" {
+		return "", nil
+	}
+	d, err := domain.ParseDecimal(value)
+	if err != nil {
+		return "", fmt.Errorf("%w %s: %v", custom_errors.ErrInvalidQuery, key, err)
+	}
+	return d, nil
+}
+
+func queryDate(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		ts, err = time.Parse("2006-01-02", value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", custom_errors.ErrInvalidQuery, key, err)
+	}
+	return &ts, nil
+}
+
+func isAmountError(err error) bool {
+	return errors.Is(err, custom_errors.ErrInvalidAmount) ||
+		errors.Is(err, custom_errors.ErrInvalidAmountPrecision) ||
+		errors.Is(err, custom_errors.ErrInvalidCurrency) ||
+		errors.Is(err, custom_errors.ErrCurrencyMismatch) ||
+		errors.Is(err, custom_errors.ErrExchangeRateNotFound)
+}
+
+// isInactiveError tells whether the error is caused by a frozen or closed account
+func isInactiveError(err error) bool {
+	return errors.Is(err, custom_errors.ErrAccountFrozen) ||
+		errors.Is(err, custom_errors.ErrAccountClosed)
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -12,26 +14,38 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type transactionServiceMock struct {
-	create func(tr *domain.Transaction) error
+	create       func(tr *domain.Transaction) error
+	list         func(filter domain.TransactionFilter) (domain.TransactionPage, error)
+	updateStatus func(id uuid.UUID, req domain.AccountStatusRequest) (domain.Account, error)
 }
 
-func (t transactionServiceMock) Create(tr *domain.Transaction) error {
+func (t transactionServiceMock) Create(ctx context.Context, tr *domain.Transaction) error {
 	return t.create(tr)
 }
 
+func (t transactionServiceMock) List(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	return t.list(filter)
+}
+
+func (t transactionServiceMock) UpdateStatus(ctx context.Context, id uuid.UUID, req domain.AccountStatusRequest) (domain.Account, error) {
+	return t.updateStatus(id, req)
+}
+
 func TestTransactionCreate(t *testing.T) {
 	t.Run("transaction create success", func(t *testing.T) {
 		serviceMock := transactionServiceMock{
 			create: func(tr *domain.Transaction) error {
-				return nil
+				return tr.ResolveAmount(domain.Account{Currency: "USD"})
 			},
 		}
 		tr := NewTransactionsHandler(serviceMock)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.POST("/test", tr.Process())
 
 		body := []byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"withdraw","amount":10000.33}`)
@@ -58,10 +72,53 @@ func TestTransactionCreate(t *testing.T) {
 		assert.Equal(t, 10000.33, responseMap["data"].(map[string]interface{})["amount"])
 		assert.NotNil(t, responseMap["data"].(map[string]interface{})["transaction_id"])
 	})
+	t.Run("transaction create by the owner of the account", func(t *testing.T) {
+		accountID := uuid.MustParse("d70d0a95-af7f-4098-8d81-caca1934e94d")
+		tr := NewTransactionsHandler(transactionServiceMock{
+			create: func(tr *domain.Transaction) error {
+				return nil
+			},
+		})
+
+		r := gin.Default()
+		r.Use(authenticated(domain.Principal{ID: uuid.New(), Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{accountID}}))
+		r.POST("/test", tr.Process())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"deposit","amount":10}`)))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+	t.Run("transaction create forbidden", func(t *testing.T) {
+		for _, p := range []domain.Principal{
+			{ID: uuid.New(), Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{uuid.New()}},
+			{ID: uuid.New(), Role: domain.RoleAuditor},
+		} {
+			tr := NewTransactionsHandler(transactionServiceMock{})
+
+			r := gin.Default()
+			r.Use(authenticated(p))
+			r.POST("/test", tr.Process())
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/test", bytes.NewBuffer([]byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"withdraw","amount":10}`)))
+			if err != nil {
+				t.Fail()
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		}
+	})
 	t.Run("transaction create invalid JSON", func(t *testing.T) {
 		tr := NewTransactionsHandler(nil)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.POST("/test", tr.Process())
 
 		body := []byte(`{"account_id":"d70d0a91231235-af7f-4098-8d81-caca1934e94d","type":"withdraw","amount":10000.33}`)
@@ -85,6 +142,110 @@ func TestTransactionCreate(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 		assert.Contains(t, responseMap["message"], "invalid json")
 	})
+	t.Run("transaction create amount with too many decimals", func(t *testing.T) {
+		tr := NewTransactionsHandler(nil)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test", tr.Process())
+
+		body := []byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"deposit","amount":10.001,"currency":"USD"}`)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		responseBody, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fail()
+		}
+		if err = json.Unmarshal(responseBody, &responseMap); err != nil {
+			t.Fail()
+		}
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, responseMap["message"], custom_errors.ErrInvalidAmountPrecision.Error())
+	})
+	t.Run("transaction create amount in the account currency", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			create: func(tr *domain.Transaction) error {
+				assert.Empty(t, tr.Currency)
+				assert.NoError(t, tr.ResolveAmount(domain.Account{Currency: "KWD"}))
+				assert.Equal(t, domain.NewMoney(1234, "KWD"), tr.Amount)
+				return nil
+			},
+		}
+		tr := NewTransactionsHandler(serviceMock)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test", tr.Process())
+
+		body := []byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"deposit","amount":1.234}`)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"amount":1.234`)
+	})
+	t.Run("transaction create currency mismatch", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			create: func(tr *domain.Transaction) error {
+				assert.Equal(t, "EUR", tr.Currency)
+				return custom_errors.ErrCurrencyMismatch
+			},
+		}
+		tr := NewTransactionsHandler(serviceMock)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test", tr.Process())
+
+		body := []byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"deposit","amount":10,"currency":"EUR"}`)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), custom_errors.ErrCurrencyMismatch.Error())
+	})
+	t.Run("transaction create frozen account", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			create: func(tr *domain.Transaction) error {
+				return custom_errors.ErrAccountFrozen
+			},
+		}
+		tr := NewTransactionsHandler(serviceMock)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.POST("/test", tr.Process())
+
+		body := []byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"withdraw","amount":10}`)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), custom_errors.ErrAccountFrozen.Error())
+	})
 	t.Run("transaction create invalid transaction type", func(t *testing.T) {
 		serviceMock := transactionServiceMock{
 			create: func(tr *domain.Transaction) error {
@@ -94,6 +255,7 @@ func TestTransactionCreate(t *testing.T) {
 		tr := NewTransactionsHandler(serviceMock)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.POST("/test", tr.Process())
 
 		body := []byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"invalid","amount":10000.33}`)
@@ -126,6 +288,7 @@ func TestTransactionCreate(t *testing.T) {
 		tr := NewTransactionsHandler(serviceMock)
 
 		r := gin.Default()
+		r.Use(authenticated(admin))
 		r.POST("/test", tr.Process())
 
 		body := []byte(`{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"withdraw","amount":10000.33}`)
@@ -150,3 +313,107 @@ func TestTransactionCreate(t *testing.T) {
 		assert.Contains(t, responseMap["message"], "test error")
 	})
 }
+
+func TestTransactionList(t *testing.T) {
+	accountID := "d70d0a95-af7f-4098-8d81-caca1934e94d"
+	serve := func(serviceMock transactionServiceMock, url string) (int, map[string]interface{}) {
+		tr := NewTransactionsHandler(serviceMock)
+
+		r := gin.Default()
+		r.Use(authenticated(admin))
+		r.GET("/test/:id/transactions", tr.List())
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+
+		responseMap := make(map[string]interface{})
+		responseBody, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fail()
+		}
+		if err = json.Unmarshal(responseBody, &responseMap); err != nil {
+			t.Fail()
+		}
+		return w.Code, responseMap
+	}
+
+	t.Run("list success with filters", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				assert.Equal(t, accountID, filter.AccountID.String())
+				assert.Equal(t, []domain.EventType{domain.Deposit, domain.Transfer}, filter.Types)
+				assert.Equal(t, domain.Decimal("10.50"), filter.MinValue)
+				assert.Equal(t, domain.Decimal("500"), filter.MaxValue)
+				assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), *filter.From)
+				assert.Equal(t, time.Date(2023, 2, 1, 12, 0, 0, 0, time.UTC), *filter.To)
+				assert.Equal(t, "abc", filter.Cursor)
+				assert.Equal(t, 5, filter.Limit)
+				return domain.TransactionPage{
+					Transactions: []domain.Transaction{{ID: uuid.New(), Type: domain.Deposit}},
+					NextCursor:   "def",
+				}, nil
+			},
+		}
+
+		code, res := serve(serviceMock, "/test/"+accountID+"/transactions?type=deposit,transfer&min_amount=10.50&max_amount=500"+
+			"&from=2023-01-01&to=2023-02-01T12:00:00Z&cursor=abc&limit=5")
+
+		assert.Equal(t, http.StatusOK, code)
+		data := res["data"].(map[string]interface{})
+		assert.Len(t, data["transactions"], 1)
+		assert.Equal(t, "def", data["next_cursor"])
+	})
+	t.Run("list invalid query params", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				t.Fatal("service called with an invalid filter")
+				return domain.TransactionPage{}, nil
+			},
+		}
+
+		for _, query := range []string{"type=create", "min_amount=ten", "max_amount=1e3", "from=yesterday", "limit=0", "limit=101"} {
+			code, _ := serve(serviceMock, "/test/"+accountID+"/transactions?"+query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+		code, _ := serve(serviceMock, "/test/invalid/transactions")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+	t.Run("list invalid cursor", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				return domain.TransactionPage{}, custom_errors.ErrInvalidCursor
+			},
+		}
+
+		code, res := serve(serviceMock, "/test/"+accountID+"/transactions?cursor=bad")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, res["message"], custom_errors.ErrInvalidCursor.Error())
+	})
+	t.Run("list account not found", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				return domain.TransactionPage{}, custom_errors.ErrNotFound
+			},
+		}
+
+		code, _ := serve(serviceMock, "/test/"+accountID+"/transactions")
+
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+	t.Run("list internal server error", func(t *testing.T) {
+		serviceMock := transactionServiceMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				return domain.TransactionPage{}, errors.New("test error")
+			},
+		}
+
+		code, _ := serve(serviceMock, "/test/"+accountID+"/transactions")
+
+		assert.Equal(t, http.StatusInternalServerError, code)
+	})
+}
@@ -1,20 +1,46 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
+	"flag"
 	"github.com/lucaspichi06/xepelin-bank/cmd/server/handler"
 	"github.com/lucaspichi06/xepelin-bank/docs"
 	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/auth"
+	"github.com/lucaspichi06/xepelin-bank/internal/config"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/fx"
+	"github.com/lucaspichi06/xepelin-bank/internal/health"
+	"github.com/lucaspichi06/xepelin-bank/internal/idempotency"
+	"github.com/lucaspichi06/xepelin-bank/internal/ledger"
+	"github.com/lucaspichi06/xepelin-bank/internal/monitoring"
+	"github.com/lucaspichi06/xepelin-bank/internal/signing"
+	"github.com/lucaspichi06/xepelin-bank/internal/statement"
 	"github.com/lucaspichi06/xepelin-bank/internal/transaction"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
 	"github.com/lucaspichi06/xepelin-bank/pkg/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 )
 
 // @title Xepelin Bank
@@ -26,46 +52,314 @@ import (
 
 // @host      localhost:8080
 func main() {
-	// opening the DB
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/my_db", os.Getenv("DB_USER"), os.Getenv("DB_PASS"), os.Getenv("DB_HOST"), os.Getenv("DB_PORT")))
+	// the server is stopped by SIGTERM or SIGINT, once the requests in flight are drained
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// the migrate command applies the migrations of the schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = db.Ping()
+	// every layer logs JSON lines with the logger of the request context, or this one
+	appLogger := logger.New(os.Stdout, cfg.Log.Level)
+	logger.SetDefault(appLogger)
+
+	// the spans are only exported when an exporter is set, otherwise they are just propagated
+	tracerProvider, err := newTracerProvider(cfg.Tracing)
 	if err != nil {
 		log.Fatal(err)
 	}
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
 
-	//storage := store.NewSqlStore(db)
+	// opening the DB
+	storage, err := newBackend(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.RequestID(appLogger), middleware.Tracing(), middleware.AccessLog(), middleware.Metrics(), middleware.Timeout(cfg.Server.RequestTimeout))
 	r.GET("/ping", func(c *gin.Context) { c.String(200, "pong") })
 
+	// health section
+	healthHandler := handler.NewHealthHandler(health.NewChecker(storage.checks, health.DefaultCheckTimeout))
+	r.GET("/healthz", healthHandler.Live())
+	r.GET("/readyz", healthHandler.Ready())
+
+	// auth section
+	unitOfWork := storage.unitOfWork
+
+	// the bootstrap admin key is used to issue the first API keys
+	authService := auth.NewService(storage.auth, cfg.Auth.BootstrapToken)
+	tokenVerifier, err := newTokenVerifier(cfg.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authenticated := middleware.Authentication(authService, tokenVerifier)
+	apiKeyHandler := handler.NewAPIKeysHandler(unitOfWork)
+	r.POST("/api-keys", authenticated, middleware.RequireRole(domain.RoleAdmin), apiKeyHandler.Create())
+
+	// the transaction service also sweeps the balance of the accounts being closed
+	var rates domain.FXRateProvider
+	if cfg.FX.RatesFile != "" {
+		rates, err = fx.NewFileProvider(cfg.FX.RatesFile)
+	} else {
+		// without rates only transfers between accounts of the same currency are allowed
+		rates, err = fx.NewStaticProvider(nil)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	transactionService := transaction.NewService(unitOfWork, rates)
+
 	// account section
-	accountRepository := account.NewRepository(db)
-	accountService := account.NewService(accountRepository)
-	accountHandler := handler.NewAccountHandler(accountService)
+	accountService := account.NewService(storage.accounts, storage.accountEvents, storage.accountSnapshots, account.DefaultSnapshotEvery)
+	accountHandler := handler.NewAccountHandler(accountService, unitOfWork, transactionService)
 
 	acc := r.Group("/accounts")
 	{
-		acc.GET(":id/balance", accountHandler.GetBalance())
-		acc.POST("", middleware.Authentication(), accountHandler.Create())
+		acc.GET("", authenticated, middleware.RequireRole(domain.RoleAdmin, domain.RoleAuditor), accountHandler.List())
+		acc.GET(":id/balance", authenticated, accountHandler.GetBalance())
+		acc.POST("", authenticated, middleware.RequireRole(domain.RoleAdmin, domain.RoleAccountOwner), accountHandler.Create())
+		acc.POST(":id/snapshot", authenticated, accountHandler.Snapshot())
+		acc.PATCH(":id/status", authenticated, middleware.RequireRole(domain.RoleAdmin), accountHandler.UpdateStatus())
 	}
 
 	// transaction section
-	transactionRepository := transaction.NewRepository(db)
-	transactionService := transaction.NewService(transactionRepository, accountService)
 	transactionHandler := handler.NewTransactionsHandler(transactionService)
 
+	idempotencyStore := storage.idempotency
+	go purgeIdempotencyKeys(ctx, idempotencyStore, cfg.Idempotency.Retention)
+
+	// the transactions submitted by other services have to be signed when signing keys are set
+	processTransaction := []gin.HandlerFunc{authenticated}
+	if len(cfg.Signing.Keys) > 0 {
+		nonceStore := storage.nonces
+		go purgeNonces(ctx, nonceStore)
+		processTransaction = append(processTransaction, middleware.Signature(cfg.Signing.Keys, nonceStore, cfg.Signing.Tolerance))
+	}
+	// monitoring section
+	monitoringRules := monitoring.DefaultRules(cfg.Monitoring.LargeDeposit)
+	if cfg.Monitoring.RulesFile != "" {
+		monitoringRules, err = monitoring.LoadRules(cfg.Monitoring.RulesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	monitoringService := monitoring.NewService(storage.monitoring, monitoringRules)
+	alertHandler := handler.NewAlertsHandler(monitoringService)
+	r.GET("/alerts", authenticated, middleware.RequireRole(domain.RoleAdmin, domain.RoleAuditor), alertHandler.List())
+
+	processTransaction = append(processTransaction, middleware.Idempotency(idempotencyStore, cfg.Idempotency.Retention), middleware.Monitor(monitoringService), transactionHandler.Process())
+
 	tran := r.Group("/transactions")
 	{
-		tran.POST("", middleware.Authentication(), middleware.Logger(), transactionHandler.Process())
+		tran.POST("", processTransaction...)
 	}
+	acc.GET(":id/transactions", authenticated, transactionHandler.List())
+
+	// statement section
+	statementService := statement.NewService(unitOfWork)
+	statementHandler := handler.NewStatementsHandler(statementService)
+	acc.GET(":id/statement", authenticated, statementHandler.Export())
+
+	// ledger section
+	ledgerService := ledger.NewService(storage.ledger)
+	ledgerHandler := handler.NewLedgerHandler(ledgerService)
+	r.GET("/ledger/verify", authenticated, middleware.RequireRole(domain.RoleAdmin, domain.RoleAuditor), ledgerHandler.Verify())
+
+	// metrics section
+	prometheus.MustRegister(newMoneyHeld(storage.accounts))
+	r.GET("/metrics", authenticated, middleware.RequireRole(domain.RoleAdmin, domain.RoleAuditor), gin.WrapH(promhttp.Handler()))
 
 	// documentation section
-	docs.SwaggerInfo.Host = os.Getenv("HOST")
+	docs.SwaggerInfo.Host = cfg.Server.PublicHost
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	r.Run(":8080")
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	if err := serve(ctx, srv, cfg.Server); err != nil {
+		log.Fatal(err)
+	}
+	if err := storage.Close(); err != nil {
+		logger.Default().Error("the database could not be closed", "error", err)
+	}
+	if err := tracerProvider.Shutdown(context.Background()); err != nil {
+		logger.Default().Error("the spans could not be exported", "error", err)
+	}
+}
+
+// newTracerProvider starts the spans of the server, and exports them in batches to the
+// exporter of the config, if any
+func newTracerProvider(cfg config.Tracing) (*sdktrace.TracerProvider, error) {
+	if cfg.Exporter != "stdout" {
+		return sdktrace.NewTracerProvider(), nil
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter)), nil
+}
+
+// openDB opens the MySQL or SQLite database and checks it can be reached
+func openDB(cfg config.Database) (*sql.DB, error) {
+	driverName := cfg.Driver
+	if cfg.Driver == config.DriverSQLite {
+		driverName = "sqlite3"
+	}
+	db, err := sql.Open(driverName, cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// readHeaderTimeout is the time the clients have to send the headers of a request
+const readHeaderTimeout = 10 * time.Second
+
+// serve runs the server until the context is done. Then it stops accepting connections and
+// waits for the requests in flight, so their transactions are committed or rolled back, for
+// up to the shutdown timeout before closing the connections left
+func serve(ctx context.Context, srv *http.Server, cfg config.Server) error {
+	errs := make(chan error, 1)
+	go func() {
+		if cfg.TLS() {
+			errs <- srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			errs <- srv.ListenAndServe()
+		}
+	}()
+	logger.Default().Info("server started", "addr", cfg.Addr, "tls", cfg.TLS())
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Default().Info("server shutting down, draining the requests in flight", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Default().Error("the requests in flight could not be drained", "error", err)
+		return srv.Close()
+	}
+	logger.Default().Info("server stopped")
+	return nil
+}
+
+// moneyHeld collects the balances of the accounts, by currency, in major units. A
+// failed read fails the whole scrape, so it never gets a partial set
+type moneyHeld struct {
+	accounts account.Repository
+	desc     *prometheus.Desc
+}
+
+func newMoneyHeld(r account.Repository) prometheus.Collector {
+	return &moneyHeld{
+		accounts: r,
+		desc:     prometheus.NewDesc("bank_money_held", "Money held in the accounts, by currency.", []string{"currency"}, nil),
+	}
+}
+
+func (m moneyHeld) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.desc
+}
+
+func (m moneyHeld) Collect(ch chan<- prometheus.Metric) {
+	totals, err := m.accounts.Totals(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(m.desc, err)
+		return
+	}
+	for _, total := range totals {
+		value, err := strconv.ParseFloat(total.String(), 64)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(m.desc, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, value, total.Currency)
+	}
+}
+
+// purgeIdempotencyKeys deletes the expired idempotency keys every hour, until the context is done
+func purgeIdempotencyKeys(ctx context.Context, s idempotency.Store, retention time.Duration) {
+	every(ctx, time.Hour, func() {
+		if _, err := s.Purge(ctx, time.Now().UTC().Add(-retention)); err != nil {
+			logger.Default().Error("idempotency keys could not be purged", "error", err)
+		}
+	})
+}
+
+// purgeNonces deletes the expired request nonces every hour, until the context is done
+func purgeNonces(ctx context.Context, s signing.NonceStore) {
+	every(ctx, time.Hour, func() {
+		if _, err := s.Purge(ctx, time.Now().UTC()); err != nil {
+			logger.Default().Error("request nonces could not be purged", "error", err)
+		}
+	})
+}
+
+func every(ctx context.Context, d time.Duration, fn func()) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+// newTokenVerifier configures the JWT bearer authentication. It is disabled, returning nil,
+// when there is neither an HMAC secret nor a JWKS file
+func newTokenVerifier(cfg config.Auth) (auth.TokenVerifier, error) {
+	var sources []auth.KeySource
+	if cfg.JWTHMACSecret != "" {
+		sources = append(sources, auth.NewHMACKeySource([]byte(cfg.JWTHMACSecret)))
+	}
+	if cfg.JWTJWKSFile != "" {
+		jwks, err := auth.NewJWKSFileKeySource(cfg.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, jwks)
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	return auth.NewJWTVerifier(auth.JWTConfig{
+		Issuer:     cfg.JWTIssuer,
+		Audience:   cfg.JWTAudience,
+		ClockSkew:  cfg.JWTClockSkew,
+		Algorithms: cfg.JWTAlgorithms,
+	}, auth.KeySources(sources...)), nil
 }
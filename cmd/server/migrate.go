@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lucaspichi06/xepelin-bank/internal/config"
+	"github.com/lucaspichi06/xepelin-bank/internal/migrate"
+	"github.com/lucaspichi06/xepelin-bank/migrations"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage: xepelin-bank migrate COMMAND [flags]
+
+commands:
+  up              apply the pending migrations
+  down [N]        roll back the last N migrations, 1 by default
+  status          list the migrations and whether they are applied
+  force VERSION   record the migrations up to VERSION as applied, and the later ones as not
+                  applied, without running them. 0 records none of them as applied
+
+The flags are the ones of the server, only the database settings are used.`
+
+// runMigrate runs the migrate command, e.g. migrate down 2 -config config.yaml. The words
+// before the first flag are the command and its argument, the flags are read by config.Load
+func runMigrate(ctx context.Context, args []string) error {
+	var command []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = append(command, args[0]), args[1:]
+	}
+	if len(command) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	cfg, err := config.Load(args, os.Getenv)
+	if err != nil {
+		return err
+	}
+	logger.SetDefault(logger.New(os.Stdout, cfg.Log.Level))
+	if cfg.Database.Driver == config.DriverMemory {
+		return errors.New("the memory driver has no schema to migrate")
+	}
+
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := newMigrator(db, cfg.Database.Driver)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case command[0] == "up" && len(command) == 1:
+		applied, err := migrator.Up(ctx)
+		logger.Default().Info("migrations applied", "count", len(applied))
+		return err
+	case command[0] == "down" && len(command) <= 2:
+		n := 1
+		if len(command) == 2 {
+			if n, err = strconv.Atoi(command[1]); err != nil {
+				return fmt.Errorf("invalid number of migrations %q", command[1])
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, n)
+		logger.Default().Info("migrations rolled back", "count", len(rolledBack))
+		return err
+	case command[0] == "force" && len(command) == 2:
+		version, err := strconv.ParseInt(command[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", command[1])
+		}
+		return migrator.Force(ctx, version)
+	case command[0] == "status" && len(command) == 1:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(os.Stdout, statuses)
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// newMigrator loads the migrations of the database driver embedded in the binary
+func newMigrator(db *sql.DB, driver string) (migrate.Migrator, error) {
+	fsys, dialect := migrations.MySQL, migrate.MySQL
+	switch driver {
+	case config.DriverPostgres:
+		fsys, dialect = migrations.Postgres, migrate.Postgres
+	case config.DriverSQLite:
+		fsys, dialect = migrations.SQLite, migrate.SQLite
+	}
+	schema, err := migrate.Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(db, dialect, schema, migrate.DefaultLockTimeout), nil
+}
+
+func printStatus(w io.Writer, statuses []migrate.Status) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		status, applied := "pending", ""
+		if s.Applied {
+			status, applied = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		switch {
+		case s.Dirty:
+			status = "dirty"
+		case s.Modified:
+			status = "modified"
+		case s.Unknown:
+			status = "applied by a newer version"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, status, applied)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,204 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// backend is a storage backend of the accounts
+type backend struct {
+	accounts  Repository
+	events    EventStore
+	snapshots SnapshotStore
+}
+
+// eachBackend runs the test on every storage backend, so they all behave the same. MySQL
+// and PostgreSQL only run when storetest.MySQLEnv and storetest.PostgresEnv are set
+func eachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, backend{NewMemoryRepository(), NewMemoryEventStore(), NewMemorySnapshotStore()})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db := storetest.SQLite(t)
+		test(t, backend{NewSQLiteRepository(db), NewEventStore(db), NewSnapshotStore(db)})
+	})
+	t.Run("mysql", func(t *testing.T) {
+		db := storetest.MySQL(t)
+		test(t, backend{NewRepository(db), NewEventStore(db), NewSnapshotStore(db)})
+	})
+	t.Run("postgres", func(t *testing.T) {
+		db := storetest.Postgres(t)
+		test(t, backend{NewPostgresRepository(db), NewEventStore(store.Postgres(db)), NewSnapshotStore(store.Postgres(db))})
+	})
+}
+
+func TestRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, b backend) {
+		usd := domain.Account{ID: uuid.New(), Name: "usd", Balance: domain.NewMoney(1000, "USD"), Status: domain.AccountActive, Version: 1}
+		clp := domain.Account{ID: uuid.New(), Name: "clp", Balance: domain.NewMoney(5000, "CLP"), Version: 1}
+		other := domain.Account{ID: uuid.New(), Name: "other", Balance: domain.NewMoney(250, "USD"), Version: 1}
+		for _, account := range []domain.Account{usd, clp, other} {
+			assert.NoError(t, b.accounts.Create(ctx, account))
+		}
+		assert.True(t, store.IsDuplicate(b.accounts.Create(ctx, usd)))
+
+		read, err := b.accounts.Read(ctx, usd.ID)
+		assert.NoError(t, err)
+		usd.Currency = "USD"
+		assert.Equal(t, usd, read)
+
+		usd.Name, usd.Balance, usd.Status, usd.Version = "renamed", domain.NewMoney(1500, "USD"), domain.AccountFrozen, 2
+		assert.NoError(t, b.accounts.Update(ctx, usd))
+		read, err = b.accounts.ReadForUpdate(ctx, usd.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, usd, read)
+
+		_, err = b.accounts.Read(ctx, uuid.New())
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = b.accounts.ReadForUpdate(ctx, uuid.New())
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		totals, err := b.accounts.Totals(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Money{domain.NewMoney(5000, "CLP"), domain.NewMoney(1750, "USD")}, totals)
+	})
+}
+
+func TestEventStoreContract(t *testing.T) {
+	ctx := context.Background()
+	timestamp := time.Date(2023, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	eachBackend(t, func(t *testing.T, b backend) {
+		id, counterparty := uuid.New(), uuid.New()
+		created := domain.AccountEvent{AccountID: id, Sequence: 1, Type: domain.Create, Amount: domain.NewMoney(1000, "USD"), Name: "name", Timestamp: timestamp}
+		deposited := domain.AccountEvent{AccountID: id, Sequence: 2, Type: domain.Deposit, Amount: domain.NewMoney(500, "USD"), Timestamp: timestamp.Add(time.Second)}
+		received := domain.AccountEvent{AccountID: id, Sequence: 3, Type: domain.TransferIn, Amount: domain.NewMoney(200, "USD"), CounterpartyID: &counterparty, Timestamp: timestamp.Add(time.Minute)}
+		closed := domain.AccountEvent{AccountID: id, Sequence: 4, Type: domain.StatusChange, Amount: domain.NewMoney(0, "USD"), Status: domain.AccountClosed, Timestamp: timestamp.Add(time.Hour)}
+		assert.NoError(t, b.events.Append(ctx, created, deposited))
+		assert.NoError(t, b.events.Append(ctx, received, closed))
+		assert.NoError(t, b.events.Append(ctx, domain.AccountEvent{AccountID: uuid.New(), Sequence: 1, Type: domain.Create, Amount: domain.NewMoney(0, "USD"), Timestamp: timestamp}))
+
+		events, err := b.events.Load(ctx, id, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.AccountEvent{created, deposited, received, closed}, events)
+
+		events, err = b.events.Load(ctx, id, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.AccountEvent{received, closed}, events)
+
+		events, err = b.events.Load(ctx, uuid.New(), 0)
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+
+		stale := deposited
+		stale.Amount = domain.NewMoney(1, "USD")
+		assert.True(t, store.IsDuplicate(b.events.Append(ctx, stale)))
+	})
+}
+
+func TestSnapshotStoreContract(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, b backend) {
+		id := uuid.New()
+		_, found, err := b.snapshots.Latest(ctx, id)
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		first := domain.Account{ID: id, Name: "name", Currency: "USD", Balance: domain.NewMoney(1000, "USD"), Version: 100}
+		latest := domain.Account{ID: id, Name: "name", Currency: "USD", Balance: domain.NewMoney(3000, "USD"), Status: domain.AccountFrozen, Version: 200}
+		assert.NoError(t, b.snapshots.Save(ctx, latest))
+		assert.NoError(t, b.snapshots.Save(ctx, first))
+		assert.True(t, store.IsDuplicate(b.snapshots.Save(ctx, first)))
+
+		snapshot, found, err := b.snapshots.Latest(ctx, id)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, latest, snapshot)
+	})
+}
+
+func TestListContract(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, b backend) {
+		accounts := []domain.Account{
+			{ID: uuid.New(), Name: "alpha", Balance: domain.NewMoney(500, "USD"), Status: domain.AccountActive, Version: 1},
+			{ID: uuid.New(), Name: "Alpine", Balance: domain.NewMoney(3000, "USD"), Status: domain.AccountFrozen, Version: 1},
+			{ID: uuid.New(), Name: "Beta", Balance: domain.NewMoney(3000, "USD"), Status: domain.AccountActive, Version: 1},
+			{ID: uuid.New(), Name: "gamma", Balance: domain.NewMoney(100000, "CLP"), Status: domain.AccountActive, Version: 1},
+			{ID: uuid.New(), Name: "al_ias", Balance: domain.NewMoney(0, "USD"), Status: domain.AccountClosed, Version: 1},
+			{ID: uuid.New(), Name: "Zeta", Balance: domain.NewMoney(0, "CLP"), Status: domain.AccountFrozen, Version: 1},
+			{ID: uuid.New(), Name: "ZETA", Balance: domain.NewMoney(0, "CLP"), Status: domain.AccountFrozen, Version: 1},
+		}
+		for i := range accounts {
+			assert.NoError(t, b.accounts.Create(ctx, accounts[i]))
+			accounts[i].Currency = accounts[i].Balance.Currency
+		}
+		alpha, alpine, beta, gamma, alias := accounts[0], accounts[1], accounts[2], accounts[3], accounts[4]
+		// the names equal but for the case are sorted by id
+		zetas := []domain.Account{accounts[5], accounts[6]}
+		if accounts[6].ID.String() < accounts[5].ID.String() {
+			zetas = []domain.Account{accounts[6], accounts[5]}
+		}
+
+		// list pages through every account and returns them in order
+		list := func(t *testing.T, filter domain.AccountFilter) []domain.Account {
+			var listed []domain.Account
+			for {
+				page, err := b.accounts.List(ctx, filter)
+				if !assert.NoError(t, err) {
+					return nil
+				}
+				listed = append(listed, page.Accounts...)
+				if page.NextCursor == "" {
+					return listed
+				}
+				filter.Cursor = page.NextCursor
+			}
+		}
+
+		t.Run("list sorted by name ignoring the case", func(t *testing.T) {
+			assert.Equal(t, []domain.Account{alias, alpha, alpine, beta, gamma, zetas[0], zetas[1]}, list(t, domain.AccountFilter{Limit: 2}))
+			assert.Equal(t, []domain.Account{zetas[1], zetas[0], gamma, beta, alpine, alpha, alias}, list(t, domain.AccountFilter{Descending: true, Limit: 2}))
+		})
+		t.Run("list sorted by balance", func(t *testing.T) {
+			sameBalance := []domain.Account{alpine, beta}
+			if beta.ID.String() < alpine.ID.String() {
+				sameBalance = []domain.Account{beta, alpine}
+			}
+			usd := domain.AccountFilter{Currency: "USD", Sort: domain.SortByBalance, Limit: 1}
+			assert.Equal(t, append([]domain.Account{alias, alpha}, sameBalance...), list(t, usd))
+		})
+		t.Run("list by name prefix", func(t *testing.T) {
+			assert.Equal(t, []domain.Account{alpha, alpine}, list(t, domain.AccountFilter{NamePrefix: "ALP"}))
+			assert.Equal(t, []domain.Account{alias}, list(t, domain.AccountFilter{NamePrefix: "al_"}))
+			assert.Empty(t, list(t, domain.AccountFilter{NamePrefix: "al%"}))
+		})
+		t.Run("list filtered", func(t *testing.T) {
+			min, max := domain.NewMoney(500, "USD"), domain.NewMoney(3000, "USD")
+			filter := domain.AccountFilter{
+				Statuses:   []domain.AccountStatus{domain.AccountActive, domain.AccountClosed},
+				Currency:   "USD",
+				MinBalance: &min,
+				MaxBalance: &max,
+			}
+			assert.Equal(t, []domain.Account{alpha, beta}, list(t, filter))
+		})
+		t.Run("list with an invalid cursor", func(t *testing.T) {
+			_, err := b.accounts.List(ctx, domain.AccountFilter{Cursor: "invalid"})
+			assert.Equal(t, custom_errors.ErrInvalidCursor, err)
+		})
+	})
+}
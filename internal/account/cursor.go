@@ -0,0 +1,62 @@
+package account
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// cursor is the position of the last account of a page. Accounts are sorted by the column
+// of the filter and then by id, so the next page starts right after it
+type cursor struct {
+	Name    string
+	Balance int64
+	ID      uuid.UUID
+}
+
+// encodeCursor keeps the sort of the page as well, so the cursor can not be used to page
+// through a differently sorted list
+func encodeCursor(filter domain.AccountFilter, acc domain.Account) string {
+	value := acc.Name
+	if filter.Sort == domain.SortByBalance {
+		value = strconv.FormatInt(acc.Balance.Amount, 10)
+	}
+	raw := sortKey(filter) + "|" + acc.ID.String() + "|" + value
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(filter domain.AccountFilter) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return cursor{}, custom_errors.ErrInvalidCursor
+	}
+	// the name goes last, as it can have any character
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 || parts[0] != sortKey(filter) {
+		return cursor{}, custom_errors.ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return cursor{}, custom_errors.ErrInvalidCursor
+	}
+	c := cursor{ID: id}
+	if filter.Sort == domain.SortByBalance {
+		if c.Balance, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+			return cursor{}, custom_errors.ErrInvalidCursor
+		}
+		return c, nil
+	}
+	c.Name = parts[2]
+	return c, nil
+}
+
+func sortKey(filter domain.AccountFilter) string {
+	if filter.Descending {
+		return "-" + string(filter.Sort)
+	}
+	return string(filter.Sort)
+}
@@ -0,0 +1,61 @@
+package account
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// EventStore is the append-only log of account events, the source of truth of every account
+type EventStore interface {
+	Append(ctx context.Context, events ...domain.AccountEvent) error
+	Load(ctx context.Context, id uuid.UUID, after int64) ([]domain.AccountEvent, error)
+}
+
+type eventStore struct {
+	db store.DBTX
+}
+
+func NewEventStore(db store.DBTX) EventStore {
+	return &eventStore{
+		db: store.Instrument(db, store.SystemOf(db), "account_events"),
+	}
+}
+
+// Append stores the events. The (account_id, sequence) primary key rejects an event
+// whose sequence was already used, so a stream can never be written from a stale state
+func (r eventStore) Append(ctx context.Context, events ...domain.AccountEvent) error {
+	query := "INSERT INTO account_events (account_id, sequence, type, amount, currency, name, counterparty_id, status, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	for _, e := range events {
+		_, err := r.db.ExecContext(ctx, query, e.AccountID, e.Sequence, e.Type, e.Amount.Amount, e.Amount.Currency, e.Name, e.CounterpartyID, e.Status, e.Timestamp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Load returns the events of the account with a sequence greater than after, in sequence order
+func (r eventStore) Load(ctx context.Context, id uuid.UUID, after int64) ([]domain.AccountEvent, error) {
+	query := "SELECT account_id, sequence, type, amount, currency, name, counterparty_id, status, timestamp FROM account_events WHERE account_id = ? AND sequence > ? ORDER BY sequence;"
+	rows, err := r.db.QueryContext(ctx, query, id, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.AccountEvent
+	for rows.Next() {
+		var e domain.AccountEvent
+		err = rows.Scan(&e.AccountID, &e.Sequence, &e.Type, &e.Amount.Amount, &e.Amount.Currency, &e.Name, &e.CounterpartyID, &e.Status, &e.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		e.Timestamp = e.Timestamp.UTC()
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,138 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var eventColumns = []string{"account_id", "sequence", "type", "amount", "currency", "name", "counterparty_id", "status", "timestamp"}
+
+func TestAppendEvents(t *testing.T) {
+	t.Run("append events success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		es := NewEventStore(db)
+
+		id := uuid.New()
+		mock.ExpectExec("INSERT INTO account_events").WithArgs(
+			id, 1, domain.Create, 0, "USD", "test", nil, "", sqlmock.AnyArg(),
+		).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO account_events").WithArgs(
+			id, 2, domain.Deposit, 10000, "USD", "", nil, "", sqlmock.AnyArg(),
+		).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err = es.Append(context.Background(),
+			domain.AccountEvent{AccountID: id, Sequence: 1, Type: domain.Create, Name: "test", Amount: domain.NewMoney(0, "USD")},
+			domain.AccountEvent{AccountID: id, Sequence: 2, Type: domain.Deposit, Amount: domain.NewMoney(10000, "USD")},
+		)
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("append events stops at the first error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		es := NewEventStore(db)
+
+		mock.ExpectExec("INSERT INTO account_events").
+			WillReturnError(errors.New("test error"))
+
+		id := uuid.New()
+		err = es.Append(context.Background(),
+			domain.AccountEvent{AccountID: id, Sequence: 1, Type: domain.Create},
+			domain.AccountEvent{AccountID: id, Sequence: 2, Type: domain.Deposit},
+		)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("append events duplicated sequence", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		es := NewEventStore(db)
+
+		mock.ExpectExec("INSERT INTO account_events").
+			WillReturnError(errors.New("Duplicate entry for key 'PRIMARY'"))
+
+		err = es.Append(context.Background(), domain.AccountEvent{AccountID: uuid.New(), Sequence: 2, Type: domain.Deposit})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "Duplicate entry")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
+
+func TestLoadEvents(t *testing.T) {
+	t.Run("load events success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		es := NewEventStore(db)
+
+		id, counterparty := uuid.New(), uuid.New()
+		now := time.Now()
+		mock.ExpectQuery("SELECT (.+) FROM account_events WHERE account_id = \\? AND sequence > \\? ORDER BY sequence").
+			WithArgs(id, 1).
+			WillReturnRows(sqlmock.NewRows(eventColumns).
+				AddRow(id.String(), 2, "deposit", 10000, "USD", "", nil, "", now).
+				AddRow(id.String(), 3, "transfer_out", 2500, "USD", "", counterparty.String(), "", now))
+
+		events, err := es.Load(context.Background(), id, 1)
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, int64(2), events[0].Sequence)
+		assert.Equal(t, domain.Deposit, events[0].Type)
+		assert.Equal(t, domain.NewMoney(10000, "USD"), events[0].Amount)
+		assert.Nil(t, events[0].CounterpartyID)
+		assert.Equal(t, domain.TransferOut, events[1].Type)
+		assert.Equal(t, counterparty, *events[1].CounterpartyID)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("load events query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		es := NewEventStore(db)
+
+		mock.ExpectQuery("SELECT (.+) FROM account_events").
+			WillReturnError(errors.New("test error"))
+
+		events, err := es.Load(context.Background(), uuid.New(), 0)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+		assert.Nil(t, events)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
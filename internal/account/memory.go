@@ -0,0 +1,281 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+type memoryRepository struct {
+	mu       sync.RWMutex
+	accounts map[uuid.UUID]domain.Account
+}
+
+// NewMemoryRepository keeps the accounts in memory. Its units of work run one at a time,
+// so ReadForUpdate does not need to lock anything
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		accounts: make(map[uuid.UUID]domain.Account),
+	}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, account domain.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[account.ID]; ok {
+		return store.ErrDuplicate
+	}
+	account.Currency = account.Balance.Currency
+	r.accounts[account.ID] = account
+	store.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.accounts, account.ID)
+	})
+	return nil
+}
+
+func (r *memoryRepository) Read(_ context.Context, id uuid.UUID) (domain.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return domain.Account{}, sql.ErrNoRows
+	}
+	return account, nil
+}
+
+func (r *memoryRepository) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return r.Read(ctx, id)
+}
+
+// Update changes the name, balance, status and version of the account, as the SQL repository does
+func (r *memoryRepository) Update(ctx context.Context, account domain.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.accounts[account.ID]
+	if !ok {
+		return nil
+	}
+	updated := previous
+	updated.Name, updated.Balance.Amount, updated.Status, updated.Version = account.Name, account.Balance.Amount, account.Status, account.Version
+	r.accounts[account.ID] = updated
+	store.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.accounts[account.ID] = previous
+	})
+	return nil
+}
+
+func (r *memoryRepository) Totals(_ context.Context) ([]domain.Money, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sums := make(map[string]int64)
+	for _, account := range r.accounts {
+		sums[account.Balance.Currency] += account.Balance.Amount
+	}
+	var totals []domain.Money
+	for currency, amount := range sums {
+		totals = append(totals, domain.NewMoney(amount, currency))
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+	return totals, nil
+}
+
+func (r *memoryRepository) List(_ context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if filter.Sort != domain.SortByBalance {
+		filter.Sort = domain.SortByName
+	}
+
+	var last *domain.Account
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter)
+		if err != nil {
+			return domain.AccountPage{}, err
+		}
+		last = &domain.Account{ID: c.ID, Name: c.Name, Balance: domain.Money{Amount: c.Balance}}
+	}
+	// sorted reports whether a comes before b in the order of the filter
+	sorted := func(a, b domain.Account) bool {
+		if filter.Descending {
+			a, b = b, a
+		}
+		return sortsBefore(filter.Sort, a, b)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page := domain.AccountPage{Accounts: []domain.Account{}}
+	for _, account := range r.accounts {
+		switch {
+		case filter.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(account.Name), strings.ToLower(filter.NamePrefix)),
+			len(filter.Statuses) > 0 && !hasStatus(filter.Statuses, account.Status),
+			filter.Currency != "" && account.Balance.Currency != filter.Currency,
+			filter.MinBalance != nil && account.Balance.Amount < filter.MinBalance.Amount,
+			filter.MaxBalance != nil && account.Balance.Amount > filter.MaxBalance.Amount,
+			last != nil && !sorted(*last, account):
+			continue
+		}
+		page.Accounts = append(page.Accounts, account)
+	}
+	sort.Slice(page.Accounts, func(i, j int) bool { return sorted(page.Accounts[i], page.Accounts[j]) })
+
+	if len(page.Accounts) > limit {
+		page.Accounts = page.Accounts[:limit]
+		page.NextCursor = encodeCursor(filter, page.Accounts[limit-1])
+	}
+	return page, nil
+}
+
+// sortsBefore reports whether a sorts before b by the column, and then by id as the
+// database sorts them. The names are compared lowered, byte by byte
+func sortsBefore(column domain.AccountSort, a, b domain.Account) bool {
+	switch {
+	case column == domain.SortByBalance && a.Balance.Amount != b.Balance.Amount:
+		return a.Balance.Amount < b.Balance.Amount
+	case column == domain.SortByName && strings.ToLower(a.Name) != strings.ToLower(b.Name):
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+func hasStatus(statuses []domain.AccountStatus, status domain.AccountStatus) bool {
+	for _, candidate := range statuses {
+		if candidate == status {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryEventStore struct {
+	mu sync.RWMutex
+	// streams holds the events of every account in sequence order
+	streams map[uuid.UUID][]domain.AccountEvent
+}
+
+func NewMemoryEventStore() EventStore {
+	return &memoryEventStore{
+		streams: make(map[uuid.UUID][]domain.AccountEvent),
+	}
+}
+
+// Append stores the events, rejecting the ones whose sequence was already used as the
+// primary key of the SQL table does
+func (s *memoryEventStore) Append(ctx context.Context, events ...domain.AccountEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range events {
+		stream := s.streams[e.AccountID]
+		i := sort.Search(len(stream), func(i int) bool { return stream[i].Sequence >= e.Sequence })
+		if i < len(stream) && stream[i].Sequence == e.Sequence {
+			return store.ErrDuplicate
+		}
+
+		e.Timestamp = e.Timestamp.UTC()
+		stream = append(stream, domain.AccountEvent{})
+		copy(stream[i+1:], stream[i:])
+		stream[i] = e
+		s.streams[e.AccountID] = stream
+
+		accountID, sequence := e.AccountID, e.Sequence
+		store.OnRollback(ctx, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			stream := s.streams[accountID]
+			for i := range stream {
+				if stream[i].Sequence == sequence {
+					s.streams[accountID] = append(stream[:i:i], stream[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+	return nil
+}
+
+func (s *memoryEventStore) Load(_ context.Context, id uuid.UUID, after int64) ([]domain.AccountEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []domain.AccountEvent
+	for _, e := range s.streams[id] {
+		if e.Sequence > after {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+type memorySnapshotStore struct {
+	mu sync.RWMutex
+	// snapshots holds the snapshots of every account in sequence order
+	snapshots map[uuid.UUID][]domain.Account
+}
+
+func NewMemorySnapshotStore() SnapshotStore {
+	return &memorySnapshotStore{
+		snapshots: make(map[uuid.UUID][]domain.Account),
+	}
+}
+
+func (s *memorySnapshotStore) Save(ctx context.Context, account domain.Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshots := s.snapshots[account.ID]
+	i := sort.Search(len(snapshots), func(i int) bool { return snapshots[i].Version >= account.Version })
+	if i < len(snapshots) && snapshots[i].Version == account.Version {
+		return store.ErrDuplicate
+	}
+
+	account.Currency = account.Balance.Currency
+	snapshots = append(snapshots, domain.Account{})
+	copy(snapshots[i+1:], snapshots[i:])
+	snapshots[i] = account
+	s.snapshots[account.ID] = snapshots
+
+	store.OnRollback(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		snapshots := s.snapshots[account.ID]
+		for i := range snapshots {
+			if snapshots[i].Version == account.Version {
+				s.snapshots[account.ID] = append(snapshots[:i:i], snapshots[i+1:]...)
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (s *memorySnapshotStore) Latest(_ context.Context, id uuid.UUID) (domain.Account, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshots := s.snapshots[id]
+	if len(snapshots) == 0 {
+		return domain.Account{}, false, nil
+	}
+	return snapshots[len(snapshots)-1], true, nil
+}
@@ -1,34 +1,81 @@
 package account
 
 import (
-	"database/sql"
+	"context"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"strings"
 )
 
+// Account pages are limited to these sizes
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Repository stores the accounts read model: the current state of every account,
+// projected from its events so it can be queried and locked cheaply
 type Repository interface {
-	Create(account domain.Account) error
-	Read(id uuid.UUID) (domain.Account, error)
-	Update(account domain.Account) error
+	Create(ctx context.Context, account domain.Account) error
+	Read(ctx context.Context, id uuid.UUID) (domain.Account, error)
+	ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error)
+	Update(ctx context.Context, account domain.Account) error
+	// Totals adds up the balances of every account, by currency
+	Totals(ctx context.Context) ([]domain.Money, error)
+	// List returns a page of the accounts selected by the filter
+	List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error)
 }
 
 type repository struct {
-	db *sql.DB
+	db store.DBTX
+	// forUpdate is the clause that locks the rows read, empty when the database locks
+	// it all for the whole transaction
+	forUpdate string
+	// nameLike matches the names with a LIKE pattern, ignoring the case
+	nameLike string
+	// nameOrder lowers a name, given as a format of its column or placeholder, into the
+	// key the names are sorted by. The keys are compared byte by byte on every database,
+	// as the in-memory repository compares them
+	nameOrder string
 }
 
-func NewRepository(db *sql.DB) Repository {
+func NewRepository(db store.DBTX) Repository {
 	return &repository{
-		db: db,
+		db:        store.Instrument(db, store.SystemMySQL, "accounts"),
+		forUpdate: " FOR UPDATE",
+		// the collation of the table already ignores the case
+		nameLike:  "name LIKE ? ESCAPE '!'",
+		nameOrder: "LOWER(%s) COLLATE utf8mb4_bin",
 	}
 }
 
-func (r repository) Create(account domain.Account) error {
-	query := "INSERT INTO accounts (id, name, balance) VALUES (?, ?, ?);"
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
+// NewPostgresRepository works on PostgreSQL, numbering the placeholders of the statements
+func NewPostgresRepository(db store.DBTX) Repository {
+	return &repository{
+		db:        store.Instrument(store.Postgres(db), store.SystemPostgreSQL, "accounts"),
+		forUpdate: " FOR UPDATE",
+		// LIKE is case sensitive, the lowered names are indexed for the search instead
+		nameLike:  "LOWER(name) LIKE LOWER(?) ESCAPE '!'",
+		nameOrder: `LOWER(%s) COLLATE "C"`,
+	}
+}
+
+// NewSQLiteRepository works on SQLite, which has no row locks: its write transactions
+// take the lock of the whole database when they begin, so they are serialized anyway
+func NewSQLiteRepository(db store.DBTX) Repository {
+	return &repository{
+		db: store.Instrument(db, store.SystemSQLite, "accounts"),
+		// LIKE ignores the case of ASCII letters, and LOWER only lowers them too
+		nameLike:  "name LIKE ? ESCAPE '!'",
+		nameOrder: "LOWER(%s)",
 	}
-	res, err := stmt.Exec(account.ID, account.Name, account.Balance)
+}
+
+func (r repository) Create(ctx context.Context, account domain.Account) error {
+	query := "INSERT INTO accounts (id, name, balance, currency, status, version) VALUES (?, ?, ?, ?, ?, ?);"
+	res, err := r.db.ExecContext(ctx, query, account.ID, account.Name, account.Balance.Amount, account.Balance.Currency, account.Status, account.Version)
 	if err != nil {
 		return err
 	}
@@ -40,24 +87,35 @@ func (r repository) Create(account domain.Account) error {
 	return nil
 }
 
-func (r repository) Read(id uuid.UUID) (domain.Account, error) {
+func (r repository) Read(ctx context.Context, id uuid.UUID) (domain.Account, error) {
 	var account domain.Account
-	query := "SELECT * FROM accounts WHERE id = ?;"
-	row := r.db.QueryRow(query, id)
-	err := row.Scan(&account.ID, &account.Name, &account.Balance)
+	query := "SELECT id, name, balance, currency, status, version FROM accounts WHERE id = ?;"
+	row := r.db.QueryRowContext(ctx, query, id)
+	err := row.Scan(&account.ID, &account.Name, &account.Balance.Amount, &account.Balance.Currency, &account.Status, &account.Version)
 	if err != nil {
 		return domain.Account{}, err
 	}
+	account.Currency = account.Balance.Currency
 	return account, nil
 }
 
-func (r repository) Update(account domain.Account) error {
-	query := "UPDATE accounts SET name = ?, balance = ? WHERE id = ?;"
-	stmt, err := r.db.Prepare(query)
+// ReadForUpdate reads the account locking its row until the surrounding
+// transaction finishes, so concurrent balance changes are serialized
+func (r repository) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	var account domain.Account
+	query := "SELECT id, name, balance, currency, status, version FROM accounts WHERE id = ?" + r.forUpdate + ";"
+	row := r.db.QueryRowContext(ctx, query, id)
+	err := row.Scan(&account.ID, &account.Name, &account.Balance.Amount, &account.Balance.Currency, &account.Status, &account.Version)
 	if err != nil {
-		return err
+		return domain.Account{}, err
 	}
-	res, err := stmt.Exec(account.Name, account.Balance, account.ID)
+	account.Currency = account.Balance.Currency
+	return account, nil
+}
+
+func (r repository) Update(ctx context.Context, account domain.Account) error {
+	query := "UPDATE accounts SET name = ?, balance = ?, status = ?, version = ? WHERE id = ?;"
+	res, err := r.db.ExecContext(ctx, query, account.Name, account.Balance.Amount, account.Status, account.Version, account.ID)
 	if err != nil {
 		return err
 	}
@@ -68,3 +126,119 @@ func (r repository) Update(account domain.Account) error {
 
 	return nil
 }
+
+func (r repository) Totals(ctx context.Context) ([]domain.Money, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT currency, COALESCE(SUM(balance), 0) FROM accounts GROUP BY currency ORDER BY currency;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []domain.Money
+	for rows.Next() {
+		var total domain.Money
+		if err = rows.Scan(&total.Currency, &total.Amount); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+func (r repository) List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if filter.Sort != domain.SortByBalance {
+		filter.Sort = domain.SortByName
+	}
+
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if filter.NamePrefix != "" {
+		conditions = append(conditions, r.nameLike)
+		args = append(args, likePrefix(filter.NamePrefix))
+	}
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			placeholders[i] = "?"
+			args = append(args, status)
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "currency = ?")
+		args = append(args, filter.Currency)
+	}
+	if filter.MinBalance != nil {
+		conditions = append(conditions, "balance >= ?")
+		args = append(args, filter.MinBalance.Amount)
+	}
+	if filter.MaxBalance != nil {
+		conditions = append(conditions, "balance <= ?")
+		args = append(args, filter.MaxBalance.Amount)
+	}
+
+	// the names are sorted ignoring the case
+	column, placeholder := fmt.Sprintf(r.nameOrder, "name"), fmt.Sprintf(r.nameOrder, "?")
+	if filter.Sort == domain.SortByBalance {
+		column, placeholder = "balance", "?"
+	}
+	direction, after := "", ">"
+	if filter.Descending {
+		direction, after = " DESC", "<"
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter)
+		if err != nil {
+			return domain.AccountPage{}, err
+		}
+		var value interface{} = c.Name
+		if filter.Sort == domain.SortByBalance {
+			value = c.Balance
+		}
+		conditions = append(conditions, "("+column+" "+after+" "+placeholder+" OR ("+column+" = "+placeholder+" AND id "+after+" ?))")
+		args = append(args, value, value, c.ID)
+	}
+	// one extra row tells whether there is a next page
+	args = append(args, limit+1)
+
+	query := "SELECT id, name, balance, currency, status, version FROM accounts WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + column + direction + ", id" + direction + " LIMIT ?;"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return domain.AccountPage{}, err
+	}
+	defer rows.Close()
+
+	page := domain.AccountPage{Accounts: []domain.Account{}}
+	for rows.Next() {
+		var account domain.Account
+		err = rows.Scan(&account.ID, &account.Name, &account.Balance.Amount, &account.Balance.Currency, &account.Status, &account.Version)
+		if err != nil {
+			return domain.AccountPage{}, err
+		}
+		account.Currency = account.Balance.Currency
+		page.Accounts = append(page.Accounts, account)
+	}
+	if err = rows.Err(); err != nil {
+		return domain.AccountPage{}, err
+	}
+
+	if len(page.Accounts) > limit {
+		page.Accounts = page.Accounts[:limit]
+		page.NextCursor = encodeCursor(filter, page.Accounts[limit-1])
+	}
+	return page, nil
+}
+
+// likePrefix is the LIKE pattern of the names starting with the prefix, escaping its
+// wildcards with !
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
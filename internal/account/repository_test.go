@@ -1,14 +1,21 @@
 package account
 
 import (
+	"context"
 	_ "database/sql"
 	"errors"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestCreateAccount(t *testing.T) {
@@ -21,23 +28,48 @@ func TestCreateAccount(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectPrepare("INSERT INTO accounts").ExpectExec().WithArgs(
-			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+		mock.ExpectExec("INSERT INTO accounts").WithArgs(
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 
 		account := domain.Account{
 			ID:      uuid.New(),
 			Name:    "test",
-			Balance: 100.0,
+			Balance: domain.NewMoney(10000, domain.DefaultCurrency),
 		}
 
-		err = repo.Create(account)
+		err = repo.Create(context.Background(), account)
 		assert.NoError(t, err)
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
-	t.Run("create account prepare error", func(t *testing.T) {
+	t.Run("create account is traced", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+		defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("INSERT INTO accounts").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err = NewRepository(db).Create(context.Background(), domain.Account{ID: uuid.New(), Name: "test", Balance: domain.NewMoney(0, "USD")})
+		assert.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		if assert.Len(t, spans, 1) {
+			assert.Equal(t, "sql.exec", spans[0].Name)
+			assert.Contains(t, spans[0].Attributes, attribute.String("db.repository", "accounts"))
+			assert.Contains(t, spans[0].Attributes, attribute.String("db.statement",
+				"INSERT INTO accounts (id, name, balance, currency, status, version) VALUES (?, ?, ?, ?, ?, ?);"))
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("create account exec error", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
@@ -46,23 +78,27 @@ func TestCreateAccount(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectPrepare("INSERT INTO accounts").
-			WillReturnError(errors.New("test error"))
+		mock.ExpectExec("INSERT INTO accounts").WithArgs(
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+		).WillReturnError(errors.New("test error"))
 
 		account := domain.Account{
 			ID:      uuid.New(),
 			Name:    "test",
-			Balance: 100.0,
+			Balance: domain.NewMoney(10000, domain.DefaultCurrency),
 		}
 
-		err = repo.Create(account)
+		err = repo.Create(context.Background(), account)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
-	t.Run("create account exec error", func(t *testing.T) {
+}
+
+func TestReadAccount(t *testing.T) {
+	t.Run("read account success", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
@@ -71,27 +107,45 @@ func TestCreateAccount(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectPrepare("INSERT INTO accounts").ExpectExec().WithArgs(
-			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
-		).WillReturnError(errors.New("test error"))
+		mock.ExpectQuery("SELECT id, name, balance, currency, status, version FROM accounts WHERE id = \\?").WithArgs(
+			sqlmock.AnyArg(),
+		).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "balance", "currency", "status", "version"}).AddRow(
+			"123e4567-e89b-12d3-a456-426614174000", "test", 10000, "USD", "active", 3,
+		))
 
-		account := domain.Account{
-			ID:      uuid.New(),
-			Name:    "test",
-			Balance: 100.0,
+		account, err := repo.Read(context.Background(), uuid.New())
+		assert.NoError(t, err)
+		assert.Equal(t, "test", account.Name)
+		assert.Equal(t, domain.NewMoney(10000, domain.DefaultCurrency), account.Balance)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("read account scan error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
 		}
+		defer db.Close()
+
+		repo := NewRepository(db)
 
-		err = repo.Create(account)
+		mock.ExpectQuery("SELECT id, name, balance, currency, status, version FROM accounts WHERE id = \\?").WithArgs(
+			sqlmock.AnyArg(),
+		).WillReturnError(errors.New("test error"))
+
+		account, err := repo.Read(context.Background(), uuid.New())
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
+		assert.Equal(t, domain.Account{}, account)
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
 }
 
-func TestReadAccount(t *testing.T) {
-	t.Run("read account success", func(t *testing.T) {
+func TestReadForUpdateAccount(t *testing.T) {
+	t.Run("read for update account success", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
@@ -100,21 +154,21 @@ func TestReadAccount(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectQuery("SELECT \\* FROM accounts WHERE id = \\?").WithArgs(
+		mock.ExpectQuery("SELECT id, name, balance, currency, status, version FROM accounts WHERE id = \\? FOR UPDATE").WithArgs(
 			sqlmock.AnyArg(),
-		).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "balance"}).AddRow(
-			"123e4567-e89b-12d3-a456-426614174000", "test", 100.0,
+		).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "balance", "currency", "status", "version"}).AddRow(
+			"123e4567-e89b-12d3-a456-426614174000", "test", 10000, "USD", "active", 3,
 		))
 
-		account, err := repo.Read(uuid.New())
+		account, err := repo.ReadForUpdate(context.Background(), uuid.New())
 		assert.NoError(t, err)
 		assert.Equal(t, "test", account.Name)
-		assert.Equal(t, 100.0, account.Balance)
+		assert.Equal(t, domain.NewMoney(10000, domain.DefaultCurrency), account.Balance)
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
-	t.Run("read account scan error", func(t *testing.T) {
+	t.Run("read for update account scan error", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
@@ -123,15 +177,39 @@ func TestReadAccount(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectQuery("SELECT \\* FROM accounts WHERE id = \\?").WithArgs(
+		mock.ExpectQuery("SELECT id, name, balance, currency, status, version FROM accounts WHERE id = \\? FOR UPDATE").WithArgs(
 			sqlmock.AnyArg(),
 		).WillReturnError(errors.New("test error"))
 
-		account, err := repo.Read(uuid.New())
+		account, err := repo.ReadForUpdate(context.Background(), uuid.New())
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
 		assert.Equal(t, domain.Account{}, account)
 
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("read for update account on postgres", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		repo := NewPostgresRepository(db)
+		id := uuid.New()
+
+		mock.ExpectQuery("SELECT id, name, balance, currency, status, version FROM accounts WHERE id = \\$1 FOR UPDATE;").WithArgs(
+			id,
+		).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "balance", "currency", "status", "version"}).AddRow(
+			id.String(), "test", 10000, "USD", "active", 3,
+		))
+
+		account, err := repo.ReadForUpdate(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, id, account.ID)
+		assert.Equal(t, int64(3), account.Version)
+
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
@@ -147,23 +225,23 @@ func TestUpdateAccount(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectPrepare("UPDATE accounts").ExpectExec().WithArgs(
-			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+		mock.ExpectExec("UPDATE accounts").WithArgs(
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 
 		account := domain.Account{
 			ID:      uuid.New(),
 			Name:    "test",
-			Balance: 100.0,
+			Balance: domain.NewMoney(10000, domain.DefaultCurrency),
 		}
 
-		err = repo.Update(account)
+		err = repo.Update(context.Background(), account)
 		assert.NoError(t, err)
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
-	t.Run("update account prepare error", func(t *testing.T) {
+	t.Run("update account exec error", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
@@ -172,46 +250,147 @@ func TestUpdateAccount(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectPrepare("UPDATE accounts").
-			WillReturnError(errors.New("test error"))
+		mock.ExpectExec("UPDATE accounts").WithArgs(
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+		).WillReturnError(errors.New("test error"))
 
 		account := domain.Account{
 			ID:      uuid.New(),
 			Name:    "test",
-			Balance: 100.0,
+			Balance: domain.NewMoney(10000, domain.DefaultCurrency),
 		}
 
-		err = repo.Update(account)
+		err = repo.Update(context.Background(), account)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
-	t.Run("update account exec error", func(t *testing.T) {
+}
+
+func TestTotals(t *testing.T) {
+	t.Run("totals success", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
 		}
 		defer db.Close()
 
-		repo := NewRepository(db)
-
-		mock.ExpectPrepare("UPDATE accounts").ExpectExec().WithArgs(
-			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
-		).WillReturnError(errors.New("test error"))
+		mock.ExpectQuery(`SELECT currency, COALESCE\(SUM\(balance\), 0\) FROM accounts GROUP BY currency`).
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("EUR", 500).AddRow("USD", 150000))
 
-		account := domain.Account{
-			ID:      uuid.New(),
-			Name:    "test",
-			Balance: 100.0,
+		totals, err := NewRepository(db).Totals(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Money{domain.NewMoney(500, "EUR"), domain.NewMoney(150000, "USD")}, totals)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("totals error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
 		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT currency").WillReturnError(errors.New("test error"))
 
-		err = repo.Update(account)
+		_, err = NewRepository(db).Totals(context.Background())
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "test error")
+	})
+}
 
-		err = mock.ExpectationsWereMet()
+func TestListAccounts(t *testing.T) {
+	columns := []string{"id", "name", "balance", "currency", "status", "version"}
+
+	t.Run("list accounts with filters", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		first, second := uuid.New(), uuid.New()
+		min, max := domain.NewMoney(100, "USD"), domain.NewMoney(5000, "USD")
+		mock.ExpectQuery(`SELECT id, name, balance, currency, status, version FROM accounts WHERE 1 = 1 AND name LIKE \? ESCAPE '!' AND status IN \(\?, \?\) AND currency = \? AND balance >= \? AND balance <= \? ORDER BY balance DESC, id DESC LIMIT \?`).
+			WithArgs("a!_b%", domain.AccountActive, domain.AccountFrozen, "USD", int64(100), int64(5000), 2).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(first.String(), "a_bc", 4000, "USD", "active", 3).
+				AddRow(second.String(), "a_bd", 200, "USD", "frozen", 5))
+
+		page, err := NewRepository(db).List(context.Background(), domain.AccountFilter{
+			NamePrefix: "a_b",
+			Statuses:   []domain.AccountStatus{domain.AccountActive, domain.AccountFrozen},
+			Currency:   "USD",
+			MinBalance: &min,
+			MaxBalance: &max,
+			Sort:       domain.SortByBalance,
+			Descending: true,
+			Limit:      1,
+		})
 		assert.NoError(t, err)
+		assert.Equal(t, []domain.Account{{ID: first, Name: "a_bc", Currency: "USD", Balance: domain.NewMoney(4000, "USD"), Status: domain.AccountActive, Version: 3}}, page.Accounts)
+		assert.NotEmpty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list accounts after the cursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		last := domain.Account{ID: uuid.New(), Name: "last"}
+		filter := domain.AccountFilter{Sort: domain.SortByName}
+		filter.Cursor = encodeCursor(filter, last)
+		mock.ExpectQuery(`WHERE 1 = 1 AND \(LOWER\(name\) COLLATE utf8mb4_bin > LOWER\(\?\) COLLATE utf8mb4_bin OR \(LOWER\(name\) COLLATE utf8mb4_bin = LOWER\(\?\) COLLATE utf8mb4_bin AND id > \?\)\) `+
+			`ORDER BY LOWER\(name\) COLLATE utf8mb4_bin, id LIMIT \?`).
+			WithArgs("last", "last", last.ID, DefaultPageSize+1).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		page, err := NewRepository(db).List(context.Background(), filter)
+		assert.NoError(t, err)
+		assert.Empty(t, page.Accounts)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list accounts with a cursor of another sort", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		cursor := encodeCursor(domain.AccountFilter{Sort: domain.SortByName}, domain.Account{ID: uuid.New(), Name: "last"})
+
+		_, err = NewRepository(db).List(context.Background(), domain.AccountFilter{Sort: domain.SortByBalance, Cursor: cursor})
+		assert.Equal(t, custom_errors.ErrInvalidCursor, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list accounts on postgres", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(`WHERE 1 = 1 AND LOWER\(name\) LIKE LOWER\(\$1\) ESCAPE '!' ORDER BY LOWER\(name\) COLLATE "C", id LIMIT \$2`).
+			WithArgs("Ab%", DefaultPageSize+1).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err = NewPostgresRepository(db).List(context.Background(), domain.AccountFilter{NamePrefix: "Ab"})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list accounts error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT id").WillReturnError(errors.New("test error"))
+
+		_, err = NewRepository(db).List(context.Background(), domain.AccountFilter{})
+		assert.EqualError(t, err, "test error")
 	})
 }
@@ -1,35 +1,170 @@
 package account
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 
 	"github.com/google/uuid"
 )
 
 type Service interface {
-	Create(account domain.Account) error
-	Read(id uuid.UUID) (domain.Account, error)
-	Update(account domain.Account) error
+	Create(ctx context.Context, account domain.Account) error
+	Read(ctx context.Context, id uuid.UUID) (domain.Account, error)
+	ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error)
+	Record(ctx context.Context, account domain.Account, event domain.AccountEvent) (domain.Account, error)
+	Rebuild(ctx context.Context, id uuid.UUID) (domain.Account, error)
+	Snapshot(ctx context.Context, id uuid.UUID) (domain.Account, error)
+	List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error)
 }
 
 type service struct {
-	r Repository
+	r             Repository
+	es            EventStore
+	ss            SnapshotStore
+	snapshotEvery int64
 }
 
-func NewService(r Repository) Service {
+// NewService creates the account service. A snapshot of an account is taken every
+// snapshotEvery events; zero disables the periodic snapshots
+func NewService(r Repository, es EventStore, ss SnapshotStore, snapshotEvery int64) Service {
 	return &service{
-		r: r,
+		r:             r,
+		es:            es,
+		ss:            ss,
+		snapshotEvery: snapshotEvery,
+	}
+}
+
+// Create opens the account stream with a create event carrying the opening balance
+// and stores its projection
+func (s service) Create(ctx context.Context, account domain.Account) error {
+	event := domain.AccountEvent{
+		AccountID: account.ID,
+		Sequence:  1,
+		Type:      domain.Create,
+		Amount:    account.Balance,
+		Name:      account.Name,
+		Timestamp: time.Now().UTC(),
+	}
+	if err := s.es.Append(ctx, event); err != nil {
+		return err
+	}
+
+	created, err := domain.ReplayAccount([]domain.AccountEvent{event})
+	if err != nil {
+		return err
+	}
+	return s.r.Create(ctx, created)
+}
+
+// Read rebuilds the account from its latest snapshot and the events recorded after it
+func (s service) Read(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	account, found, err := s.ss.Latest(ctx, id)
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	events, err := s.es.Load(ctx, id, account.Version)
+	if err != nil {
+		return domain.Account{}, err
+	}
+	if !found && len(events) == 0 {
+		return domain.Account{}, custom_errors.ErrNotFound
+	}
+
+	for _, e := range events {
+		if err := account.Apply(e); err != nil {
+			return domain.Account{}, err
+		}
+	}
+	return account, nil
+}
+
+// ReadForUpdate locks the account until the surrounding transaction finishes and
+// rebuilds it from its events, so no other event can be recorded in the meantime
+func (s service) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	if _, err := s.r.ReadForUpdate(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Account{}, custom_errors.ErrNotFound
+		}
+		return domain.Account{}, err
+	}
+
+	return s.Read(ctx, id)
+}
+
+// Record appends the event to the account stream and updates the account projection.
+// It returns the account with the event applied
+func (s service) Record(ctx context.Context, account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+	event.AccountID = account.ID
+	event.Sequence = account.Version + 1
+	event.Timestamp = time.Now().UTC()
+
+	if err := account.Apply(event); err != nil {
+		return domain.Account{}, err
+	}
+	if err := s.es.Append(ctx, event); err != nil {
+		return domain.Account{}, err
 	}
+
+	if err := s.r.Update(ctx, account); err != nil {
+		return domain.Account{}, err
+	}
+
+	if s.snapshotEvery > 0 && account.Version%s.snapshotEvery == 0 {
+		if err := s.ss.Save(ctx, account); err != nil {
+			return domain.Account{}, err
+		}
+	}
+
+	return account, nil
 }
 
-func (s service) Create(account domain.Account) error {
-	return s.r.Create(account)
+// Rebuild replays every event of the account, ignoring its snapshots, and overwrites
+// its projection with the result
+func (s service) Rebuild(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	events, err := s.es.Load(ctx, id, 0)
+	if err != nil {
+		return domain.Account{}, err
+	}
+	if len(events) == 0 {
+		return domain.Account{}, custom_errors.ErrNotFound
+	}
+
+	account, err := domain.ReplayAccount(events)
+	if err != nil {
+		return domain.Account{}, err
+	}
+	return account, s.r.Update(ctx, account)
 }
 
-func (s service) Read(id uuid.UUID) (domain.Account, error) {
-	return s.r.Read(id)
+// Snapshot takes a snapshot of the account at its current version, unless its
+// latest snapshot is already at that version. The account is locked first, so it has to
+// run in a unit of work for two snapshots of the same version not to collide
+func (s service) Snapshot(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	account, err := s.ReadForUpdate(ctx, id)
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	latest, _, err := s.ss.Latest(ctx, id)
+	if err != nil {
+		return domain.Account{}, err
+	}
+	if latest.Version == account.Version {
+		return account, nil
+	}
+
+	return account, s.ss.Save(ctx, account)
 }
 
-func (s service) Update(account domain.Account) error {
-	return s.r.Update(account)
+// List returns a page of the accounts from their projection, as the events recorded
+// keep it up to date
+func (s service) List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	return s.r.List(ctx, filter)
 }
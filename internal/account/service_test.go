@@ -0,0 +1,394 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type repositoryMock struct {
+	create        func(account domain.Account) error
+	read          func(id uuid.UUID) (domain.Account, error)
+	readForUpdate func(id uuid.UUID) (domain.Account, error)
+	update        func(account domain.Account) error
+	totals        func() ([]domain.Money, error)
+	list          func(filter domain.AccountFilter) (domain.AccountPage, error)
+}
+
+func (r repositoryMock) Create(ctx context.Context, account domain.Account) error {
+	return r.create(account)
+}
+
+func (r repositoryMock) Read(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return r.read(id)
+}
+
+func (r repositoryMock) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return r.readForUpdate(id)
+}
+
+func (r repositoryMock) Update(ctx context.Context, account domain.Account) error {
+	return r.update(account)
+}
+
+func (r repositoryMock) Totals(ctx context.Context) ([]domain.Money, error) {
+	return r.totals()
+}
+
+func (r repositoryMock) List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	return r.list(filter)
+}
+
+// fakeEventStore keeps the streams in memory, enforcing unique sequences like the database
+// does and counting the events loaded
+type fakeEventStore struct {
+	streams map[uuid.UUID][]domain.AccountEvent
+	loaded  int
+}
+
+func newFakeEventStore() *fakeEventStore {
+	return &fakeEventStore{
+		streams: make(map[uuid.UUID][]domain.AccountEvent),
+	}
+}
+
+func (m *fakeEventStore) Append(ctx context.Context, events ...domain.AccountEvent) error {
+	for _, e := range events {
+		stream := m.streams[e.AccountID]
+		if int64(len(stream))+1 != e.Sequence {
+			return errors.New("duplicated sequence")
+		}
+		m.streams[e.AccountID] = append(stream, e)
+	}
+	return nil
+}
+
+func (m *fakeEventStore) Load(ctx context.Context, id uuid.UUID, after int64) ([]domain.AccountEvent, error) {
+	var events []domain.AccountEvent
+	for _, e := range m.streams[id] {
+		if e.Sequence > after {
+			m.loaded++
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// fakeSnapshotStore keeps the snapshots of every account in memory, oldest first
+type fakeSnapshotStore struct {
+	snapshots map[uuid.UUID][]domain.Account
+}
+
+func newFakeSnapshotStore() *fakeSnapshotStore {
+	return &fakeSnapshotStore{
+		snapshots: make(map[uuid.UUID][]domain.Account),
+	}
+}
+
+func (m *fakeSnapshotStore) Save(ctx context.Context, account domain.Account) error {
+	m.snapshots[account.ID] = append(m.snapshots[account.ID], account)
+	return nil
+}
+
+func (m *fakeSnapshotStore) Latest(ctx context.Context, id uuid.UUID) (domain.Account, bool, error) {
+	snapshots := m.snapshots[id]
+	if len(snapshots) == 0 {
+		return domain.Account{}, false, nil
+	}
+	return snapshots[len(snapshots)-1], true, nil
+}
+
+func TestServiceCreate(t *testing.T) {
+	t.Run("create appends the create event and stores the projection", func(t *testing.T) {
+		es := newFakeEventStore()
+		var projection domain.Account
+		s := NewService(repositoryMock{
+			create: func(account domain.Account) error {
+				projection = account
+				return nil
+			},
+		}, es, newFakeSnapshotStore(), 0)
+
+		id := uuid.New()
+		err := s.Create(context.Background(), domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(0, "USD")})
+		assert.NoError(t, err)
+
+		assert.Len(t, es.streams[id], 1)
+		assert.Equal(t, domain.Create, es.streams[id][0].Type)
+		assert.Equal(t, "test", projection.Name)
+		assert.Equal(t, int64(1), projection.Version)
+	})
+	t.Run("create projection error", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			create: func(account domain.Account) error {
+				return errors.New("test error")
+			},
+		}, newFakeEventStore(), newFakeSnapshotStore(), 0)
+
+		err := s.Create(context.Background(), domain.Account{ID: uuid.New(), Name: "test"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+	})
+}
+
+func TestServiceRead(t *testing.T) {
+	t.Run("read replays the account events", func(t *testing.T) {
+		es := newFakeEventStore()
+		s := NewService(repositoryMock{
+			create: func(account domain.Account) error { return nil },
+			update: func(account domain.Account) error { return nil },
+		}, es, newFakeSnapshotStore(), 0)
+
+		id := uuid.New()
+		assert.NoError(t, s.Create(context.Background(), domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(0, "USD")}))
+
+		acc, err := s.Read(context.Background(), id)
+		assert.NoError(t, err)
+		acc, err = s.Record(context.Background(), acc, domain.AccountEvent{Type: domain.Deposit, Amount: domain.NewMoney(10000, "USD")})
+		assert.NoError(t, err)
+		_, err = s.Record(context.Background(), acc, domain.AccountEvent{Type: domain.WithDraw, Amount: domain.NewMoney(2500, "USD")})
+		assert.NoError(t, err)
+
+		acc, err = s.Read(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, id, acc.ID)
+		assert.Equal(t, "test", acc.Name)
+		assert.Equal(t, domain.NewMoney(7500, "USD"), acc.Balance)
+		assert.Equal(t, int64(3), acc.Version)
+	})
+	t.Run("read account without events", func(t *testing.T) {
+		s := NewService(repositoryMock{}, newFakeEventStore(), newFakeSnapshotStore(), 0)
+
+		acc, err := s.Read(context.Background(), uuid.New())
+		assert.Equal(t, custom_errors.ErrNotFound, err)
+		assert.Equal(t, domain.Account{}, acc)
+	})
+}
+
+func TestServiceReadForUpdate(t *testing.T) {
+	t.Run("read for update locks the projection and replays the events", func(t *testing.T) {
+		es := newFakeEventStore()
+		locked := false
+		s := NewService(repositoryMock{
+			create: func(account domain.Account) error { return nil },
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				locked = true
+				return domain.Account{ID: id}, nil
+			},
+		}, es, newFakeSnapshotStore(), 0)
+
+		id := uuid.New()
+		assert.NoError(t, s.Create(context.Background(), domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(500, "USD")}))
+
+		acc, err := s.ReadForUpdate(context.Background(), id)
+		assert.NoError(t, err)
+		assert.True(t, locked)
+		assert.Equal(t, domain.NewMoney(500, "USD"), acc.Balance)
+	})
+	t.Run("read for update missing account", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{}, sql.ErrNoRows
+			},
+		}, newFakeEventStore(), newFakeSnapshotStore(), 0)
+
+		_, err := s.ReadForUpdate(context.Background(), uuid.New())
+		assert.Equal(t, custom_errors.ErrNotFound, err)
+	})
+}
+
+func TestServiceRecord(t *testing.T) {
+	t.Run("record from a stale account is rejected", func(t *testing.T) {
+		es := newFakeEventStore()
+		s := NewService(repositoryMock{
+			create: func(account domain.Account) error { return nil },
+			update: func(account domain.Account) error { return nil },
+		}, es, newFakeSnapshotStore(), 0)
+
+		id := uuid.New()
+		assert.NoError(t, s.Create(context.Background(), domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(0, "USD")}))
+		stale, err := s.Read(context.Background(), id)
+		assert.NoError(t, err)
+
+		_, err = s.Record(context.Background(), stale, domain.AccountEvent{Type: domain.Deposit, Amount: domain.NewMoney(100, "USD")})
+		assert.NoError(t, err)
+		_, err = s.Record(context.Background(), stale, domain.AccountEvent{Type: domain.Deposit, Amount: domain.NewMoney(100, "USD")})
+		assert.Error(t, err)
+		assert.Len(t, es.streams[id], 2)
+	})
+	t.Run("record overflowing the balance is rejected", func(t *testing.T) {
+		es := newFakeEventStore()
+		s := NewService(repositoryMock{
+			create: func(account domain.Account) error { return nil },
+			update: func(account domain.Account) error { return nil },
+		}, es, newFakeSnapshotStore(), 0)
+
+		id := uuid.New()
+		assert.NoError(t, s.Create(context.Background(), domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(0, "USD")}))
+		acc, err := s.Record(context.Background(), domain.Account{ID: id, Version: 1, Balance: domain.NewMoney(0, "USD")},
+			domain.AccountEvent{Type: domain.Deposit, Amount: domain.NewMoney(math.MaxInt64-100, "USD")})
+		assert.NoError(t, err)
+
+		_, err = s.Record(context.Background(), acc, domain.AccountEvent{Type: domain.Deposit, Amount: domain.NewMoney(101, "USD")})
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount)
+		assert.Len(t, es.streams[id], 2)
+	})
+	t.Run("record projection error", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			update: func(account domain.Account) error {
+				return errors.New("test error")
+			},
+		}, newFakeEventStore(), newFakeSnapshotStore(), 0)
+
+		_, err := s.Record(context.Background(), domain.Account{ID: uuid.New()}, domain.AccountEvent{Type: domain.Create})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+	})
+}
+
+func TestServiceRebuild(t *testing.T) {
+	t.Run("rebuild overwrites the projection with the replayed state", func(t *testing.T) {
+		es := newFakeEventStore()
+		id := uuid.New()
+		assert.NoError(t, es.Append(context.Background(),
+			domain.AccountEvent{AccountID: id, Sequence: 1, Type: domain.Create, Name: "test", Amount: domain.NewMoney(0, "USD")},
+			domain.AccountEvent{AccountID: id, Sequence: 2, Type: domain.Deposit, Amount: domain.NewMoney(1000, "USD")},
+			domain.AccountEvent{AccountID: id, Sequence: 3, Type: domain.TransferIn, Amount: domain.NewMoney(250, "USD")},
+		))
+
+		var projection domain.Account
+		s := NewService(repositoryMock{
+			update: func(account domain.Account) error {
+				projection = account
+				return nil
+			},
+		}, es, newFakeSnapshotStore(), 0)
+
+		acc, err := s.Rebuild(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, acc, projection)
+		assert.Equal(t, domain.NewMoney(1250, "USD"), projection.Balance)
+		assert.Equal(t, int64(3), projection.Version)
+	})
+}
+
+func TestServiceList(t *testing.T) {
+	t.Run("list reads the projection", func(t *testing.T) {
+		filter := domain.AccountFilter{NamePrefix: "test", Sort: domain.SortByBalance, Limit: 10}
+		expected := domain.AccountPage{Accounts: []domain.Account{{ID: uuid.New(), Name: "test", Status: domain.AccountActive}}, NextCursor: "abc"}
+		s := NewService(repositoryMock{
+			list: func(f domain.AccountFilter) (domain.AccountPage, error) {
+				assert.Equal(t, filter, f)
+				return expected, nil
+			},
+		}, newFakeEventStore(), newFakeSnapshotStore(), 0)
+
+		page, err := s.List(context.Background(), filter)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, page)
+	})
+}
+
+func TestServiceSnapshots(t *testing.T) {
+	// recordEvents opens an account and records n deposits and withdrawals on it
+	recordEvents := func(t *testing.T, s Service, id uuid.UUID, n int) {
+		assert.NoError(t, s.Create(context.Background(), domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(0, "USD")}))
+		acc, err := s.Read(context.Background(), id)
+		assert.NoError(t, err)
+		for i := 1; i <= n; i++ {
+			event := domain.AccountEvent{Type: domain.Deposit, Amount: domain.NewMoney(int64(i*7), "USD")}
+			if i%3 == 0 {
+				event = domain.AccountEvent{Type: domain.WithDraw, Amount: domain.NewMoney(int64(i), "USD")}
+			}
+			acc, err = s.Record(context.Background(), acc, event)
+			assert.NoError(t, err)
+		}
+	}
+	repo := repositoryMock{
+		create:        func(account domain.Account) error { return nil },
+		readForUpdate: func(id uuid.UUID) (domain.Account, error) { return domain.Account{ID: id}, nil },
+		update:        func(account domain.Account) error { return nil },
+	}
+
+	t.Run("snapshot plus tail equals a full replay", func(t *testing.T) {
+		es, ss := newFakeEventStore(), newFakeSnapshotStore()
+		s := NewService(repo, es, ss, 100)
+
+		id := uuid.New()
+		recordEvents(t, s, id, 249)
+
+		assert.Len(t, ss.snapshots[id], 2)
+		assert.Equal(t, int64(200), ss.snapshots[id][1].Version)
+
+		es.loaded = 0
+		acc, err := s.Read(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, 50, es.loaded)
+
+		full, err := domain.ReplayAccount(es.streams[id])
+		assert.NoError(t, err)
+		assert.Equal(t, full, acc)
+		assert.Equal(t, int64(250), acc.Version)
+	})
+	t.Run("snapshots disabled", func(t *testing.T) {
+		es, ss := newFakeEventStore(), newFakeSnapshotStore()
+		s := NewService(repo, es, ss, 0)
+
+		id := uuid.New()
+		recordEvents(t, s, id, 150)
+
+		assert.Empty(t, ss.snapshots[id])
+		acc, err := s.Read(context.Background(), id)
+		assert.NoError(t, err)
+		replayed, err := domain.ReplayAccount(es.streams[id])
+		assert.NoError(t, err)
+		assert.Equal(t, replayed, acc)
+	})
+	t.Run("snapshot on demand", func(t *testing.T) {
+		es, ss := newFakeEventStore(), newFakeSnapshotStore()
+		s := NewService(repo, es, ss, 0)
+
+		id := uuid.New()
+		recordEvents(t, s, id, 10)
+
+		acc, err := s.Snapshot(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Len(t, ss.snapshots[id], 1)
+		assert.Equal(t, int64(11), ss.snapshots[id][0].Version)
+		replayed, err := domain.ReplayAccount(es.streams[id])
+		assert.NoError(t, err)
+		assert.Equal(t, replayed, acc)
+
+		_, err = s.Snapshot(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Len(t, ss.snapshots[id], 1)
+
+		es.loaded = 0
+		read, err := s.Read(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, es.loaded)
+		assert.Equal(t, acc, read)
+	})
+	t.Run("rebuild ignores the snapshots", func(t *testing.T) {
+		es, ss := newFakeEventStore(), newFakeSnapshotStore()
+		s := NewService(repo, es, ss, 5)
+
+		id := uuid.New()
+		recordEvents(t, s, id, 12)
+		ss.snapshots[id][1].Balance = domain.NewMoney(-1, "USD")
+
+		es.loaded = 0
+		acc, err := s.Rebuild(context.Background(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, 13, es.loaded)
+		replayed, err := domain.ReplayAccount(es.streams[id])
+		assert.NoError(t, err)
+		assert.Equal(t, replayed, acc)
+	})
+}
@@ -0,0 +1,55 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// DefaultSnapshotEvery is the number of events between two snapshots of an account
+const DefaultSnapshotEvery = 100
+
+// SnapshotStore keeps the state of accounts at a given event sequence, so loading an
+// account only needs the events recorded after its latest snapshot
+type SnapshotStore interface {
+	Save(ctx context.Context, account domain.Account) error
+	Latest(ctx context.Context, id uuid.UUID) (domain.Account, bool, error)
+}
+
+type snapshotStore struct {
+	db store.DBTX
+}
+
+func NewSnapshotStore(db store.DBTX) SnapshotStore {
+	return &snapshotStore{
+		db: store.Instrument(db, store.SystemOf(db), "account_snapshots"),
+	}
+}
+
+// Save stores the account state at its current version
+func (r snapshotStore) Save(ctx context.Context, account domain.Account) error {
+	query := "INSERT INTO account_snapshots (account_id, sequence, name, balance, currency, status, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?);"
+	_, err := r.db.ExecContext(ctx, query, account.ID, account.Version, account.Name, account.Balance.Amount, account.Balance.Currency, account.Status, time.Now().UTC())
+	return err
+}
+
+// Latest returns the most recent snapshot of the account, if there is any
+func (r snapshotStore) Latest(ctx context.Context, id uuid.UUID) (domain.Account, bool, error) {
+	var account domain.Account
+	query := "SELECT account_id, sequence, name, balance, currency, status FROM account_snapshots WHERE account_id = ? ORDER BY sequence DESC LIMIT 1;"
+	row := r.db.QueryRowContext(ctx, query, id)
+	err := row.Scan(&account.ID, &account.Version, &account.Name, &account.Balance.Amount, &account.Balance.Currency, &account.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Account{}, false, nil
+	}
+	if err != nil {
+		return domain.Account{}, false, err
+	}
+	account.Currency = account.Balance.Currency
+	return account, true, nil
+}
@@ -0,0 +1,118 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSaveSnapshot(t *testing.T) {
+	t.Run("save snapshot success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		ss := NewSnapshotStore(db)
+
+		id := uuid.New()
+		mock.ExpectExec("INSERT INTO account_snapshots").WithArgs(
+			id, 100, "test", 10000, "USD", domain.AccountFrozen, sqlmock.AnyArg(),
+		).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err = ss.Save(context.Background(), domain.Account{ID: id, Name: "test", Balance: domain.NewMoney(10000, "USD"), Status: domain.AccountFrozen, Version: 100})
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("save snapshot exec error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		ss := NewSnapshotStore(db)
+
+		mock.ExpectExec("INSERT INTO account_snapshots").
+			WillReturnError(errors.New("test error"))
+
+		err = ss.Save(context.Background(), domain.Account{ID: uuid.New()})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
+
+func TestLatestSnapshot(t *testing.T) {
+	t.Run("latest snapshot success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		ss := NewSnapshotStore(db)
+
+		id := uuid.New()
+		mock.ExpectQuery("SELECT (.+) FROM account_snapshots WHERE account_id = \\? ORDER BY sequence DESC LIMIT 1").
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"account_id", "sequence", "name", "balance", "currency", "status"}).
+				AddRow(id.String(), 200, "test", 10000, "USD", "frozen"))
+
+		acc, found, err := ss.Latest(context.Background(), id)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, domain.Account{ID: id, Name: "test", Currency: "USD", Balance: domain.NewMoney(10000, "USD"), Status: domain.AccountFrozen, Version: 200}, acc)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("latest snapshot not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		ss := NewSnapshotStore(db)
+
+		mock.ExpectQuery("SELECT (.+) FROM account_snapshots").
+			WillReturnRows(sqlmock.NewRows([]string{"account_id", "sequence", "name", "balance", "currency", "status"}))
+
+		acc, found, err := ss.Latest(context.Background(), uuid.New())
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Equal(t, domain.Account{}, acc)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("latest snapshot query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		ss := NewSnapshotStore(db)
+
+		mock.ExpectQuery("SELECT (.+) FROM account_snapshots").
+			WillReturnError(errors.New("test error"))
+
+		_, found, err := ss.Latest(context.Background(), uuid.New())
+		assert.Error(t, err)
+		assert.False(t, found)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
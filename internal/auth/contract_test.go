@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// eachBackend runs the test on every storage backend, so they all behave the same. MySQL
+// and PostgreSQL only run when storetest.MySQLEnv and storetest.PostgresEnv are set
+func eachBackend(t *testing.T, test func(t *testing.T, accounts account.Repository, r Repository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, account.NewMemoryRepository(), NewMemoryRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db := storetest.SQLite(t)
+		test(t, account.NewSQLiteRepository(db), NewRepository(db))
+	})
+	t.Run("mysql", func(t *testing.T) {
+		db := storetest.MySQL(t)
+		test(t, account.NewRepository(db), NewRepository(db))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		db := storetest.Postgres(t)
+		test(t, account.NewPostgresRepository(db), NewRepository(store.Postgres(db)))
+	})
+}
+
+func TestRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, accounts account.Repository, r Repository) {
+		first, second := uuid.New(), uuid.New()
+		for _, id := range []uuid.UUID{first, second} {
+			assert.NoError(t, accounts.Create(ctx, domain.Account{ID: id, Balance: domain.NewMoney(0, "USD")}))
+		}
+		if first.String() > second.String() {
+			first, second = second, first
+		}
+
+		owner := domain.Principal{ID: uuid.New(), Name: "owner", Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{second}}
+		assert.NoError(t, r.Create(ctx, owner, "owner hash"))
+		assert.NoError(t, r.Grant(ctx, owner.ID, first))
+		assert.True(t, store.IsDuplicate(r.Grant(ctx, owner.ID, first)))
+
+		admin := domain.Principal{ID: uuid.New(), Name: "admin", Role: domain.RoleAdmin}
+		assert.NoError(t, r.Create(ctx, admin, "admin hash"))
+		assert.True(t, store.IsDuplicate(r.Create(ctx, domain.Principal{ID: uuid.New(), Name: "other", Role: domain.RoleAdmin}, "admin hash")))
+
+		found, err := r.FindByKeyHash(ctx, "owner hash")
+		assert.NoError(t, err)
+		owner.Accounts = []uuid.UUID{first, second}
+		assert.Equal(t, owner, found)
+
+		found, err = r.FindByKeyHash(ctx, "admin hash")
+		assert.NoError(t, err)
+		assert.Equal(t, admin, found)
+
+		_, err = r.FindByKeyHash(ctx, "unknown hash")
+		assert.ErrorIs(t, err, custom_errors.ErrNotFound)
+	})
+}
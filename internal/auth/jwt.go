@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// DefaultClockSkew is the leeway given to the time claims when no skew is configured
+const DefaultClockSkew = time.Minute
+
+// Claims are the validated claims of a bearer token
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	Scopes    []string
+	ExpiresAt time.Time
+	// Role and Accounts are the private claims mapping the token to a principal
+	Role     domain.Role
+	Accounts []uuid.UUID
+}
+
+// HasScope reports whether the token was granted the scope
+func (c Claims) HasScope(scope string) bool {
+	return contains(c.Scopes, scope)
+}
+
+// Principal is the caller the token was issued to. Subjects that are not a UUID get a
+// stable id derived from the issuer and the subject
+func (c Claims) Principal() domain.Principal {
+	id, err := uuid.Parse(c.Subject)
+	if err != nil {
+		id = uuid.NewSHA1(uuid.NameSpaceURL, []byte(c.Issuer+"#"+c.Subject))
+	}
+	p := domain.Principal{ID: id, Name: c.Subject, Role: c.Role, FromToken: true}
+	if c.Role == domain.RoleAccountOwner {
+		p.Accounts = c.Accounts
+	}
+	return p
+}
+
+// TokenVerifier validates bearer tokens
+type TokenVerifier interface {
+	Verify(token string) (Claims, error)
+}
+
+// JWTConfig sets what a JWT has to comply with. Empty issuer and audience are not checked
+type JWTConfig struct {
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+	// Algorithms are the accepted signing algorithms, all the supported ones when empty
+	Algorithms []string
+}
+
+type jwtVerifier struct {
+	cfg  JWTConfig
+	keys KeySource
+	now  func() time.Time
+}
+
+func NewJWTVerifier(cfg JWTConfig, keys KeySource) TokenVerifier {
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []string{HS256, RS256, ES256}
+	}
+	return &jwtVerifier{
+		cfg:  cfg,
+		keys: keys,
+		now:  time.Now,
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Sub      string      `json:"sub"`
+	Iss      string      `json:"iss"`
+	Aud      audience    `json:"aud"`
+	Exp      *float64    `json:"exp"`
+	Nbf      *float64    `json:"nbf"`
+	Iat      *float64    `json:"iat"`
+	Scope    string      `json:"scope"`
+	Role     domain.Role `json:"role"`
+	Accounts []uuid.UUID `json:"accounts"`
+}
+
+// audience is a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify checks the signature and the registered claims of a compact JWS. The token has
+// to expire, and the time claims are checked with the configured clock skew
+func (v jwtVerifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, invalidToken("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, invalidToken("malformed header")
+	}
+	if !v.allowed(header.Alg) {
+		return Claims{}, invalidToken("algorithm " + header.Alg + " not allowed")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, invalidToken("malformed signature")
+	}
+	key, err := v.keys.Key(header.Kid, header.Alg)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err = verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	var raw jwtClaims
+	if err = decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, invalidToken("malformed claims")
+	}
+	return v.validate(raw)
+}
+
+func (v jwtVerifier) validate(raw jwtClaims) (Claims, error) {
+	now := v.now()
+	skew := v.cfg.ClockSkew
+	if raw.Exp == nil {
+		return Claims{}, invalidToken("missing expiration")
+	}
+	expiresAt := numericDate(*raw.Exp)
+	if !now.Before(expiresAt.Add(skew)) {
+		return Claims{}, invalidToken("token expired")
+	}
+	if raw.Nbf != nil && now.Add(skew).Before(numericDate(*raw.Nbf)) {
+		return Claims{}, invalidToken("token not valid yet")
+	}
+	if raw.Iat != nil && now.Add(skew).Before(numericDate(*raw.Iat)) {
+		return Claims{}, invalidToken("token issued in the future")
+	}
+	if v.cfg.Issuer != "" && raw.Iss != v.cfg.Issuer {
+		return Claims{}, invalidToken("unexpected issuer")
+	}
+	if v.cfg.Audience != "" && !contains(raw.Aud, v.cfg.Audience) {
+		return Claims{}, invalidToken("unexpected audience")
+	}
+	if raw.Sub == "" {
+		return Claims{}, invalidToken("missing subject")
+	}
+
+	return Claims{
+		Subject:   raw.Sub,
+		Issuer:    raw.Iss,
+		Audience:  raw.Aud,
+		Scopes:    strings.Fields(raw.Scope),
+		ExpiresAt: expiresAt,
+		Role:      raw.Role,
+		Accounts:  raw.Accounts,
+	}, nil
+}
+
+func (v jwtVerifier) allowed(alg string) bool {
+	return contains(v.cfg.Algorithms, alg)
+}
+
+// verifySignature checks the signature with a key of the type the algorithm needs, so a
+// public key can never be used as an HMAC secret
+func verifySignature(alg string, key interface{}, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return invalidToken("key does not match the algorithm")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return invalidToken("invalid signature")
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return invalidToken("key does not match the algorithm")
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return invalidToken("invalid signature")
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return invalidToken("key does not match the algorithm")
+		}
+		// the signature is the concatenation of r and s, 32 bytes each
+		if len(signature) != 64 {
+			return invalidToken("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return invalidToken("invalid signature")
+		}
+	default:
+		return invalidToken("algorithm " + alg + " not supported")
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", custom_errors.ErrInvalidToken, reason)
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	hmacSecret = []byte("test-secret")
+	rsaKey, _  = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _   = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwtNow     = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// signJWT builds a token signed with the key of the algorithm
+func signJWT(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch alg {
+	case HS256:
+		mac := hmac.New(sha256.New, hmacSecret)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case RS256:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case ES256:
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		assert.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "user-1",
+		"iss":   "https://issuer.test",
+		"aud":   []string{"xepelin-bank", "other"},
+		"exp":   jwtNow.Add(time.Hour).Unix(),
+		"iat":   jwtNow.Unix(),
+		"scope": "transactions:write accounts:read",
+	}
+}
+
+func newTestVerifier(cfg JWTConfig) TokenVerifier {
+	jwks, _ := NewJWKSKeySource(testJWKS())
+	v := NewJWTVerifier(cfg, KeySources(NewHMACKeySource(hmacSecret), jwks)).(*jwtVerifier)
+	v.now = func() time.Time { return jwtNow }
+	return v
+}
+
+func TestJWTVerify(t *testing.T) {
+	cfg := JWTConfig{Issuer: "https://issuer.test", Audience: "xepelin-bank", ClockSkew: time.Minute}
+
+	t.Run("valid tokens of every algorithm", func(t *testing.T) {
+		v := newTestVerifier(cfg)
+		for _, alg := range []string{HS256, RS256, ES256} {
+			claims, err := v.Verify(signJWT(t, alg, alg+"-key", validClaims()))
+			assert.NoError(t, err, alg)
+			assert.Equal(t, "user-1", claims.Subject)
+			assert.Equal(t, []string{"transactions:write", "accounts:read"}, claims.Scopes)
+			assert.True(t, claims.HasScope("accounts:read"))
+			assert.Equal(t, jwtNow.Add(time.Hour), claims.ExpiresAt.UTC())
+		}
+	})
+	t.Run("single audience", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "xepelin-bank"
+
+		_, err := newTestVerifier(cfg).Verify(signJWT(t, HS256, "", claims))
+		assert.NoError(t, err)
+	})
+	t.Run("clock skew", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = jwtNow.Add(-30 * time.Second).Unix()
+		claims["nbf"] = jwtNow.Add(30 * time.Second).Unix()
+
+		_, err := newTestVerifier(cfg).Verify(signJWT(t, HS256, "", claims))
+		assert.NoError(t, err)
+
+		_, err = newTestVerifier(JWTConfig{}).Verify(signJWT(t, HS256, "", claims))
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidToken)
+	})
+	t.Run("invalid claims", func(t *testing.T) {
+		for name, change := range map[string]func(claims map[string]interface{}){
+			"expired":         func(c map[string]interface{}) { c["exp"] = jwtNow.Add(-2 * time.Minute).Unix() },
+			"without exp":     func(c map[string]interface{}) { delete(c, "exp") },
+			"not valid yet":   func(c map[string]interface{}) { c["nbf"] = jwtNow.Add(2 * time.Minute).Unix() },
+			"future iat":      func(c map[string]interface{}) { c["iat"] = jwtNow.Add(2 * time.Minute).Unix() },
+			"wrong issuer":    func(c map[string]interface{}) { c["iss"] = "https://other.test" },
+			"wrong audience":  func(c map[string]interface{}) { c["aud"] = "other" },
+			"without subject": func(c map[string]interface{}) { delete(c, "sub") },
+		} {
+			claims := validClaims()
+			change(claims)
+
+			_, err := newTestVerifier(cfg).Verify(signJWT(t, HS256, "", claims))
+			assert.ErrorIs(t, err, custom_errors.ErrInvalidToken, name)
+		}
+	})
+	t.Run("invalid tokens", func(t *testing.T) {
+		v := newTestVerifier(cfg)
+		valid := signJWT(t, RS256, "RS256-key", validClaims())
+		tampered := signJWT(t, RS256, "RS256-key", map[string]interface{}{"sub": "admin", "exp": jwtNow.Add(time.Hour).Unix()})
+
+		for name, token := range map[string]string{
+			"malformed":           "abc.def",
+			"unsigned":            signJWT(t, "none", "", validClaims()),
+			"unknown key":         signJWT(t, RS256, "other-key", validClaims()),
+			"tampered claims":     tampered[:lastDot(tampered)] + valid[lastDot(valid):],
+			"malformed signature": valid[:lastDot(valid)] + ".%%%",
+		} {
+			_, err := v.Verify(token)
+			assert.ErrorIs(t, err, custom_errors.ErrInvalidToken, name)
+		}
+	})
+	t.Run("public key used as HMAC secret", func(t *testing.T) {
+		// an RS256 key id with an HS256 token must not verify with the RSA key
+		jwks, _ := NewJWKSKeySource(testJWKS())
+		v := NewJWTVerifier(cfg, jwks).(*jwtVerifier)
+		v.now = func() time.Time { return jwtNow }
+
+		_, err := v.Verify(signJWT(t, HS256, "RS256-key", validClaims()))
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidToken)
+	})
+	t.Run("algorithm not allowed", func(t *testing.T) {
+		v := newTestVerifier(JWTConfig{Algorithms: []string{RS256}})
+
+		_, err := v.Verify(signJWT(t, HS256, "", validClaims()))
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidToken)
+	})
+}
+
+func lastDot(token string) int {
+	return strings.LastIndex(token, ".")
+}
+
+func TestClaimsPrincipal(t *testing.T) {
+	id, accountID := uuid.New(), uuid.New()
+
+	p := Claims{Subject: id.String(), Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{accountID}}.Principal()
+	assert.Equal(t, id, p.ID)
+	assert.True(t, p.CanOperate(accountID))
+	assert.True(t, p.FromToken)
+
+	first := Claims{Subject: "user-1", Issuer: "https://issuer.test", Role: domain.RoleAuditor, Accounts: []uuid.UUID{accountID}}.Principal()
+	second := Claims{Subject: "user-1", Issuer: "https://issuer.test"}.Principal()
+	assert.Equal(t, first.ID, second.ID)
+	assert.Empty(t, first.Accounts)
+	assert.NotEqual(t, first.ID, Claims{Subject: "user-1", Issuer: "https://other.test"}.Principal().ID)
+	assert.False(t, second.CanRead(accountID))
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// Supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// KeySource returns the key that verifies the signature of a token: a []byte secret for
+// HS256, an *rsa.PublicKey for RS256 and an *ecdsa.PublicKey for ES256
+type KeySource interface {
+	Key(kid, alg string) (interface{}, error)
+}
+
+type hmacKeySource struct {
+	secret []byte
+}
+
+// NewHMACKeySource returns a source of a single shared secret, used for HS256 tokens
+// whatever their key id is
+func NewHMACKeySource(secret []byte) KeySource {
+	return &hmacKeySource{secret: secret}
+}
+
+func (s hmacKeySource) Key(kid, alg string) (interface{}, error) {
+	if alg != HS256 {
+		return nil, fmt.Errorf("%w: no key for %s", custom_errors.ErrInvalidToken, alg)
+	}
+	return s.secret, nil
+}
+
+// jwk is a JSON Web Key, RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type jwksKey struct {
+	kid string
+	alg string
+	key interface{}
+}
+
+type jwksKeySource struct {
+	keys []jwksKey
+}
+
+// NewJWKSKeySource parses a JSON Web Key Set. RSA, P-256 EC and symmetric (oct) keys are
+// supported, and keys meant for encryption are skipped
+func NewJWKSKeySource(data []byte) (KeySource, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	s := &jwksKeySource{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, alg, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		s.keys = append(s.keys, jwksKey{kid: k.Kid, alg: alg, key: key})
+	}
+	return s, nil
+}
+
+// NewJWKSFileKeySource loads a JSON Web Key Set from a local file
+func NewJWKSFileKeySource(path string) (KeySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewJWKSKeySource(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Key returns the key with the id that verifies the algorithm. Tokens without key id are
+// verified with the only key of the set for the algorithm, if there is just one
+func (s jwksKeySource) Key(kid, alg string) (interface{}, error) {
+	var found []interface{}
+	for _, k := range s.keys {
+		if k.alg == alg && (kid == "" || k.kid == kid) {
+			found = append(found, k.key)
+		}
+	}
+	if len(found) != 1 {
+		return nil, fmt.Errorf("%w: no key %q for %s", custom_errors.ErrInvalidToken, kid, alg)
+	}
+	return found[0], nil
+}
+
+// parse returns the key and the algorithm it verifies
+func (k jwk) parse() (interface{}, string, error) {
+	switch k.Kty {
+	case "RSA":
+		if k.Alg != "" && k.Alg != RS256 {
+			return nil, "", fmt.Errorf("unsupported algorithm %s", k.Alg)
+		}
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, "", err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, "", err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, "", fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, RS256, nil
+	case "EC":
+		if k.Crv != "P-256" || (k.Alg != "" && k.Alg != ES256) {
+			return nil, "", fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, "", err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, "", err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, "", fmt.Errorf("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, ES256, nil
+	case "oct":
+		if k.Alg != "" && k.Alg != HS256 {
+			return nil, "", fmt.Errorf("unsupported algorithm %s", k.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, "", fmt.Errorf("invalid secret")
+		}
+		return secret, HS256, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+type keySources []KeySource
+
+// KeySources combines several sources, returning the key of the first one that has it
+func KeySources(sources ...KeySource) KeySource {
+	return keySources(sources)
+}
+
+func (s keySources) Key(kid, alg string) (interface{}, error) {
+	err := fmt.Errorf("%w: no key %q for %s", custom_errors.ErrInvalidToken, kid, alg)
+	for _, source := range s {
+		var key interface{}
+		if key, err = source.Key(kid, alg); err == nil {
+			return key, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// testJWKS is a key set with the test RSA and EC public keys, and an encryption key
+func testJWKS() []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "RS256-key", "use": "sig", "alg": "RS256", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ES256-key", "crv": "P-256", "x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y)},
+			{"kty": "RSA", "kid": "encryption-key", "use": "enc", "n": encodeBigInt(rsaKey.N), "e": "AQAB"},
+		},
+	})
+	return data
+}
+
+func TestJWKSKeySource(t *testing.T) {
+	t.Run("keys by id and algorithm", func(t *testing.T) {
+		s, err := NewJWKSKeySource(testJWKS())
+		assert.NoError(t, err)
+
+		key, err := s.Key("RS256-key", RS256)
+		assert.NoError(t, err)
+		assert.Equal(t, rsaKey.N, key.(*rsa.PublicKey).N)
+
+		key, err = s.Key("ES256-key", ES256)
+		assert.NoError(t, err)
+		assert.Equal(t, ecKey.X, key.(*ecdsa.PublicKey).X)
+
+		// a key is only returned for the algorithm it verifies
+		_, err = s.Key("RS256-key", ES256)
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidToken)
+		_, err = s.Key("encryption-key", RS256)
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidToken)
+	})
+	t.Run("token without key id", func(t *testing.T) {
+		s, err := NewJWKSKeySource(testJWKS())
+		assert.NoError(t, err)
+
+		_, err = s.Key("", ES256)
+		assert.NoError(t, err)
+	})
+	t.Run("symmetric key", func(t *testing.T) {
+		s, err := NewJWKSKeySource([]byte(`{"keys":[{"kty":"oct","kid":"k1","k":"` + base64.RawURLEncoding.EncodeToString(hmacSecret) + `"}]}`))
+		assert.NoError(t, err)
+
+		key, err := s.Key("k1", HS256)
+		assert.NoError(t, err)
+		assert.Equal(t, hmacSecret, key)
+	})
+	t.Run("invalid key sets", func(t *testing.T) {
+		for _, data := range []string{
+			`not json`,
+			`{"keys":[{"kty":"RSA","kid":"k1","n":"","e":"AQAB"}]}`,
+			`{"keys":[{"kty":"EC","kid":"k1","crv":"P-384","x":"AQ","y":"AQ"}]}`,
+			`{"keys":[{"kty":"EC","kid":"k1","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+			`{"keys":[{"kty":"OKP","kid":"k1"}]}`,
+		} {
+			_, err := NewJWKSKeySource([]byte(data))
+			assert.Error(t, err, data)
+		}
+	})
+	t.Run("load from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		assert.NoError(t, os.WriteFile(path, testJWKS(), 0o600))
+
+		s, err := NewJWKSFileKeySource(path)
+		assert.NoError(t, err)
+		_, err = s.Key("RS256-key", RS256)
+		assert.NoError(t, err)
+
+		_, err = NewJWKSFileKeySource(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+}
+
+func TestHMACKeySource(t *testing.T) {
+	s := NewHMACKeySource(hmacSecret)
+
+	key, err := s.Key("any", HS256)
+	assert.NoError(t, err)
+	assert.Equal(t, hmacSecret, key)
+
+	_, err = s.Key("any", RS256)
+	assert.ErrorIs(t, err, custom_errors.ErrInvalidToken)
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+type memoryRepository struct {
+	mu         sync.RWMutex
+	principals map[uuid.UUID]domain.Principal
+	// keys holds the principal of every key hash
+	keys map[string]uuid.UUID
+	// accounts holds the accounts granted to every principal
+	accounts map[uuid.UUID]map[uuid.UUID]bool
+}
+
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		principals: make(map[uuid.UUID]domain.Principal),
+		keys:       make(map[string]uuid.UUID),
+		accounts:   make(map[uuid.UUID]map[uuid.UUID]bool),
+	}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, p domain.Principal, keyHash string) error {
+	r.mu.Lock()
+	_, principalTaken := r.principals[p.ID]
+	_, keyTaken := r.keys[keyHash]
+	if principalTaken || keyTaken {
+		r.mu.Unlock()
+		return store.ErrDuplicate
+	}
+	r.principals[p.ID] = domain.Principal{ID: p.ID, Name: p.Name, Role: p.Role}
+	r.keys[keyHash] = p.ID
+	r.mu.Unlock()
+
+	store.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.principals, p.ID)
+		delete(r.keys, keyHash)
+	})
+
+	for _, accountID := range p.Accounts {
+		if err := r.Grant(ctx, p.ID, accountID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) Grant(ctx context.Context, principalID, accountID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.accounts[principalID][accountID] {
+		return store.ErrDuplicate
+	}
+	if r.accounts[principalID] == nil {
+		r.accounts[principalID] = make(map[uuid.UUID]bool)
+	}
+	r.accounts[principalID][accountID] = true
+
+	store.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.accounts[principalID], accountID)
+	})
+	return nil
+}
+
+func (r *memoryRepository) FindByKeyHash(_ context.Context, keyHash string) (domain.Principal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.keys[keyHash]
+	if !ok {
+		return domain.Principal{}, custom_errors.ErrNotFound
+	}
+	p := r.principals[id]
+	for accountID := range r.accounts[id] {
+		p.Accounts = append(p.Accounts, accountID)
+	}
+	sort.Slice(p.Accounts, func(i, j int) bool { return p.Accounts[i].String() < p.Accounts[j].String() })
+	return p, nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// Repository stores the principals, the hashes of their API keys and the accounts they own
+type Repository interface {
+	// Create stores the principal with its key hash and owned accounts
+	Create(ctx context.Context, p domain.Principal, keyHash string) error
+	Grant(ctx context.Context, principalID, accountID uuid.UUID) error
+	// FindByKeyHash returns the principal of a key hash with its owned accounts
+	FindByKeyHash(ctx context.Context, keyHash string) (domain.Principal, error)
+}
+
+type repository struct {
+	db store.DBTX
+}
+
+func NewRepository(db store.DBTX) Repository {
+	return &repository{
+		db: store.Instrument(db, store.SystemOf(db), "auth"),
+	}
+}
+
+func (r repository) Create(ctx context.Context, p domain.Principal, keyHash string) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, "INSERT INTO principals (id, name, role, created_at) VALUES (?, ?, ?, ?);",
+		p.ID, p.Name, p.Role, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO api_keys (key_hash, principal_id, created_at) VALUES (?, ?, ?);",
+		keyHash, p.ID, now)
+	if err != nil {
+		return err
+	}
+
+	for _, accountID := range p.Accounts {
+		if err = r.Grant(ctx, p.ID, accountID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r repository) Grant(ctx context.Context, principalID, accountID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO principal_accounts (principal_id, account_id) VALUES (?, ?);",
+		principalID, accountID)
+	return err
+}
+
+func (r repository) FindByKeyHash(ctx context.Context, keyHash string) (domain.Principal, error) {
+	var p domain.Principal
+	row := r.db.QueryRowContext(ctx, "SELECT p.id, p.name, p.role FROM api_keys k JOIN principals p ON p.id = k.principal_id WHERE k.key_hash = ?;", keyHash)
+	err := row.Scan(&p.ID, &p.Name, &p.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Principal{}, custom_errors.ErrNotFound
+	}
+	if err != nil {
+		return domain.Principal{}, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT account_id FROM principal_accounts WHERE principal_id = ? ORDER BY account_id;", p.ID)
+	if err != nil {
+		return domain.Principal{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accountID uuid.UUID
+		if err = rows.Scan(&accountID); err != nil {
+			return domain.Principal{}, err
+		}
+		p.Accounts = append(p.Accounts, accountID)
+	}
+	return p, rows.Err()
+}
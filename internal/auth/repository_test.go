@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const findPrincipalQuery = "SELECT p.id, p.name, p.role FROM api_keys k JOIN principals p ON p.id = k.principal_id WHERE k.key_hash = \\?"
+
+func TestCreate(t *testing.T) {
+	t.Run("create success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		p := domain.Principal{ID: uuid.New(), Name: "test", Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{uuid.New()}}
+		mock.ExpectExec("INSERT INTO principals").WithArgs(p.ID, "test", domain.RoleAccountOwner, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO api_keys").WithArgs("hash", p.ID, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO principal_accounts").WithArgs(p.ID, p.Accounts[0]).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = r.Create(context.Background(), p, "hash")
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("create key error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectExec("INSERT INTO principals").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO api_keys").WillReturnError(errors.New("test error"))
+
+		err = r.Create(context.Background(), domain.Principal{ID: uuid.New()}, "hash")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
+
+func TestFindByKeyHash(t *testing.T) {
+	t.Run("find success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		id, accountID := uuid.New(), uuid.New()
+		mock.ExpectQuery(findPrincipalQuery).WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role"}).AddRow(id.String(), "test", "account_owner"))
+		mock.ExpectQuery("SELECT account_id FROM principal_accounts WHERE principal_id = \\?").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"account_id"}).AddRow(accountID.String()))
+
+		p, err := r.FindByKeyHash(context.Background(), "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Principal{ID: id, Name: "test", Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{accountID}}, p)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("find not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectQuery(findPrincipalQuery).WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role"}))
+
+		_, err = r.FindByKeyHash(context.Background(), "hash")
+		assert.Equal(t, custom_errors.ErrNotFound, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("find query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectQuery(findPrincipalQuery).WillReturnError(errors.New("test error"))
+
+		_, err = r.FindByKeyHash(context.Background(), "hash")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// keyPrefix makes the API keys easy to recognize, e.g. by secret scanners
+const keyPrefix = "xbk_"
+
+// BootstrapPrincipal is the admin authenticated by the bootstrap key
+var BootstrapPrincipal = domain.Principal{ID: uuid.Nil, Name: "bootstrap", Role: domain.RoleAdmin}
+
+type Service interface {
+	// Authenticate returns the principal of an API key
+	Authenticate(ctx context.Context, key string) (domain.Principal, error)
+	// Issue creates a principal and returns its new API key
+	Issue(ctx context.Context, req domain.APIKeyRequest) (domain.APIKey, error)
+	// Grant makes the principal the owner of the account
+	Grant(ctx context.Context, principalID, accountID uuid.UUID) error
+}
+
+type service struct {
+	r            Repository
+	bootstrapKey string
+}
+
+// NewService creates the service. The bootstrap key, when it is not empty, authenticates
+// as an admin without being stored, so the first API keys can be issued
+func NewService(r Repository, bootstrapKey string) Service {
+	return &service{
+		r:            r,
+		bootstrapKey: bootstrapKey,
+	}
+}
+
+func (s service) Authenticate(ctx context.Context, key string) (domain.Principal, error) {
+	if key == "" {
+		return domain.Principal{}, custom_errors.ErrTokenNotFound
+	}
+	if s.bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.bootstrapKey)) == 1 {
+		return BootstrapPrincipal, nil
+	}
+
+	p, err := s.r.FindByKeyHash(ctx, hashKey(key))
+	if errors.Is(err, custom_errors.ErrNotFound) {
+		return domain.Principal{}, custom_errors.ErrInvalidToken
+	}
+	return p, err
+}
+
+func (s service) Issue(ctx context.Context, req domain.APIKeyRequest) (domain.APIKey, error) {
+	if !req.Role.Valid() {
+		return domain.APIKey{}, custom_errors.ErrInvalidRole
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.APIKey{}, err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	p := domain.Principal{
+		ID:   uuid.New(),
+		Name: req.Name,
+		Role: req.Role,
+	}
+	// only account owners are restricted to their accounts
+	if req.Role == domain.RoleAccountOwner {
+		p.Accounts = req.Accounts
+	}
+	if err := s.r.Create(ctx, p, hashKey(key)); err != nil {
+		return domain.APIKey{}, err
+	}
+	return domain.APIKey{Key: key, Principal: p}, nil
+}
+
+func (s service) Grant(ctx context.Context, principalID, accountID uuid.UUID) error {
+	return s.r.Grant(ctx, principalID, accountID)
+}
+
+// hashKey is the digest stored instead of the key. The keys are random, so a plain
+// SHA-256 is enough to make a leaked table useless
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
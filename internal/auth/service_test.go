@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRepository keeps the principals by key hash
+type fakeRepository struct {
+	principals map[string]domain.Principal
+	err        error
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{principals: map[string]domain.Principal{}}
+}
+
+func (m *fakeRepository) Create(ctx context.Context, p domain.Principal, keyHash string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.principals[keyHash] = p
+	return nil
+}
+
+func (m *fakeRepository) Grant(ctx context.Context, principalID, accountID uuid.UUID) error {
+	for hash, p := range m.principals {
+		if p.ID == principalID {
+			p.Accounts = append(p.Accounts, accountID)
+			m.principals[hash] = p
+		}
+	}
+	return m.err
+}
+
+func (m *fakeRepository) FindByKeyHash(ctx context.Context, keyHash string) (domain.Principal, error) {
+	if m.err != nil {
+		return domain.Principal{}, m.err
+	}
+	p, ok := m.principals[keyHash]
+	if !ok {
+		return domain.Principal{}, custom_errors.ErrNotFound
+	}
+	return p, nil
+}
+
+func TestIssue(t *testing.T) {
+	t.Run("issued key authenticates its principal", func(t *testing.T) {
+		r := newFakeRepository()
+		s := NewService(r, "")
+		accountID := uuid.New()
+
+		key, err := s.Issue(context.Background(), domain.APIKeyRequest{Name: "test", Role: domain.RoleAccountOwner, Accounts: []uuid.UUID{accountID}})
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(key.Key, keyPrefix))
+		assert.Equal(t, []uuid.UUID{accountID}, key.Principal.Accounts)
+
+		// only the hash is stored
+		for hash := range r.principals {
+			assert.NotContains(t, hash, key.Key)
+		}
+
+		p, err := s.Authenticate(context.Background(), key.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, key.Principal, p)
+	})
+	t.Run("keys are unique", func(t *testing.T) {
+		s := NewService(newFakeRepository(), "")
+
+		first, err := s.Issue(context.Background(), domain.APIKeyRequest{Name: "first", Role: domain.RoleAuditor})
+		assert.NoError(t, err)
+		second, err := s.Issue(context.Background(), domain.APIKeyRequest{Name: "second", Role: domain.RoleAuditor})
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.Key, second.Key)
+	})
+	t.Run("only account owners are restricted to accounts", func(t *testing.T) {
+		s := NewService(newFakeRepository(), "")
+
+		key, err := s.Issue(context.Background(), domain.APIKeyRequest{Name: "test", Role: domain.RoleAuditor, Accounts: []uuid.UUID{uuid.New()}})
+		assert.NoError(t, err)
+		assert.Empty(t, key.Principal.Accounts)
+	})
+	t.Run("invalid role", func(t *testing.T) {
+		s := NewService(newFakeRepository(), "")
+
+		_, err := s.Issue(context.Background(), domain.APIKeyRequest{Name: "test", Role: "root"})
+		assert.Equal(t, custom_errors.ErrInvalidRole, err)
+	})
+	t.Run("repository error", func(t *testing.T) {
+		r := newFakeRepository()
+		r.err = errors.New("test error")
+		s := NewService(r, "")
+
+		_, err := s.Issue(context.Background(), domain.APIKeyRequest{Name: "test", Role: domain.RoleAdmin})
+		assert.Error(t, err)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Run("bootstrap key", func(t *testing.T) {
+		s := NewService(newFakeRepository(), "secret")
+
+		p, err := s.Authenticate(context.Background(), "secret")
+		assert.NoError(t, err)
+		assert.Equal(t, BootstrapPrincipal, p)
+	})
+	t.Run("empty bootstrap key is disabled", func(t *testing.T) {
+		s := NewService(newFakeRepository(), "")
+
+		_, err := s.Authenticate(context.Background(), "xbk_unknown")
+		assert.Equal(t, custom_errors.ErrInvalidToken, err)
+	})
+	t.Run("missing key", func(t *testing.T) {
+		s := NewService(newFakeRepository(), "secret")
+
+		_, err := s.Authenticate(context.Background(), "")
+		assert.Equal(t, custom_errors.ErrTokenNotFound, err)
+	})
+	t.Run("repository error", func(t *testing.T) {
+		r := newFakeRepository()
+		r.err = errors.New("test error")
+		s := NewService(r, "")
+
+		_, err := s.Authenticate(context.Background(), "xbk_key")
+		assert.Error(t, err)
+		assert.NotEqual(t, custom_errors.ErrInvalidToken, err)
+	})
+}
@@ -0,0 +1,341 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lucaspichi06/xepelin-bank/internal/auth"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/idempotency"
+	"github.com/lucaspichi06/xepelin-bank/internal/monitoring"
+	"github.com/lucaspichi06/xepelin-bank/internal/signing"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"github.com/lucaspichi06/xepelin-bank/pkg/middleware"
+)
+
+// FileEnv is the environment variable of the configuration file, also set with the -config flag
+const FileEnv = "CONFIG_FILE"
+
+// Config is the configuration of the server
+type Config struct {
+	Server      Server
+	Database    Database
+	Auth        Auth
+	Signing     Signing
+	Idempotency Idempotency
+	FX          FX
+	Monitoring  Monitoring
+	Log         Log
+	Tracing     Tracing
+}
+
+type Server struct {
+	Addr string
+	// PublicHost is the host shown in the API docs
+	PublicHost     string
+	TLSCertFile    string
+	TLSKeyFile     string
+	RequestTimeout time.Duration
+	// ShutdownTimeout is the time the requests in flight have to finish once the server is stopped
+	ShutdownTimeout time.Duration
+}
+
+// TLS tells whether the server is served over TLS
+func (s Server) TLS() bool {
+	return s.TLSCertFile != ""
+}
+
+// The storage backends of Database.Driver
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	// DriverMemory keeps everything in memory, so it is lost when the server stops
+	DriverMemory = "memory"
+)
+
+// DefaultPostgresPort is the database.port of PostgreSQL when none is given, as the
+// default of Database.Port is the one of MySQL
+const DefaultPostgresPort = 5432
+
+type Database struct {
+	Driver   string
+	User     string
+	Password string
+	Host     string
+	Port     int
+	// Name is the path of the database file on SQLite
+	Name string
+	// SSLMode is the sslmode of the PostgreSQL connections
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// DSN is the data source name of the database. The SQLite transactions take the lock of
+// the database when they begin, and wait for it up to 5 seconds
+func (d Database) DSN() string {
+	switch d.Driver {
+	case DriverSQLite:
+		return "file:" + d.Name + "?_txlock=immediate&_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL"
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(d.User, d.Password),
+			Host:     net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+			Path:     "/" + d.Name,
+			RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+		}
+		return dsn.String()
+	}
+	cfg := mysql.NewConfig()
+	cfg.User = d.User
+	cfg.Passwd = d.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+	cfg.DBName = d.Name
+	cfg.ParseTime = true
+	return cfg.FormatDSN()
+}
+
+type Auth struct {
+	// BootstrapToken is the admin key used to issue the first API keys
+	BootstrapToken string
+	JWTHMACSecret  string
+	JWTJWKSFile    string
+	JWTIssuer      string
+	JWTAudience    string
+	JWTClockSkew   time.Duration
+	JWTAlgorithms  []string
+}
+
+type Signing struct {
+	// Keys are the request signing keys, signing is disabled when there are none
+	Keys      signing.Keys
+	Tolerance time.Duration
+}
+
+type Idempotency struct {
+	Retention time.Duration
+}
+
+type FX struct {
+	RatesFile string
+}
+
+type Monitoring struct {
+	RulesFile string
+	// LargeDeposit is the threshold of the default rules, used when there is no rules file
+	LargeDeposit domain.Money
+}
+
+type Log struct {
+	Level logger.Level
+}
+
+type Tracing struct {
+	Exporter string
+}
+
+// Default returns the configuration used for the settings that are not set
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8080",
+			RequestTimeout:  middleware.DefaultTimeout,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: Database{
+			Driver:          DriverMySQL,
+			Port:            3306,
+			Name:            "my_db",
+			SSLMode:         "require",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Auth: Auth{
+			JWTClockSkew: auth.DefaultClockSkew,
+		},
+		Signing: Signing{
+			Tolerance: signing.DefaultTolerance,
+		},
+		Idempotency: Idempotency{
+			Retention: idempotency.DefaultRetention,
+		},
+		Monitoring: Monitoring{
+			LargeDeposit: monitoring.DefaultLargeDeposit,
+		},
+		Log: Log{
+			Level: logger.LevelInfo,
+		},
+		Tracing: Tracing{
+			Exporter: "none",
+		},
+	}
+}
+
+// Load reads the configuration from the defaults, the configuration file, the environment
+// and the flags, each one overriding the previous ones, and validates it. The file is given
+// by the -config flag or the CONFIG_FILE environment variable
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", "", "configuration file, YAML or TOML ("+FileEnv+")")
+	flags := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flags[s.key] = fs.String(s.flag(), "", s.usage+" ("+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	cfg := Default()
+	var errs ValidationError
+	given := map[string]bool{}
+
+	if *path == "" {
+		*path = getenv(FileEnv)
+	}
+	if *path != "" {
+		values, err := readFile(*path)
+		if err != nil {
+			return Config{}, err
+		}
+		for _, key := range unknownKeys(values) {
+			errs = append(errs, fmt.Sprintf("%s: unknown setting in %s", key, *path))
+		}
+		for _, s := range settings {
+			if value, ok := values[s.key]; ok {
+				given[s.key] = true
+				errs = errs.add(s, value, *path, s.set(&cfg, value))
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			given[s.key] = true
+			errs = errs.add(s, value, s.env, s.set(&cfg, value))
+		}
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, s := range settings {
+		if set[s.flag()] {
+			given[s.key] = true
+			value := *flags[s.key]
+			errs = errs.add(s, value, "-"+s.flag(), s.set(&cfg, value))
+		}
+	}
+
+	if cfg.Database.Driver == DriverPostgres && !given["database.port"] {
+		cfg.Database.Port = DefaultPostgresPort
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err.(ValidationError)...)
+	}
+	if len(errs) > 0 {
+		return Config{}, errs
+	}
+	return cfg, nil
+}
+
+// Validate checks the settings make sense together, returning all the problems found
+func (c Config) Validate() error {
+	var errs ValidationError
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr: it is required")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file: both of them have to be set to serve TLS")
+	for _, path := range []string{c.Server.TLSCertFile, c.Server.TLSKeyFile} {
+		if path != "" {
+			_, err := os.Stat(path)
+			check(err == nil, "server.tls: %v", err)
+		}
+	}
+	check(c.Server.RequestTimeout >= 0, "server.request_timeout: it can not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: it has to be positive")
+
+	switch c.Database.Driver {
+	case DriverMySQL:
+		check(c.Database.User != "", "database.user: it is required")
+		check(c.Database.Host != "", "database.host: it is required")
+		check(c.Database.Name != "", "database.name: it is required")
+		check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port: %d is not a valid port", c.Database.Port)
+	case DriverPostgres:
+		check(c.Database.User != "", "database.user: it is required")
+		check(c.Database.Host != "", "database.host: it is required")
+		check(c.Database.Name != "", "database.name: it is required")
+		check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port: %d is not a valid port", c.Database.Port)
+		check(validSSLMode(c.Database.SSLMode), "database.ssl_mode: %q is not valid, it must be disable, require, verify-ca or verify-full", c.Database.SSLMode)
+	case DriverSQLite:
+		check(c.Database.Name != "", "database.name: it is required, it is the path of the database file")
+	case DriverMemory:
+	default:
+		check(false, "database.driver: %q is not valid, it must be mysql, postgres, sqlite or memory", c.Database.Driver)
+	}
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns: it can not be negative, 0 is unlimited")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns: it can not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns: %d is more than the %d max_open_conns", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime: it can not be negative, 0 is unlimited")
+
+	check(c.Auth.JWTClockSkew >= 0, "auth.jwt_clock_skew: it can not be negative")
+	for _, alg := range c.Auth.JWTAlgorithms {
+		check(alg == auth.HS256 || alg == auth.RS256 || alg == auth.ES256, "auth.jwt_algorithms: %q is not supported, it must be HS256, RS256 or ES256", alg)
+	}
+
+	check(c.Signing.Tolerance > 0, "signing.tolerance: it has to be positive")
+	check(c.Idempotency.Retention > 0, "idempotency.retention: it has to be positive")
+	check(c.Monitoring.LargeDeposit.IsPositive(), "monitoring.large_deposit: it has to be positive")
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "stdout", "tracing.exporter: %q is not valid, it must be stdout or none", c.Tracing.Exporter)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validSSLMode reports whether the sslmode is one the PostgreSQL driver supports
+func validSSLMode(mode string) bool {
+	switch mode {
+	case "disable", "require", "verify-ca", "verify-full":
+		return true
+	}
+	return false
+}
+
+// ValidationError lists every invalid setting, so they can all be fixed at once
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// add records the error of a setting set from a source, if any
+func (e ValidationError) add(s setting, value, source string, err error) ValidationError {
+	if err == nil {
+		return e
+	}
+	if s.secret {
+		value = "***"
+	}
+	return append(e, fmt.Sprintf("%s: invalid value %q from %s: %v", s.key, value, source, err))
+}
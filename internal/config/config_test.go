@@ -0,0 +1,229 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// env returns a getenv reading the variables given
+func env(vars map[string]string) func(string) string {
+	return func(key string) string {
+		return vars[key]
+	}
+}
+
+var database = map[string]string{"DB_USER": "user", "DB_PASS": "pass", "DB_HOST": "localhost"}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("load defaults and environment", func(t *testing.T) {
+		cfg, err := Load(nil, env(map[string]string{
+			"DB_USER":                   "user",
+			"DB_PASS":                   "pass",
+			"DB_HOST":                   "db",
+			"DB_PORT":                   "3307",
+			"TOKEN":                     "secret",
+			"JWT_ALGORITHMS":            "HS256, RS256",
+			"REQUEST_SIGNING_KEYS":      "k1:s1,k2:s2",
+			"MONITORING_LARGE_DEPOSIT":  "5000.50 eur",
+			"LOG_LEVEL":                 "debug",
+			"REQUEST_SIGNING_TOLERANCE": "1m",
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":8080", cfg.Server.Addr)
+		assert.Equal(t, 10*time.Second, cfg.Server.RequestTimeout)
+		assert.Equal(t, Database{Driver: DriverMySQL, User: "user", Password: "pass", Host: "db", Port: 3307, Name: "my_db", SSLMode: "require", MaxOpenConns: 25, MaxIdleConns: 25, ConnMaxLifetime: 5 * time.Minute}, cfg.Database)
+		assert.Equal(t, "secret", cfg.Auth.BootstrapToken)
+		assert.Equal(t, []string{"HS256", "RS256"}, cfg.Auth.JWTAlgorithms)
+		assert.Equal(t, []byte("s2"), cfg.Signing.Keys["k2"])
+		assert.Equal(t, time.Minute, cfg.Signing.Tolerance)
+		assert.Equal(t, domain.NewMoney(500050, "EUR"), cfg.Monitoring.LargeDeposit)
+		assert.Equal(t, logger.LevelDebug, cfg.Log.Level)
+		assert.Equal(t, "none", cfg.Tracing.Exporter)
+	})
+	t.Run("load yaml file", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9090"
+  request_timeout: 30s
+database:
+  user: file
+  host: db
+  max_open_conns: 50
+auth:
+  jwt_algorithms: [ES256]
+`)
+		cfg, err := Load([]string{"-config", path}, env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":9090", cfg.Server.Addr)
+		assert.Equal(t, 30*time.Second, cfg.Server.RequestTimeout)
+		assert.Equal(t, "file", cfg.Database.User)
+		assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+		assert.Equal(t, []string{"ES256"}, cfg.Auth.JWTAlgorithms)
+	})
+	t.Run("load toml file", func(t *testing.T) {
+		path := writeFile(t, "config.toml", `
+[database]
+user = "file"
+host = "db"
+max_idle_conns = 5
+
+[monitoring]
+large_deposit = "20000"
+`)
+		cfg, err := Load(nil, env(map[string]string{FileEnv: path}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, cfg.Database.MaxIdleConns)
+		assert.Equal(t, domain.NewMoney(2000000, domain.DefaultCurrency), cfg.Monitoring.LargeDeposit)
+	})
+	t.Run("override file with environment and flags", func(t *testing.T) {
+		path := writeFile(t, "config.yml", `
+server:
+  addr: ":9090"
+database:
+  user: file
+  host: db
+  port: 3310
+`)
+		cfg, err := Load([]string{"-config", path, "-database-port", "3320"}, env(map[string]string{
+			"LISTEN_ADDR": ":7070",
+			"DB_PORT":     "3315",
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":7070", cfg.Server.Addr)
+		assert.Equal(t, 3320, cfg.Database.Port)
+		assert.Equal(t, "file", cfg.Database.User)
+	})
+	t.Run("default the postgres port", func(t *testing.T) {
+		cfg, err := Load(nil, env(map[string]string{"DB_DRIVER": "postgres", "DB_USER": "user", "DB_HOST": "db"}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultPostgresPort, cfg.Database.Port)
+
+		cfg, err = Load([]string{"-database-port", "3306"}, env(map[string]string{"DB_DRIVER": "postgres", "DB_USER": "user", "DB_HOST": "db"}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3306, cfg.Database.Port)
+	})
+	t.Run("report every invalid value", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+database:
+  max_conns: 10
+`)
+		_, err := Load([]string{"-config", path, "-server-request-timeout", "soon"}, env(map[string]string{
+			"DB_PORT":              "mysql",
+			"REQUEST_SIGNING_KEYS": "no-secret",
+		}))
+
+		assert.IsType(t, ValidationError{}, err)
+		assert.Equal(t, ValidationError{
+			"database.max_conns: unknown setting in " + path,
+			`database.port: invalid value "mysql" from DB_PORT: it must be an integer`,
+			`signing.keys: invalid value "***" from REQUEST_SIGNING_KEYS: invalid signing key "no-secret", it must be id:secret`,
+			`server.request_timeout: invalid value "soon" from -server-request-timeout: it must be a duration, e.g. 30s or 5m`,
+			"database.user: it is required",
+			"database.host: it is required",
+		}, err)
+	})
+	t.Run("reject unsupported file", func(t *testing.T) {
+		_, err := Load([]string{"-config", writeFile(t, "config.json", "{}")}, env(nil))
+
+		assert.Error(t, err)
+	})
+	t.Run("reject arguments", func(t *testing.T) {
+		_, err := Load([]string{"serve"}, env(database))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Config {
+		cfg := Default()
+		cfg.Database.User, cfg.Database.Host = "user", "localhost"
+		return cfg
+	}
+
+	t.Run("valid configuration", func(t *testing.T) {
+		assert.NoError(t, valid().Validate())
+	})
+	t.Run("invalid configuration", func(t *testing.T) {
+		cfg := valid()
+		cfg.Database.Host = ""
+		cfg.Database.MaxOpenConns = 10
+		cfg.Database.MaxIdleConns = 20
+		cfg.Server.TLSCertFile = "cert.pem"
+		cfg.Auth.JWTAlgorithms = []string{"none"}
+		cfg.Tracing.Exporter = "jaeger"
+		cfg.Monitoring.LargeDeposit = domain.NewMoney(0, domain.DefaultCurrency)
+
+		err := cfg.Validate()
+
+		assert.Equal(t, ValidationError{
+			"server.tls_cert_file and server.tls_key_file: both of them have to be set to serve TLS",
+			"server.tls: stat cert.pem: no such file or directory",
+			"database.host: it is required",
+			"database.max_idle_conns: 20 is more than the 10 max_open_conns",
+			`auth.jwt_algorithms: "none" is not supported, it must be HS256, RS256 or ES256`,
+			"monitoring.large_deposit: it has to be positive",
+			`tracing.exporter: "jaeger" is not valid, it must be stdout or none`,
+		}, err)
+	})
+	t.Run("sqlite only needs the database file", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.Driver = DriverSQLite
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Database.Name = ""
+		assert.Equal(t, ValidationError{"database.name: it is required, it is the path of the database file"}, cfg.Validate())
+	})
+	t.Run("postgres needs a server and a valid ssl mode", func(t *testing.T) {
+		cfg := valid()
+		cfg.Database.Driver, cfg.Database.Port = DriverPostgres, 5432
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Database.Host, cfg.Database.SSLMode = "", "prefer"
+		assert.Equal(t, ValidationError{
+			"database.host: it is required",
+			`database.ssl_mode: "prefer" is not valid, it must be disable, require, verify-ca or verify-full`,
+		}, cfg.Validate())
+	})
+	t.Run("memory needs no database", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database = Database{Driver: DriverMemory}
+		assert.NoError(t, cfg.Validate())
+	})
+	t.Run("unknown driver", func(t *testing.T) {
+		cfg := valid()
+		cfg.Database.Driver = "oracle"
+
+		assert.Equal(t, ValidationError{`database.driver: "oracle" is not valid, it must be mysql, postgres, sqlite or memory`}, cfg.Validate())
+	})
+}
+
+func TestDSN(t *testing.T) {
+	d := Database{User: "user", Password: "p@ss", Host: "db", Port: 3306, Name: "my_db"}
+
+	assert.Equal(t, "user:p@ss@tcp(db:3306)/my_db?parseTime=true", d.DSN())
+
+	d = Database{Driver: DriverSQLite, Name: "/data/bank.db"}
+	assert.Equal(t, "file:/data/bank.db?_txlock=immediate&_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL", d.DSN())
+
+	d = Database{Driver: DriverPostgres, User: "user", Password: "p@ss", Host: "db", Port: 5432, Name: "my_db", SSLMode: "disable"}
+	assert.Equal(t, "postgres://user:p%40ss@db:5432/my_db?sslmode=disable", d.DSN())
+}
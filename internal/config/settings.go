@@ -0,0 +1,240 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/signing"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting is a value of the configuration, named by its key in the file, its environment
+// variable and its flag. The values of every source are parsed by the same set func
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	set    func(c *Config, value string) error
+}
+
+// flag is the name of the flag of the setting, e.g. -database-max-open-conns
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+var settings = []setting{
+	{key: "server.addr", env: "LISTEN_ADDR", usage: "address the server listens on",
+		set: stringVar(func(c *Config) *string { return &c.Server.Addr })},
+	{key: "server.public_host", env: "HOST", usage: "host shown in the API docs",
+		set: stringVar(func(c *Config) *string { return &c.Server.PublicHost })},
+	{key: "server.tls_cert_file", env: "TLS_CERT_FILE", usage: "TLS certificate file, the server is served over TLS when it is set",
+		set: stringVar(func(c *Config) *string { return &c.Server.TLSCertFile })},
+	{key: "server.tls_key_file", env: "TLS_KEY_FILE", usage: "TLS private key file",
+		set: stringVar(func(c *Config) *string { return &c.Server.TLSKeyFile })},
+	{key: "server.request_timeout", env: "REQUEST_TIMEOUT", usage: "time a request has to be processed, 0 disables it",
+		set: durationVar(func(c *Config) *time.Duration { return &c.Server.RequestTimeout })},
+	{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", usage: "time the requests in flight have to finish once the server is stopped",
+		set: durationVar(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+
+	{key: "database.driver", env: "DB_DRIVER", usage: "storage backend: mysql, postgres, sqlite or memory",
+		set: stringVar(func(c *Config) *string { return &c.Database.Driver })},
+	{key: "database.user", env: "DB_USER", usage: "database user",
+		set: stringVar(func(c *Config) *string { return &c.Database.User })},
+	{key: "database.password", env: "DB_PASS", usage: "database password", secret: true,
+		set: stringVar(func(c *Config) *string { return &c.Database.Password })},
+	{key: "database.host", env: "DB_HOST", usage: "database host",
+		set: stringVar(func(c *Config) *string { return &c.Database.Host })},
+	{key: "database.port", env: "DB_PORT", usage: "database port",
+		set: intVar(func(c *Config) *int { return &c.Database.Port })},
+	{key: "database.name", env: "DB_NAME", usage: "database name",
+		set: stringVar(func(c *Config) *string { return &c.Database.Name })},
+	{key: "database.ssl_mode", env: "DB_SSL_MODE", usage: "sslmode of the PostgreSQL connections: disable, require, verify-ca or verify-full",
+		set: stringVar(func(c *Config) *string { return &c.Database.SSLMode })},
+	{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum open connections, 0 is unlimited",
+		set: intVar(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum idle connections",
+		set: intVar(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "maximum time a connection is reused, 0 is unlimited",
+		set: durationVar(func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime })},
+
+	{key: "auth.bootstrap_token", env: "TOKEN", usage: "bootstrap admin key, used to issue the first API keys", secret: true,
+		set: stringVar(func(c *Config) *string { return &c.Auth.BootstrapToken })},
+	{key: "auth.jwt_hmac_secret", env: "JWT_HMAC_SECRET", usage: "secret of the HS256 bearer tokens", secret: true,
+		set: stringVar(func(c *Config) *string { return &c.Auth.JWTHMACSecret })},
+	{key: "auth.jwt_jwks_file", env: "JWT_JWKS_FILE", usage: "JWKS file with the public keys of the bearer tokens",
+		set: stringVar(func(c *Config) *string { return &c.Auth.JWTJWKSFile })},
+	{key: "auth.jwt_issuer", env: "JWT_ISSUER", usage: "issuer the bearer tokens must have",
+		set: stringVar(func(c *Config) *string { return &c.Auth.JWTIssuer })},
+	{key: "auth.jwt_audience", env: "JWT_AUDIENCE", usage: "audience the bearer tokens must have",
+		set: stringVar(func(c *Config) *string { return &c.Auth.JWTAudience })},
+	{key: "auth.jwt_clock_skew", env: "JWT_CLOCK_SKEW", usage: "leeway given to the time claims of the bearer tokens",
+		set: durationVar(func(c *Config) *time.Duration { return &c.Auth.JWTClockSkew })},
+	{key: "auth.jwt_algorithms", env: "JWT_ALGORITHMS", usage: "comma separated algorithms accepted for the bearer tokens",
+		set: listVar(func(c *Config) *[]string { return &c.Auth.JWTAlgorithms })},
+
+	{key: "signing.keys", env: "REQUEST_SIGNING_KEYS", usage: "comma separated id:secret request signing keys", secret: true,
+		set: func(c *Config, value string) (err error) {
+			c.Signing.Keys, err = signing.ParseKeys(value)
+			return err
+		}},
+	{key: "signing.tolerance", env: "REQUEST_SIGNING_TOLERANCE", usage: "accepted distance between the signed timestamp and the server clock",
+		set: durationVar(func(c *Config) *time.Duration { return &c.Signing.Tolerance })},
+
+	{key: "idempotency.retention", env: "IDEMPOTENCY_RETENTION", usage: "time the idempotency keys are kept",
+		set: durationVar(func(c *Config) *time.Duration { return &c.Idempotency.Retention })},
+
+	{key: "fx.rates_file", env: "FX_RATES_FILE", usage: "JSON file with the exchange rates",
+		set: stringVar(func(c *Config) *string { return &c.FX.RatesFile })},
+
+	{key: "monitoring.rules_file", env: "MONITORING_RULES_FILE", usage: "JSON file with the monitoring rules",
+		set: stringVar(func(c *Config) *string { return &c.Monitoring.RulesFile })},
+	{key: "monitoring.large_deposit", env: "MONITORING_LARGE_DEPOSIT", usage: "deposits alerted by the default rules, e.g. 10000 or 10000 USD",
+		set: func(c *Config, value string) (err error) {
+			c.Monitoring.LargeDeposit, err = parseMoney(value)
+			return err
+		}},
+
+	{key: "log.level", env: "LOG_LEVEL", usage: "minimum level logged: debug, info, warn or error",
+		set: func(c *Config, value string) (err error) {
+			c.Log.Level, err = logger.ParseLevel(value)
+			return err
+		}},
+
+	{key: "tracing.exporter", env: "TRACING_EXPORTER", usage: "where the spans are exported: stdout or none",
+		set: stringVar(func(c *Config) *string { return &c.Tracing.Exporter })},
+}
+
+var settingsByKey = func() map[string]setting {
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+	return byKey
+}()
+
+func stringVar(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func intVar(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("it must be an integer")
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func durationVar(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("it must be a duration, e.g. 30s or 5m")
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func listVar(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+// parseMoney reads an amount followed by its currency, USD when it is not given
+func parseMoney(value string) (domain.Money, error) {
+	fields := strings.Fields(value)
+	switch len(fields) {
+	case 1:
+		return domain.ParseMoney(fields[0], domain.DefaultCurrency)
+	case 2:
+		return domain.ParseMoney(fields[0], strings.ToUpper(fields[1]))
+	default:
+		return domain.Money{}, fmt.Errorf("it must be an amount and an optional currency")
+	}
+}
+
+// readFile reads the settings of a YAML or TOML file, by their key, e.g.
+//
+//	database:
+//	  max_open_conns: 50
+//
+// is the database.max_open_conns setting. Lists are read as comma separated values
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("configuration file %s: unsupported format %q, it must be .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("configuration file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", tree, values)
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, values)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// unknownKeys returns the keys of the file that are not settings, sorted
+func unknownKeys(values map[string]string) []string {
+	var unknown []string
+	for key := range values {
+		if _, ok := settingsByKey[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -1,13 +1,104 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// AccountStatus tells whether money can move in and out of an account
+type AccountStatus string
+
+const (
+	AccountActive AccountStatus = "active"
+	// AccountFrozen keeps the money of the account, but no operation can move it
+	AccountFrozen AccountStatus = "frozen"
+	// AccountClosed is an account with no money left, it can be reopened
+	AccountClosed AccountStatus = "closed"
+)
 
 type Account struct {
-	ID      uuid.UUID `json:"id"`
-	Name    string    `json:"name"`
-	Balance float64   `json:"balance"`
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Currency is the ISO-4217 currency of the account, fixed when it is created
+	Currency string        `json:"currency"`
+	Balance  Money         `json:"balance" swaggertype:"number"`
+	Status   AccountStatus `json:"status" enums:"active,frozen,closed"`
+	// Version is the sequence of the last event applied to the account
+	Version int64 `json:"-"`
 }
 
 type AccountRequest struct {
 	Name string `json:"name" binding:"required"`
+	// Currency is the ISO-4217 currency of the account, USD when it is not sent
+	Currency string `json:"currency"`
+}
+
+// AccountStatusRequest changes the status of an account
+type AccountStatusRequest struct {
+	Status AccountStatus `json:"status" binding:"required" enums:"active,frozen,closed"`
+	// SweepDestinationID is the account the balance is transferred to when the account is
+	// closed with money left
+	SweepDestinationID *uuid.UUID `json:"sweep_destination_id,omitempty"`
+}
+
+// CheckActive returns why no money can move in or out of the account, nil when it is active
+func (a Account) CheckActive() error {
+	switch a.Status {
+	case AccountFrozen:
+		return custom_errors.ErrAccountFrozen
+	case AccountClosed:
+		return custom_errors.ErrAccountClosed
+	}
+	return nil
+}
+
+// CheckStatusChange returns why the account can not change to the status. Frozen accounts
+// are unfrozen or closed, and closed accounts can only be reopened. Closing an account
+// requires a zero balance
+func (a Account) CheckStatusChange(status AccountStatus) error {
+	switch status {
+	case AccountActive, AccountFrozen, AccountClosed:
+	default:
+		return custom_errors.ErrInvalidAccountStatus
+	}
+	if status == a.Status || (a.Status == AccountClosed && status == AccountFrozen) {
+		return fmt.Errorf("%w: the account is %s", custom_errors.ErrInvalidStatusTransition, a.Status)
+	}
+	if status == AccountClosed && !a.Balance.IsZero() {
+		return custom_errors.ErrAccountNotEmpty
+	}
+	return nil
+}
+
+// AccountSort is the column the accounts are listed by
+type AccountSort string
+
+const (
+	SortByName    AccountSort = "name"
+	SortByBalance AccountSort = "balance"
+)
+
+// AccountFilter selects accounts, sorted by name when no sort is set, ascending unless
+// Descending is set. Nil or empty fields do not filter
+type AccountFilter struct {
+	// NamePrefix selects the accounts whose name starts with it, ignoring the case
+	NamePrefix string
+	Statuses   []AccountStatus
+	Currency   string
+	// MinBalance and MaxBalance are in the currency filtered, they require one
+	MinBalance *Money
+	MaxBalance *Money
+	Sort       AccountSort
+	Descending bool
+	// Cursor is the opaque position returned as NextCursor by the previous page
+	Cursor string
+	Limit  int
+}
+
+// AccountPage is a page of accounts, in the order of the filter
+type AccountPage struct {
+	Accounts   []Account `json:"accounts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferOut EventType = "transfer_out"
+	TransferIn  EventType = "transfer_in"
+	// StatusChange moves the account to the status of the event
+	StatusChange EventType = "status_change"
+)
+
+// AccountEvent is a fact recorded in the stream of an account. The state of an
+// account is the result of applying its events in sequence order
+type AccountEvent struct {
+	AccountID      uuid.UUID  `json:"account_id"`
+	Sequence       int64      `json:"sequence"`
+	Type           EventType  `json:"type"`
+	Amount         Money      `json:"amount" swaggertype:"number"`
+	Name           string     `json:"name,omitempty"`
+	CounterpartyID *uuid.UUID `json:"counterparty_id,omitempty"`
+	// Status is the status the account changes to, set on status change events
+	Status    AccountStatus `json:"status,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// Apply moves the account to the state right after the event. The account is left
+// unchanged when the event amount is not in the account currency
+func (a *Account) Apply(e AccountEvent) error {
+	balance := a.Balance
+	var err error
+	switch e.Type {
+	case Create:
+		a.ID = e.AccountID
+		a.Name = e.Name
+		a.Currency = e.Amount.Currency
+		a.Status = AccountActive
+		balance = e.Amount
+	case Deposit, TransferIn:
+		balance, err = a.Balance.Add(e.Amount)
+	case WithDraw, TransferOut:
+		balance, err = a.Balance.Sub(e.Amount)
+	case StatusChange:
+		a.Status = e.Status
+	}
+	if err != nil {
+		return err
+	}
+	a.Balance = balance
+	a.Version = e.Sequence
+	return nil
+}
+
+// ReplayAccount rebuilds an account from its events
+func ReplayAccount(events []AccountEvent) (Account, error) {
+	var acc Account
+	for _, e := range events {
+		if err := acc.Apply(e); err != nil {
+			return Account{}, err
+		}
+	}
+	return acc, nil
+}
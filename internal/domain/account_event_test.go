@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReplayAccount(t *testing.T) {
+	id, counterparty := uuid.New(), uuid.New()
+	acc, err := ReplayAccount([]AccountEvent{
+		{AccountID: id, Sequence: 1, Type: Create, Name: "test", Amount: NewMoney(1000, "USD")},
+		{AccountID: id, Sequence: 2, Type: Deposit, Amount: NewMoney(500, "USD")},
+		{AccountID: id, Sequence: 3, Type: WithDraw, Amount: NewMoney(200, "USD")},
+		{AccountID: id, Sequence: 4, Type: TransferOut, Amount: NewMoney(300, "USD"), CounterpartyID: &counterparty},
+		{AccountID: id, Sequence: 5, Type: TransferIn, Amount: NewMoney(50, "USD"), CounterpartyID: &counterparty},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, id, acc.ID)
+	assert.Equal(t, "test", acc.Name)
+	assert.Equal(t, NewMoney(1050, "USD"), acc.Balance)
+	assert.Equal(t, int64(5), acc.Version)
+}
+
+func TestApplyStatusChange(t *testing.T) {
+	var acc Account
+	assert.NoError(t, acc.Apply(AccountEvent{Sequence: 1, Type: Create, Name: "test", Amount: NewMoney(0, "USD")}))
+	assert.Equal(t, AccountActive, acc.Status)
+
+	assert.NoError(t, acc.Apply(AccountEvent{Sequence: 2, Type: StatusChange, Amount: NewMoney(0, "USD"), Status: AccountClosed}))
+	assert.Equal(t, AccountClosed, acc.Status)
+	assert.Equal(t, NewMoney(0, "USD"), acc.Balance)
+	assert.Equal(t, int64(2), acc.Version)
+}
+
+func TestApplyCurrencyMismatch(t *testing.T) {
+	var acc Account
+	assert.NoError(t, acc.Apply(AccountEvent{Sequence: 1, Type: Create, Name: "test", Amount: NewMoney(1000, "USD")}))
+
+	err := acc.Apply(AccountEvent{Sequence: 2, Type: Deposit, Amount: NewMoney(500, "EUR")})
+
+	assert.ErrorIs(t, err, custom_errors.ErrCurrencyMismatch)
+	assert.Equal(t, NewMoney(1000, "USD"), acc.Balance)
+	assert.Equal(t, int64(1), acc.Version)
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAccountCheckActive(t *testing.T) {
+	assert.NoError(t, Account{Status: AccountActive}.CheckActive())
+	assert.Equal(t, custom_errors.ErrAccountFrozen, Account{Status: AccountFrozen}.CheckActive())
+	assert.Equal(t, custom_errors.ErrAccountClosed, Account{Status: AccountClosed}.CheckActive())
+}
+
+func TestAccountCheckStatusChange(t *testing.T) {
+	empty := NewMoney(0, "USD")
+	for _, tc := range []struct {
+		name    string
+		from    AccountStatus
+		balance Money
+		to      AccountStatus
+		err     error
+	}{
+		{"freeze", AccountActive, NewMoney(100, "USD"), AccountFrozen, nil},
+		{"unfreeze", AccountFrozen, NewMoney(100, "USD"), AccountActive, nil},
+		{"close empty", AccountActive, empty, AccountClosed, nil},
+		{"close frozen empty", AccountFrozen, empty, AccountClosed, nil},
+		{"reopen", AccountClosed, empty, AccountActive, nil},
+		{"close with money", AccountActive, NewMoney(100, "USD"), AccountClosed, custom_errors.ErrAccountNotEmpty},
+		{"same status", AccountFrozen, empty, AccountFrozen, custom_errors.ErrInvalidStatusTransition},
+		{"freeze closed", AccountClosed, empty, AccountFrozen, custom_errors.ErrInvalidStatusTransition},
+		{"unknown status", AccountActive, empty, "deleted", custom_errors.ErrInvalidAccountStatus},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Account{Status: tc.from, Balance: tc.balance}.CheckStatusChange(tc.to)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tc.err), err)
+		})
+	}
+}
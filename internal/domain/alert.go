@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alert is raised when a processed transaction matches a monitoring rule
+type Alert struct {
+	ID            uuid.UUID `json:"id"`
+	Rule          string    `json:"rule"`
+	AccountID     uuid.UUID `json:"account_id"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	Type          EventType `json:"type"`
+	Amount        Money     `json:"amount" swaggertype:"number"`
+	// Details explains why the rule matched
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AlertFilter selects alerts, newest first. Nil or empty fields do not filter
+type AlertFilter struct {
+	AccountID *uuid.UUID
+	Rule      string
+	From      *time.Time
+	To        *time.Time
+	// Cursor is the opaque position returned as NextCursor by the previous page
+	Cursor string
+	Limit  int
+}
+
+// AlertPage is a page of alerts, newest first
+type AlertPage struct {
+	Alerts     []Alert `json:"alerts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
 
 const (
 	Create   EventType = "create"
@@ -11,7 +15,7 @@ const (
 )
 
 type Event interface {
-	Process() (Account, error)
+	Process(ctx context.Context) (Account, error)
 }
 
 type EventType string
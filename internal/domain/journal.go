@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// CustomerLedgerAccount is the ledger account of a bank account
+func CustomerLedgerAccount(id uuid.UUID) string {
+	return "account:" + id.String()
+}
+
+// CashLedgerAccount is the system ledger account of the money deposited in and withdrawn
+// from the bank
+func CashLedgerAccount(currency string) string {
+	return "system:cash:" + currency
+}
+
+// ClearingLedgerAccount is the system ledger account transfers go through, so an entry
+// balances in every currency even when the transfer converts between them
+func ClearingLedgerAccount(currency string) string {
+	return "system:clearing:" + currency
+}
+
+// Posting is a line of a journal entry. Amount is positive for debits and negative for credits
+type Posting struct {
+	LedgerAccount string `json:"ledger_account"`
+	Amount        Money  `json:"amount" swaggertype:"number"`
+}
+
+// JournalEntry is the double-entry record of a transaction. Its postings add up to zero
+// in every currency
+type JournalEntry struct {
+	ID            uuid.UUID `json:"id"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Postings      []Posting `json:"postings"`
+}
+
+// NewJournalEntry builds the balanced journal entry of a processed transaction. The
+// customer accounts are liabilities of the bank: a deposit debits cash and credits the
+// account, a withdrawal does the opposite, and a transfer debits the source account and
+// credits the destination account through the clearing account of each currency
+func NewJournalEntry(tr Transaction) (JournalEntry, error) {
+	entry := JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: tr.ID,
+		Timestamp:     tr.Timestamp,
+	}
+	account := CustomerLedgerAccount(tr.AccountID)
+	amount := tr.Amount
+
+	switch tr.Type {
+	case Deposit:
+		entry.Postings = []Posting{
+			{LedgerAccount: CashLedgerAccount(amount.Currency), Amount: amount},
+			{LedgerAccount: account, Amount: negate(amount)},
+		}
+	case WithDraw:
+		entry.Postings = []Posting{
+			{LedgerAccount: account, Amount: amount},
+			{LedgerAccount: CashLedgerAccount(amount.Currency), Amount: negate(amount)},
+		}
+	case Transfer:
+		if tr.DestinationID == nil {
+			return JournalEntry{}, custom_errors.ErrInvalidTransactionDestination
+		}
+		credited := amount
+		if tr.DestinationAmount != nil {
+			credited = *tr.DestinationAmount
+		}
+		entry.Postings = []Posting{
+			{LedgerAccount: account, Amount: amount},
+			{LedgerAccount: ClearingLedgerAccount(amount.Currency), Amount: negate(amount)},
+			{LedgerAccount: ClearingLedgerAccount(credited.Currency), Amount: credited},
+			{LedgerAccount: CustomerLedgerAccount(*tr.DestinationID), Amount: negate(credited)},
+		}
+	default:
+		return JournalEntry{}, custom_errors.ErrInvalidTransactionType
+	}
+
+	if !entry.Balanced() {
+		return JournalEntry{}, custom_errors.ErrLedgerUnbalanced
+	}
+	return entry, nil
+}
+
+// Balanced reports whether the postings add up to zero in every currency
+func (e JournalEntry) Balanced() bool {
+	return len(e.Postings) > 0 && len(UnbalancedTotals(e.Postings)) == 0
+}
+
+// UnbalancedTotals adds up the postings by currency, returning the currencies whose total
+// is not zero
+func UnbalancedTotals(postings []Posting) map[string]int64 {
+	totals := make(map[string]int64)
+	for _, p := range postings {
+		totals[p.Amount.Currency] += p.Amount.Amount
+	}
+	for currency, total := range totals {
+		if total == 0 {
+			delete(totals, currency)
+		}
+	}
+	return totals
+}
+
+func negate(m Money) Money {
+	return NewMoney(-m.Amount, m.Currency)
+}
+
+// LedgerVerification is the result of checking that the whole ledger is balanced
+type LedgerVerification struct {
+	Balanced bool `json:"balanced"`
+	// Unbalanced holds the total of the currencies whose postings do not add up to zero
+	Unbalanced map[string]Money `json:"unbalanced,omitempty" swaggertype:"object,number"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewJournalEntry(t *testing.T) {
+	id, other := uuid.New(), uuid.New()
+
+	t.Run("deposit debits cash and credits the account", func(t *testing.T) {
+		entry, err := NewJournalEntry(Transaction{ID: uuid.New(), AccountID: id, Type: Deposit, Amount: NewMoney(1000, "USD")})
+		assert.NoError(t, err)
+		assert.Equal(t, []Posting{
+			{LedgerAccount: "system:cash:USD", Amount: NewMoney(1000, "USD")},
+			{LedgerAccount: "account:" + id.String(), Amount: NewMoney(-1000, "USD")},
+		}, entry.Postings)
+		assert.True(t, entry.Balanced())
+	})
+	t.Run("withdraw debits the account and credits cash", func(t *testing.T) {
+		entry, err := NewJournalEntry(Transaction{ID: uuid.New(), AccountID: id, Type: WithDraw, Amount: NewMoney(1000, "USD")})
+		assert.NoError(t, err)
+		assert.Equal(t, []Posting{
+			{LedgerAccount: "account:" + id.String(), Amount: NewMoney(1000, "USD")},
+			{LedgerAccount: "system:cash:USD", Amount: NewMoney(-1000, "USD")},
+		}, entry.Postings)
+	})
+	t.Run("transfer goes through the clearing account", func(t *testing.T) {
+		entry, err := NewJournalEntry(Transaction{ID: uuid.New(), AccountID: id, DestinationID: &other, Type: Transfer, Amount: NewMoney(1000, "USD")})
+		assert.NoError(t, err)
+		assert.Equal(t, []Posting{
+			{LedgerAccount: "account:" + id.String(), Amount: NewMoney(1000, "USD")},
+			{LedgerAccount: "system:clearing:USD", Amount: NewMoney(-1000, "USD")},
+			{LedgerAccount: "system:clearing:USD", Amount: NewMoney(1000, "USD")},
+			{LedgerAccount: "account:" + other.String(), Amount: NewMoney(-1000, "USD")},
+		}, entry.Postings)
+	})
+	t.Run("converted transfer balances in both currencies", func(t *testing.T) {
+		credited := NewMoney(920, "EUR")
+		entry, err := NewJournalEntry(Transaction{ID: uuid.New(), AccountID: id, DestinationID: &other, Type: Transfer, Amount: NewMoney(1000, "USD"), DestinationAmount: &credited})
+		assert.NoError(t, err)
+		assert.True(t, entry.Balanced())
+		assert.Equal(t, NewMoney(-920, "EUR"), entry.Postings[3].Amount)
+	})
+	t.Run("transfer without destination", func(t *testing.T) {
+		_, err := NewJournalEntry(Transaction{ID: uuid.New(), AccountID: id, Type: Transfer, Amount: NewMoney(1000, "USD")})
+		assert.Equal(t, custom_errors.ErrInvalidTransactionDestination, err)
+	})
+	t.Run("invalid type", func(t *testing.T) {
+		_, err := NewJournalEntry(Transaction{ID: uuid.New(), AccountID: id, Type: Balance, Amount: NewMoney(1000, "USD")})
+		assert.Equal(t, custom_errors.ErrInvalidTransactionType, err)
+	})
+}
+
+func TestUnbalancedTotals(t *testing.T) {
+	totals := UnbalancedTotals([]Posting{
+		{LedgerAccount: "a", Amount: NewMoney(1000, "USD")},
+		{LedgerAccount: "b", Amount: NewMoney(-1000, "USD")},
+		{LedgerAccount: "c", Amount: NewMoney(500, "EUR")},
+	})
+	assert.Equal(t, map[string]int64{"EUR": 500}, totals)
+	assert.False(t, JournalEntry{}.Balanced())
+}
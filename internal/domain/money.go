@@ -0,0 +1,219 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// DefaultCurrency is the currency used when none is provided
+const DefaultCurrency = "USD"
+
+// currencyExponents holds the number of decimal places (minor units) of each supported ISO-4217 currency
+var currencyExponents = map[string]int{
+	"ARS": 2,
+	"BHD": 3,
+	"BRL": 2,
+	"CLP": 0,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"MXN": 2,
+	"USD": 2,
+}
+
+// CurrencyExponent returns the number of decimal places allowed by the currency
+func CurrencyExponent(currency string) (int, bool) {
+	exp, ok := currencyExponents[currency]
+	return exp, ok
+}
+
+// Money is an exact amount of a currency, expressed in its minor units (e.g. cents)
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+	}
+}
+
+// ParseMoney parses a decimal amount such as "100.25" into money of the given currency.
+// Amounts with more decimal places than the currency allows are rejected
+func ParseMoney(value string, currency string) (Money, error) {
+	exp, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, custom_errors.ErrInvalidCurrency
+	}
+
+	negative, units, fraction, err := splitDecimal(value)
+	if err != nil {
+		return Money{}, err
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exp {
+		return Money{}, fmt.Errorf("%w: %s allows %d decimal places", custom_errors.ErrInvalidAmountPrecision, currency, exp)
+	}
+	fraction += strings.Repeat("0", exp-len(fraction))
+
+	amount, err := strconv.ParseInt(units+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", custom_errors.ErrInvalidAmount, value)
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return NewMoney(amount, currency), nil
+}
+
+// splitDecimal splits a decimal number such as "-100.25" into its sign, units and fraction digits
+func splitDecimal(value string) (negative bool, units string, fraction string, err error) {
+	negative = strings.HasPrefix(value, "-")
+	units = strings.TrimPrefix(value, "-")
+
+	if i := strings.IndexByte(units, '.'); i >= 0 {
+		units, fraction = units[:i], units[i+1:]
+	}
+	if units == "" || !isDigits(units) || !isDigits(fraction) {
+		return false, "", "", fmt.Errorf("%w: %q", custom_errors.ErrInvalidAmount, value)
+	}
+	return negative, units, fraction, nil
+}
+
+// Decimal is an amount whose currency is not known yet, kept as sent so its decimal
+// places are only checked once it is parsed into the currency
+type Decimal string
+
+// ParseDecimal checks that the value is a plain decimal number such as "100.25"
+func ParseDecimal(value string) (Decimal, error) {
+	if _, _, _, err := splitDecimal(value); err != nil {
+		return "", err
+	}
+	return Decimal(value), nil
+}
+
+// Money parses the decimal into money of the given currency
+func (d Decimal) Money(currency string) (Money, error) {
+	return ParseMoney(string(d), currency)
+}
+
+func (d Decimal) IsPositive() bool {
+	negative, units, fraction, err := splitDecimal(string(d))
+	return err == nil && !negative && strings.Trim(units+fraction, "0") != ""
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns the sum of both amounts, which must be of the same currency. It fails when
+// the sum does not fit in the minor units
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, custom_errors.ErrCurrencyMismatch
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s overflows", custom_errors.ErrInvalidAmount, m, other)
+	}
+	return NewMoney(sum, m.Currency), nil
+}
+
+// Sub returns the difference of both amounts, which must be of the same currency. It fails
+// when the difference does not fit in the minor units
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, custom_errors.ErrCurrencyMismatch
+	}
+	diff := m.Amount - other.Amount
+	if (other.Amount > 0 && diff > m.Amount) || (other.Amount < 0 && diff < m.Amount) {
+		return Money{}, fmt.Errorf("%w: %s - %s overflows", custom_errors.ErrInvalidAmount, m, other)
+	}
+	return NewMoney(diff, m.Currency), nil
+}
+
+// Rescale expresses the same decimal amount in the minor units of another currency. It
+// fails if the amount has more decimal places than the currency allows
+func (m Money) Rescale(currency string) (Money, error) {
+	return ParseMoney(m.String(), currency)
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.Amount < other.Amount
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// String formats the amount as a decimal number with the currency decimal places, e.g. "100.25"
+func (m Money) String() string {
+	exp, ok := CurrencyExponent(m.Currency)
+	if !ok {
+		exp, _ = CurrencyExponent(DefaultCurrency)
+	}
+
+	sign := ""
+	amount := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		amount = uint64(-m.Amount)
+		if m.Amount == math.MinInt64 {
+			amount = uint64(math.MaxInt64) + 1
+		}
+	}
+
+	digits := strconv.FormatUint(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// MarshalJSON encodes the money as a plain JSON number, keeping every decimal place
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or string. The amount is parsed in the money
+// currency, or in the default currency when it has none
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := string(bytes.Trim(data, `"`))
+	if strings.ContainsAny(value, "eE") {
+		return fmt.Errorf("%w: %q", custom_errors.ErrInvalidAmount, value)
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	money, err := ParseMoney(value, currency)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
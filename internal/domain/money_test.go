@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"encoding/json"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	t.Run("parse money success", func(t *testing.T) {
+		m, err := ParseMoney("100.25", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(10025, "USD"), m)
+	})
+	t.Run("parse money without decimals", func(t *testing.T) {
+		m, err := ParseMoney("100", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(10000, "USD"), m)
+	})
+	t.Run("parse money negative", func(t *testing.T) {
+		m, err := ParseMoney("-0.5", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(-50, "USD"), m)
+	})
+	t.Run("parse money trailing zeros", func(t *testing.T) {
+		m, err := ParseMoney("1.2500", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(125, "USD"), m)
+	})
+	t.Run("parse money zero decimal currency", func(t *testing.T) {
+		m, err := ParseMoney("1500", "CLP")
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(1500, "CLP"), m)
+	})
+	t.Run("parse money too many decimals", func(t *testing.T) {
+		_, err := ParseMoney("10.001", "USD")
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmountPrecision)
+
+		_, err = ParseMoney("10.5", "JPY")
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmountPrecision)
+	})
+	t.Run("parse money invalid amount", func(t *testing.T) {
+		for _, value := range []string{"", ".5", "1.2.3", "abc", "1e3", "99999999999999999999"} {
+			_, err := ParseMoney(value, "USD")
+			assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount, value)
+		}
+	})
+	t.Run("parse money invalid currency", func(t *testing.T) {
+		_, err := ParseMoney("10", "XXX")
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidCurrency)
+	})
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "100.25", NewMoney(10025, "USD").String())
+	assert.Equal(t, "0.05", NewMoney(5, "USD").String())
+	assert.Equal(t, "-0.05", NewMoney(-5, "USD").String())
+	assert.Equal(t, "0.00", NewMoney(0, "USD").String())
+	assert.Equal(t, "1500", NewMoney(1500, "CLP").String())
+	assert.Equal(t, "1.005", NewMoney(1005, "BHD").String())
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is not 0.3 in float64, it must be with money
+	sum := NewMoney(0, "USD")
+	for i := 0; i < 1000; i++ {
+		for _, cents := range []int64{10, 20} {
+			var err error
+			sum, err = sum.Add(NewMoney(cents, "USD"))
+			assert.NoError(t, err)
+		}
+	}
+	assert.Equal(t, NewMoney(30000, "USD"), sum)
+	diff, err := sum.Sub(NewMoney(10, "USD"))
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(29990, "USD"), diff)
+	assert.True(t, NewMoney(1, "USD").LessThan(NewMoney(2, "USD")))
+	assert.True(t, NewMoney(0, "USD").IsZero())
+	assert.False(t, NewMoney(0, "USD").IsPositive())
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	_, err := NewMoney(100, "USD").Add(NewMoney(100, "EUR"))
+	assert.ErrorIs(t, err, custom_errors.ErrCurrencyMismatch)
+
+	_, err = NewMoney(100, "USD").Sub(NewMoney(100, "EUR"))
+	assert.ErrorIs(t, err, custom_errors.ErrCurrencyMismatch)
+}
+
+func TestMoneyOverflow(t *testing.T) {
+	t.Run("add overflow", func(t *testing.T) {
+		_, err := NewMoney(math.MaxInt64, "USD").Add(NewMoney(1, "USD"))
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount)
+
+		_, err = NewMoney(math.MinInt64, "USD").Add(NewMoney(-1, "USD"))
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount)
+	})
+	t.Run("sub underflow", func(t *testing.T) {
+		_, err := NewMoney(math.MinInt64, "USD").Sub(NewMoney(1, "USD"))
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount)
+
+		_, err = NewMoney(0, "USD").Sub(NewMoney(math.MinInt64, "USD"))
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount)
+	})
+	t.Run("operations at the limits", func(t *testing.T) {
+		sum, err := NewMoney(math.MaxInt64-1, "USD").Add(NewMoney(1, "USD"))
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(math.MaxInt64, "USD"), sum)
+
+		diff, err := NewMoney(math.MinInt64+1, "USD").Sub(NewMoney(1, "USD"))
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(math.MinInt64, "USD"), diff)
+	})
+}
+
+func TestParseDecimal(t *testing.T) {
+	for _, value := range []string{"10", "10.125", "0.0001", "-1.5"} {
+		d, err := ParseDecimal(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, Decimal(value), d)
+	}
+	for _, value := range []string{"", "abc", "1e3", "1.2.3", ".5", "1,5"} {
+		_, err := ParseDecimal(value)
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount, value)
+	}
+
+	assert.True(t, Decimal("0.001").IsPositive())
+	assert.False(t, Decimal("0.000").IsPositive())
+	assert.False(t, Decimal("-1").IsPositive())
+
+	m, err := Decimal("1.234").Money("KWD")
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(1234, "KWD"), m)
+	_, err = Decimal("1.234").Money("USD")
+	assert.ErrorIs(t, err, custom_errors.ErrInvalidAmountPrecision)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Run("money json round trip", func(t *testing.T) {
+		var tr Transaction
+		err := json.Unmarshal([]byte(`{"amount": 10000.33}`), &tr)
+		assert.NoError(t, err)
+		assert.NoError(t, tr.ResolveAmount(Account{Currency: DefaultCurrency}))
+		assert.Equal(t, NewMoney(1000033, DefaultCurrency), tr.Amount)
+
+		data, err := json.Marshal(tr.Amount)
+		assert.NoError(t, err)
+		assert.Equal(t, "10000.33", string(data))
+	})
+	t.Run("money json string", func(t *testing.T) {
+		var m Money
+		err := json.Unmarshal([]byte(`"12.5"`), &m)
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(1250, DefaultCurrency), m)
+	})
+	t.Run("money json too many decimals", func(t *testing.T) {
+		var tr Transaction
+		err := json.Unmarshal([]byte(`{"amount": 10.001, "currency": "USD"}`), &tr)
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmountPrecision)
+	})
+	t.Run("money json exponent", func(t *testing.T) {
+		var m Money
+		err := json.Unmarshal([]byte(`1e3`), &m)
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount)
+	})
+}
@@ -0,0 +1,76 @@
+package domain
+
+import "github.com/google/uuid"
+
+// Role is what a principal is allowed to do
+type Role string
+
+const (
+	// RoleAdmin can operate on every account and issue API keys
+	RoleAdmin Role = "admin"
+	// RoleAccountOwner can only operate on the accounts it owns
+	RoleAccountOwner Role = "account_owner"
+	// RoleAuditor can read every account but can not operate on them
+	RoleAuditor Role = "auditor"
+)
+
+// Valid reports whether the role is one of the supported ones
+func (r Role) Valid() bool {
+	return r == RoleAdmin || r == RoleAccountOwner || r == RoleAuditor
+}
+
+// Principal is the caller an API key authenticates
+type Principal struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Role Role      `json:"role" swaggertype:"string" enums:"admin,account_owner,auditor"`
+	// Accounts are the accounts owned by the principal
+	Accounts []uuid.UUID `json:"accounts,omitempty"`
+	// FromToken is set on the principals of bearer tokens. They are not stored, their
+	// accounts are the ones in the token claims
+	FromToken bool `json:"-"`
+}
+
+// Owns reports whether the account belongs to the principal
+func (p Principal) Owns(accountID uuid.UUID) bool {
+	for _, id := range p.Accounts {
+		if id == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRead reports whether the principal can see the balance and transactions of the account
+func (p Principal) CanRead(accountID uuid.UUID) bool {
+	return p.Role == RoleAdmin || p.Role == RoleAuditor || (p.Role == RoleAccountOwner && p.Owns(accountID))
+}
+
+// CanOperate reports whether the principal can move money from the account
+func (p Principal) CanOperate(accountID uuid.UUID) bool {
+	return p.Role == RoleAdmin || (p.Role == RoleAccountOwner && p.Owns(accountID))
+}
+
+// HasRole reports whether the principal has any of the roles
+func (p Principal) HasRole(roles ...Role) bool {
+	for _, r := range roles {
+		if p.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role Role   `json:"role" binding:"required" swaggertype:"string" enums:"admin,account_owner,auditor"`
+	// Accounts are the accounts owned by an account_owner
+	Accounts []uuid.UUID `json:"accounts"`
+}
+
+// APIKey is an issued credential. The key is only known when it is issued, as just
+// its hash is stored
+type APIKey struct {
+	Key       string    `json:"key"`
+	Principal Principal `json:"principal"`
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrincipalPermissions(t *testing.T) {
+	owned, other := uuid.New(), uuid.New()
+
+	admin := Principal{Role: RoleAdmin}
+	owner := Principal{Role: RoleAccountOwner, Accounts: []uuid.UUID{owned}}
+	auditor := Principal{Role: RoleAuditor}
+
+	assert.True(t, admin.CanRead(other))
+	assert.True(t, admin.CanOperate(other))
+
+	assert.True(t, owner.CanRead(owned))
+	assert.True(t, owner.CanOperate(owned))
+	assert.False(t, owner.CanRead(other))
+	assert.False(t, owner.CanOperate(other))
+
+	assert.True(t, auditor.CanRead(other))
+	assert.False(t, auditor.CanOperate(other))
+
+	assert.False(t, Principal{}.CanRead(owned))
+	assert.True(t, auditor.HasRole(RoleAdmin, RoleAuditor))
+	assert.False(t, owner.HasRole(RoleAdmin, RoleAuditor))
+}
+
+func TestRoleValid(t *testing.T) {
+	for _, r := range []Role{RoleAdmin, RoleAccountOwner, RoleAuditor} {
+		assert.True(t, r.Valid())
+	}
+	assert.False(t, Role("root").Valid())
+	assert.False(t, Role("").Valid())
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// rateDecimals is the number of decimal places kept in the exchange rates
+const rateDecimals = 12
+
+// Rate is an exchange rate: how many units of the quote currency one unit of the base
+// currency buys. The zero value is invalid
+type Rate struct {
+	rat *big.Rat
+}
+
+// FXRateProvider returns the exchange rate to convert from one currency to another
+type FXRateProvider interface {
+	Rate(from, to string) (Rate, error)
+}
+
+// OneRate converts a currency to itself
+func OneRate() Rate {
+	return Rate{rat: big.NewRat(1, 1)}
+}
+
+// ParseRate parses a positive decimal exchange rate such as "1.0856"
+func ParseRate(value string) (Rate, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, "eE/") {
+		return Rate{}, fmt.Errorf("%w: %q", custom_errors.ErrInvalidExchangeRate, value)
+	}
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok || rat.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("%w: %q", custom_errors.ErrInvalidExchangeRate, value)
+	}
+	if parts := strings.SplitN(value, ".", 2); len(parts) == 2 && len(parts[1]) > rateDecimals {
+		return Rate{}, fmt.Errorf("%w: %q has more than %d decimal places", custom_errors.ErrInvalidExchangeRate, value, rateDecimals)
+	}
+	return Rate{rat: rat}, nil
+}
+
+func (r Rate) IsZero() bool {
+	return r.rat == nil
+}
+
+// Inverse returns the rate of the opposite conversion, rounded to the rate decimal places
+func (r Rate) Inverse() Rate {
+	inv, _ := ParseRate(new(big.Rat).Inv(r.rat).FloatString(rateDecimals))
+	return inv
+}
+
+// Convert converts the money to the currency, rounding half away from zero to the minor
+// units of the currency
+func (r Rate) Convert(m Money, currency string) (Money, error) {
+	fromExp, ok := CurrencyExponent(m.Currency)
+	if !ok {
+		return Money{}, custom_errors.ErrInvalidCurrency
+	}
+	toExp, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, custom_errors.ErrInvalidCurrency
+	}
+
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), r.rat)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(toExp-fromExp))), nil)
+	if toExp > fromExp {
+		value.Mul(value, new(big.Rat).SetInt(scale))
+	} else {
+		value.Quo(value, new(big.Rat).SetInt(scale))
+	}
+
+	amount, err := roundHalfAwayFromZero(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return NewMoney(amount, currency), nil
+}
+
+// String formats the rate as a decimal number without trailing zeros
+func (r Rate) String() string {
+	if r.rat == nil {
+		return "0"
+	}
+	s := strings.TrimRight(r.rat.FloatString(rateDecimals), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// MarshalJSON encodes the rate as a plain JSON number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or string
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	rate, err := ParseRate(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+func roundHalfAwayFromZero(value *big.Rat) (int64, error) {
+	num, den := new(big.Int).Set(value.Num()), value.Denom()
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if negative {
+		quo.Neg(quo)
+	}
+	if !quo.IsInt64() {
+		return 0, custom_errors.ErrInvalidAmount
+	}
+	return quo.Int64(), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
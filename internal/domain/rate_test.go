@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"encoding/json"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	for _, value := range []string{"1", "0.92", "149.5", "0.000000000001"} {
+		rate, err := ParseRate(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, value, rate.String())
+	}
+	for _, value := range []string{"", "0", "-1", "abc", "1e3", "1/3", "0.0000000000001"} {
+		_, err := ParseRate(value)
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidExchangeRate, value)
+	}
+}
+
+func TestRateConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     string
+		amount   Money
+		currency string
+		expected Money
+	}{
+		{"same exponent", "0.92", NewMoney(1000, "USD"), "EUR", NewMoney(920, "EUR")},
+		{"rounds half up", "0.925", NewMoney(1, "USD"), "EUR", NewMoney(1, "EUR")},
+		{"rounds down", "0.4", NewMoney(1, "USD"), "EUR", NewMoney(0, "EUR")},
+		{"to fewer decimals", "149.5", NewMoney(1234, "USD"), "JPY", NewMoney(1845, "JPY")},
+		{"to more decimals", "0.3077", NewMoney(1000, "USD"), "KWD", NewMoney(3077, "KWD")},
+		{"from no decimals", "0.0067", NewMoney(1000, "JPY"), "USD", NewMoney(670, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := ParseRate(tt.rate)
+			assert.NoError(t, err)
+
+			converted, err := rate.Convert(tt.amount, tt.currency)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, converted)
+		})
+	}
+	t.Run("unknown currency", func(t *testing.T) {
+		_, err := OneRate().Convert(NewMoney(100, "USD"), "XXX")
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidCurrency)
+	})
+}
+
+func TestRateInverse(t *testing.T) {
+	rate, _ := ParseRate("0.8")
+	assert.Equal(t, "1.25", rate.Inverse().String())
+
+	rate, _ = ParseRate("3")
+	assert.Equal(t, "0.333333333333", rate.Inverse().String())
+}
+
+func TestRateJSON(t *testing.T) {
+	var rate Rate
+	assert.NoError(t, json.Unmarshal([]byte(`"1.0856"`), &rate))
+	data, err := json.Marshal(rate)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0856", string(data))
+}
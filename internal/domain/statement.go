@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statement lists the transactions of an account in a period, with the balance before,
+// after and in between them
+type Statement struct {
+	AccountID      uuid.UUID        `json:"account_id"`
+	AccountName    string           `json:"account_name"`
+	Currency       string           `json:"currency"`
+	From           time.Time        `json:"from"`
+	To             time.Time        `json:"to"`
+	OpeningBalance Money            `json:"opening_balance" swaggertype:"number"`
+	ClosingBalance Money            `json:"closing_balance" swaggertype:"number"`
+	Entries        []StatementEntry `json:"entries"`
+}
+
+// StatementEntry is a transaction seen from the account of the statement. Amount is
+// negative for debits and Balance is the running balance after the transaction
+type StatementEntry struct {
+	TransactionID  uuid.UUID  `json:"transaction_id"`
+	Type           EventType  `json:"type"`
+	Timestamp      time.Time  `json:"timestamp"`
+	CounterpartyID *uuid.UUID `json:"counterparty_id,omitempty"`
+	Amount         Money      `json:"amount" swaggertype:"number"`
+	Balance        Money      `json:"balance" swaggertype:"number"`
+}
+
+// IsCredit reports whether the transaction added money to the account
+func (t Transaction) IsCredit(accountID uuid.UUID) bool {
+	return t.Type == Deposit || (t.Type == Transfer && t.DestinationID != nil && *t.DestinationID == accountID)
+}
+
+// Counterparty returns the other account of a transfer
+func (t Transaction) Counterparty(accountID uuid.UUID) *uuid.UUID {
+	if t.Type != Transfer {
+		return nil
+	}
+	if t.AccountID == accountID {
+		return t.DestinationID
+	}
+	id := t.AccountID
+	return &id
+}
+
+// NewStatement builds the statement of the transactions, sorted oldest first, starting
+// from the opening balance. Every amount has to be in the currency of the opening balance
+func NewStatement(acc Account, from, to time.Time, opening Money, transactions []Transaction) (Statement, error) {
+	st := Statement{
+		AccountID:      acc.ID,
+		AccountName:    acc.Name,
+		Currency:       opening.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		Entries:        make([]StatementEntry, 0, len(transactions)),
+	}
+	for _, tr := range transactions {
+		amount := tr.CreditedAmount(acc.ID)
+		if !tr.IsCredit(acc.ID) {
+			amount = NewMoney(-tr.Amount.Amount, tr.Amount.Currency)
+		}
+		balance, err := st.ClosingBalance.Add(amount)
+		if err != nil {
+			return Statement{}, err
+		}
+		st.ClosingBalance = balance
+		st.Entries = append(st.Entries, StatementEntry{
+			TransactionID:  tr.ID,
+			Type:           tr.Type,
+			Timestamp:      tr.Timestamp,
+			CounterpartyID: tr.Counterparty(acc.ID),
+			Amount:         amount,
+			Balance:        st.ClosingBalance,
+		})
+	}
+	return st, nil
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewStatement(t *testing.T) {
+	acc := Account{ID: uuid.New(), Name: "test"}
+	other := uuid.New()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	st, err := NewStatement(acc, from, to, NewMoney(1000, "USD"), []Transaction{
+		{ID: uuid.New(), AccountID: acc.ID, Type: Deposit, Amount: NewMoney(500, "USD"), Timestamp: from},
+		{ID: uuid.New(), AccountID: acc.ID, Type: WithDraw, Amount: NewMoney(200, "USD"), Timestamp: from.Add(time.Hour)},
+		{ID: uuid.New(), AccountID: acc.ID, DestinationID: &other, Type: Transfer, Amount: NewMoney(300, "USD"), Timestamp: from.Add(2 * time.Hour)},
+		{ID: uuid.New(), AccountID: other, DestinationID: &acc.ID, Type: Transfer, Amount: NewMoney(50, "USD"), Timestamp: from.Add(3 * time.Hour)},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", st.Currency)
+	assert.Equal(t, NewMoney(1000, "USD"), st.OpeningBalance)
+	assert.Equal(t, NewMoney(1050, "USD"), st.ClosingBalance)
+	assert.Len(t, st.Entries, 4)
+
+	assert.Equal(t, NewMoney(500, "USD"), st.Entries[0].Amount)
+	assert.Equal(t, NewMoney(1500, "USD"), st.Entries[0].Balance)
+	assert.Nil(t, st.Entries[0].CounterpartyID)
+	assert.Equal(t, NewMoney(-200, "USD"), st.Entries[1].Amount)
+	assert.Equal(t, NewMoney(-300, "USD"), st.Entries[2].Amount)
+	assert.Equal(t, other, *st.Entries[2].CounterpartyID)
+	assert.Equal(t, NewMoney(50, "USD"), st.Entries[3].Amount)
+	assert.Equal(t, other, *st.Entries[3].CounterpartyID)
+	assert.Equal(t, st.ClosingBalance, st.Entries[3].Balance)
+}
+
+func TestEmptyStatement(t *testing.T) {
+	st, err := NewStatement(Account{ID: uuid.New()}, time.Time{}, time.Now(), NewMoney(1000, "USD"), nil)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, st.Entries)
+	assert.Equal(t, st.OpeningBalance, st.ClosingBalance)
+}
+
+func TestStatementTransferBetweenCurrencies(t *testing.T) {
+	acc := Account{ID: uuid.New(), Currency: "EUR"}
+	other := uuid.New()
+	converted := NewMoney(920, "EUR")
+
+	st, err := NewStatement(acc, time.Time{}, time.Now(), NewMoney(0, "EUR"), []Transaction{
+		{ID: uuid.New(), AccountID: other, DestinationID: &acc.ID, Type: Transfer, Amount: NewMoney(1000, "USD"), DestinationAmount: &converted},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, converted, st.Entries[0].Amount)
+	assert.Equal(t, converted, st.ClosingBalance)
+}
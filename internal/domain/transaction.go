@@ -1,7 +1,13 @@
 package domain
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 )
 
 type Transaction struct {
@@ -9,6 +15,112 @@ type Transaction struct {
 	AccountID     uuid.UUID  `json:"account_id" binding:"required"`
 	DestinationID *uuid.UUID `json:"destination_id,omitempty"`
 	Type          EventType  `json:"type" binding:"required"`
-	Amount        float64    `json:"amount" binding:"required"`
-	Timestamp     string     `json:"timestamp"`
+	Amount        Money      `json:"amount" binding:"required" swaggertype:"number"`
+	// Currency of the amount, the currency of the account when it is not sent
+	Currency string `json:"currency,omitempty"`
+	// Rate, DestinationAmount and DestinationCurrency are set on transfers, with the
+	// amount credited to the destination account after the currency conversion
+	Rate                *Rate     `json:"rate,omitempty" swaggertype:"number"`
+	DestinationAmount   *Money    `json:"destination_amount,omitempty" swaggertype:"number"`
+	DestinationCurrency string    `json:"destination_currency,omitempty"`
+	Timestamp           time.Time `json:"timestamp"`
+
+	// amount is the decimal sent without currency, parsed by ResolveAmount once the
+	// account currency is known
+	amount Decimal
+}
+
+// UnmarshalJSON parses the amount in the currency sent with it, so it is validated against
+// the right number of decimal places. An amount sent without currency is kept as a decimal
+// until ResolveAmount parses it in the account currency
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	type transaction Transaction
+	var tr struct {
+		transaction
+		Amount json.RawMessage `json:"amount"`
+	}
+	if err := json.Unmarshal(data, &tr); err != nil {
+		return err
+	}
+	tr.Currency = strings.ToUpper(tr.Currency)
+
+	if len(tr.Amount) > 0 && string(tr.Amount) != "null" {
+		if tr.Currency != "" {
+			tr.transaction.Amount.Currency = tr.Currency
+			if err := tr.transaction.Amount.UnmarshalJSON(tr.Amount); err != nil {
+				return err
+			}
+		} else {
+			amount, err := ParseDecimal(string(bytes.Trim(tr.Amount, `"`)))
+			if err != nil {
+				return err
+			}
+			tr.transaction.amount = amount
+		}
+	}
+	*t = Transaction(tr.transaction)
+	return nil
+}
+
+// HasPositiveAmount tells whether the amount is greater than zero, even before it is resolved
+func (t Transaction) HasPositiveAmount() bool {
+	if t.amount != "" {
+		return t.amount.IsPositive()
+	}
+	return t.Amount.IsPositive()
+}
+
+// ResolveAmount sets the amount in the currency of the account it is taken from or added
+// to. An amount sent without currency is taken in the account currency
+func (t *Transaction) ResolveAmount(acc Account) error {
+	if t.Currency == "" {
+		var amount Money
+		var err error
+		if t.amount != "" {
+			amount, err = t.amount.Money(acc.Currency)
+		} else {
+			amount, err = t.Amount.Rescale(acc.Currency)
+		}
+		if err != nil {
+			return err
+		}
+		t.Amount, t.Currency, t.amount = amount, acc.Currency, ""
+	}
+	if t.Currency != acc.Currency {
+		return custom_errors.ErrCurrencyMismatch
+	}
+	return nil
+}
+
+// CreditedAmount is the amount added to the account by the transaction, which for
+// transfers received is the converted amount
+func (t Transaction) CreditedAmount(accountID uuid.UUID) Money {
+	if t.Type == Transfer && t.DestinationID != nil && *t.DestinationID == accountID && t.DestinationAmount != nil {
+		return *t.DestinationAmount
+	}
+	return t.Amount
+}
+
+// TransactionFilter selects the transactions of an account, both the ones it sent and
+// the transfers it received. Nil or empty fields do not filter
+type TransactionFilter struct {
+	AccountID uuid.UUID
+	Types     []EventType
+	// MinValue and MaxValue are the amounts asked for, in the account currency. The
+	// service parses them into MinAmount and MaxAmount once the account is read
+	MinValue  Decimal
+	MaxValue  Decimal
+	MinAmount *Money
+	MaxAmount *Money
+	From      *time.Time
+	To        *time.Time
+	// Cursor is the opaque position returned as NextCursor by the previous page
+	Cursor string
+	Limit  int
+}
+
+// TransactionPage is a page of transactions, newest first
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTransactionUnmarshalJSON(t *testing.T) {
+	t.Run("amount in the currency sent", func(t *testing.T) {
+		var tr Transaction
+		err := json.Unmarshal([]byte(`{"type":"deposit","amount":10.125,"currency":"kwd"}`), &tr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "KWD", tr.Currency)
+		assert.Equal(t, NewMoney(10125, "KWD"), tr.Amount)
+	})
+	t.Run("amount without currency", func(t *testing.T) {
+		var tr Transaction
+		err := json.Unmarshal([]byte(`{"type":"deposit","amount":10.125}`), &tr)
+
+		assert.NoError(t, err)
+		assert.Empty(t, tr.Currency)
+		assert.True(t, tr.HasPositiveAmount())
+		assert.NoError(t, tr.ResolveAmount(Account{Currency: "KWD"}))
+		assert.Equal(t, NewMoney(10125, "KWD"), tr.Amount)
+	})
+	t.Run("invalid amount without currency", func(t *testing.T) {
+		var tr Transaction
+		err := json.Unmarshal([]byte(`{"type":"deposit","amount":"ten"}`), &tr)
+
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmount)
+	})
+	t.Run("amount precision of the currency sent", func(t *testing.T) {
+		var tr Transaction
+		err := json.Unmarshal([]byte(`{"type":"deposit","amount":10.5,"currency":"JPY"}`), &tr)
+
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmountPrecision)
+	})
+}
+
+func TestResolveAmount(t *testing.T) {
+	acc := Account{ID: uuid.New(), Currency: "JPY"}
+
+	t.Run("amount without currency takes the account currency", func(t *testing.T) {
+		tr := Transaction{Amount: NewMoney(150000, DefaultCurrency)}
+
+		assert.NoError(t, tr.ResolveAmount(acc))
+		assert.Equal(t, "JPY", tr.Currency)
+		assert.Equal(t, NewMoney(1500, "JPY"), tr.Amount)
+	})
+	t.Run("decimal amount in the account currency", func(t *testing.T) {
+		var tr Transaction
+		assert.NoError(t, json.Unmarshal([]byte(`{"type":"deposit","amount":"1.234"}`), &tr))
+
+		assert.NoError(t, tr.ResolveAmount(Account{ID: uuid.New(), Currency: "BHD"}))
+		assert.Equal(t, "BHD", tr.Currency)
+		assert.Equal(t, NewMoney(1234, "BHD"), tr.Amount)
+	})
+	t.Run("decimal amount too precise for the account", func(t *testing.T) {
+		var tr Transaction
+		assert.NoError(t, json.Unmarshal([]byte(`{"type":"deposit","amount":1.5}`), &tr))
+
+		assert.ErrorIs(t, tr.ResolveAmount(acc), custom_errors.ErrInvalidAmountPrecision)
+	})
+	t.Run("amount without currency too precise for the account", func(t *testing.T) {
+		tr := Transaction{Amount: NewMoney(150050, DefaultCurrency)}
+
+		assert.ErrorIs(t, tr.ResolveAmount(acc), custom_errors.ErrInvalidAmountPrecision)
+	})
+	t.Run("amount in the account currency", func(t *testing.T) {
+		tr := Transaction{Amount: NewMoney(1500, "JPY"), Currency: "JPY"}
+
+		assert.NoError(t, tr.ResolveAmount(acc))
+		assert.Equal(t, NewMoney(1500, "JPY"), tr.Amount)
+	})
+	t.Run("amount in another currency", func(t *testing.T) {
+		tr := Transaction{Amount: NewMoney(1500, "EUR"), Currency: "EUR"}
+
+		assert.ErrorIs(t, tr.ResolveAmount(acc), custom_errors.ErrCurrencyMismatch)
+	})
+}
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/account"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
@@ -21,8 +22,8 @@ func NewBalanceEvent(id uuid.UUID, service account.Service) domain.Event {
 	return &event
 }
 
-func (t *balanceEvent) Process() (domain.Account, error) {
-	acc, err := t.service.Read(t.AccId)
+func (t *balanceEvent) Process(ctx context.Context) (domain.Account, error) {
+	acc, err := t.service.Read(ctx, t.AccId)
 	if err != nil {
 		return domain.Account{}, err
 	}
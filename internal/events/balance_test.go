@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
@@ -9,21 +10,41 @@ import (
 )
 
 type accServiceMock struct {
-	create func(account domain.Account) error
-	read   func(id uuid.UUID) (domain.Account, error)
-	update func(account domain.Account) error
+	create        func(account domain.Account) error
+	read          func(id uuid.UUID) (domain.Account, error)
+	readForUpdate func(id uuid.UUID) (domain.Account, error)
+	record        func(account domain.Account, event domain.AccountEvent) (domain.Account, error)
+	rebuild       func(id uuid.UUID) (domain.Account, error)
+	snapshot      func(id uuid.UUID) (domain.Account, error)
+	list          func(filter domain.AccountFilter) (domain.AccountPage, error)
 }
 
-func (a accServiceMock) Create(account domain.Account) error {
+func (a accServiceMock) Create(ctx context.Context, account domain.Account) error {
 	return a.create(account)
 }
 
-func (a accServiceMock) Read(id uuid.UUID) (domain.Account, error) {
+func (a accServiceMock) Read(ctx context.Context, id uuid.UUID) (domain.Account, error) {
 	return a.read(id)
 }
 
-func (a accServiceMock) Update(account domain.Account) error {
-	return a.update(account)
+func (a accServiceMock) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.readForUpdate(id)
+}
+
+func (a accServiceMock) Record(ctx context.Context, account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+	return a.record(account, event)
+}
+
+func (a accServiceMock) Rebuild(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.rebuild(id)
+}
+
+func (a accServiceMock) Snapshot(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.snapshot(id)
+}
+
+func (a accServiceMock) List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	return a.list(filter)
 }
 
 func TestBalanceProcess(t *testing.T) {
@@ -32,18 +53,18 @@ func TestBalanceProcess(t *testing.T) {
 			read: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
 					ID:      id,
-					Balance: 1000.00,
+					Balance: domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
 		}
 
 		balance := NewBalanceEvent(uuid.New(), serviceMock)
 
-		acc, err := balance.Process()
+		acc, err := balance.Process(context.Background())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, acc)
-		assert.Equal(t, 1000.00, acc.Balance)
+		assert.Equal(t, domain.NewMoney(100000, domain.DefaultCurrency), acc.Balance)
 	})
 	t.Run("balance process not found", func(t *testing.T) {
 		serviceMock := accServiceMock{
@@ -54,7 +75,7 @@ func TestBalanceProcess(t *testing.T) {
 
 		balance := NewBalanceEvent(uuid.New(), serviceMock)
 
-		acc, err := balance.Process()
+		acc, err := balance.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Equal(t, err, custom_errors.ErrNotFound)
@@ -69,7 +90,7 @@ func TestBalanceProcess(t *testing.T) {
 
 		balance := NewBalanceEvent(uuid.New(), serviceMock)
 
-		acc, err := balance.Process()
+		acc, err := balance.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.NotNil(t, acc)
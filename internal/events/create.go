@@ -1,31 +1,47 @@
 package events
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/account"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 )
 
 type createEvent struct {
 	domain.DefaultEvent
-	AccName string
-	service account.Service
+	AccName  string
+	Currency string
+	service  account.Service
 }
 
-func NewCreateAccountEvent(name string, service account.Service) domain.Event {
+// NewCreateAccountEvent opens an account in the currency, or in the default currency when
+// it is empty
+func NewCreateAccountEvent(name string, currency string, service account.Service) domain.Event {
 	var event createEvent
 	event.AccId = uuid.New()
 	event.Type = domain.Create
 	event.AccName = name
+	event.Currency = currency
 	event.service = service
 	return &event
 }
 
-func (t *createEvent) Process() (domain.Account, error) {
+func (t *createEvent) Process(ctx context.Context) (domain.Account, error) {
+	currency := t.Currency
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+	if _, ok := domain.CurrencyExponent(currency); !ok {
+		return domain.Account{}, custom_errors.ErrInvalidCurrency
+	}
+
 	acc := domain.Account{
-		ID:      t.AccId,
-		Name:    t.AccName,
-		Balance: 0,
+		ID:       t.AccId,
+		Name:     t.AccName,
+		Currency: currency,
+		Balance:  domain.NewMoney(0, currency),
+		Status:   domain.AccountActive,
 	}
-	return acc, t.service.Create(acc)
+	return acc, t.service.Create(ctx, acc)
 }
@@ -1,8 +1,10 @@
 package events
 
 import (
+	"context"
 	"errors"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -15,14 +17,35 @@ func TestCreateProcess(t *testing.T) {
 			},
 		}
 
-		create := NewCreateAccountEvent("test", serviceMock)
+		create := NewCreateAccountEvent("test", "", serviceMock)
 
-		acc, err := create.Process()
+		acc, err := create.Process(context.Background())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, acc)
 		assert.Equal(t, "test", acc.Name)
-		assert.Equal(t, 0.00, acc.Balance)
+		assert.Equal(t, domain.NewMoney(0, domain.DefaultCurrency), acc.Balance)
+		assert.Equal(t, domain.DefaultCurrency, acc.Currency)
+		assert.Equal(t, domain.AccountActive, acc.Status)
+	})
+	t.Run("create process with currency", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			create: func(account domain.Account) error {
+				return nil
+			},
+		}
+
+		acc, err := NewCreateAccountEvent("test", "JPY", serviceMock).Process(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "JPY", acc.Currency)
+		assert.Equal(t, domain.NewMoney(0, "JPY"), acc.Balance)
+	})
+	t.Run("create process invalid currency", func(t *testing.T) {
+		acc, err := NewCreateAccountEvent("test", "XXX", accServiceMock{}).Process(context.Background())
+
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidCurrency)
+		assert.Equal(t, domain.Account{}, acc)
 	})
 	t.Run("balance process error", func(t *testing.T) {
 		serviceMock := accServiceMock{
@@ -31,9 +54,9 @@ func TestCreateProcess(t *testing.T) {
 			},
 		}
 
-		create := NewCreateAccountEvent("test", serviceMock)
+		create := NewCreateAccountEvent("test", "", serviceMock)
 
-		_, err := create.Process()
+		_, err := create.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
@@ -1,7 +1,7 @@
 package events
 
 import (
-	"github.com/google/uuid"
+	"context"
 	"github.com/lucaspichi06/xepelin-bank/internal/account"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
@@ -10,21 +10,21 @@ import (
 
 type depositEvent struct {
 	domain.DefaultEvent
-	Amount  float64
+	tr      *domain.Transaction
 	service account.Service
 }
 
-func NewDepositEvent(id uuid.UUID, amt float64, service account.Service) domain.Event {
+func NewDepositEvent(tr *domain.Transaction, service account.Service) domain.Event {
 	var event depositEvent
-	event.AccId = id
+	event.AccId = tr.AccountID
 	event.Type = domain.Deposit
-	event.Amount = amt
+	event.tr = tr
 	event.service = service
 	return &event
 }
 
-func (t *depositEvent) Process() (domain.Account, error) {
-	acc, err := t.service.Read(t.AccId)
+func (t *depositEvent) Process(ctx context.Context) (domain.Account, error) {
+	acc, err := t.service.ReadForUpdate(ctx, t.AccId)
 	if err != nil {
 		return domain.Account{}, err
 	}
@@ -33,6 +33,16 @@ func (t *depositEvent) Process() (domain.Account, error) {
 		return domain.Account{}, custom_errors.ErrNotFound
 	}
 
-	acc.Balance = acc.Balance + t.Amount
-	return acc, t.service.Update(acc)
+	if err = acc.CheckActive(); err != nil {
+		return domain.Account{}, err
+	}
+
+	if err = t.tr.ResolveAmount(acc); err != nil {
+		return domain.Account{}, err
+	}
+
+	return t.service.Record(ctx, acc, domain.AccountEvent{
+		Type:   domain.Deposit,
+		Amount: t.tr.Amount,
+	})
 }
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
@@ -12,35 +13,37 @@ import (
 func TestDepositProcess(t *testing.T) {
 	t.Run("deposit process success", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
-		deposit := NewDepositEvent(uuid.New(), 100.00, serviceMock)
+		deposit := NewDepositEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		acc, err := deposit.Process()
+		acc, err := deposit.Process(context.Background())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, acc)
-		assert.Equal(t, 1100.00, acc.Balance)
+		assert.Equal(t, domain.NewMoney(110000, domain.DefaultCurrency), acc.Balance)
 	})
 	t.Run("deposit process not found", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{}, custom_errors.ErrNotFound
 			},
 		}
 
-		deposit := NewDepositEvent(uuid.New(), 100.00, serviceMock)
+		deposit := NewDepositEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		acc, err := deposit.Process()
+		acc, err := deposit.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Equal(t, err, custom_errors.ErrNotFound)
@@ -48,36 +51,55 @@ func TestDepositProcess(t *testing.T) {
 	})
 	t.Run("deposit process empty account", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{}, nil
 			},
 		}
 
-		deposit := NewDepositEvent(uuid.New(), 100.00, serviceMock)
+		deposit := NewDepositEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		acc, err := deposit.Process()
+		acc, err := deposit.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.NotNil(t, acc)
 		assert.Equal(t, domain.Account{}, acc)
 		assert.Equal(t, err, custom_errors.ErrNotFound)
 	})
-	t.Run("deposit process update error", func(t *testing.T) {
+	t.Run("deposit process closed account", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(0, domain.DefaultCurrency),
+					Status:   domain.AccountClosed,
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return errors.New("test error")
+		}
+
+		deposit := NewDepositEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
+
+		_, err := deposit.Process(context.Background())
+
+		assert.Equal(t, custom_errors.ErrAccountClosed, err)
+	})
+	t.Run("deposit process record error", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
+				}, nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				return domain.Account{}, errors.New("test error")
 			},
 		}
 
-		deposit := NewDepositEvent(uuid.New(), 100.00, serviceMock)
+		deposit := NewDepositEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		_, err := deposit.Process()
+		_, err := deposit.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
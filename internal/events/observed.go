@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracerName is the instrumentation scope of the spans of the events
+const tracerName = "github.com/lucaspichi06/xepelin-bank/internal/events"
+
+// loggable events describe themselves with the args of their log line and span
+type loggable interface {
+	logArgs() []interface{}
+}
+
+type observedEvent struct {
+	event domain.Event
+}
+
+// Observed traces the event as a span of the context it is processed with, and logs its
+// outcome with the logger of that context
+func Observed(event domain.Event) domain.Event {
+	return &observedEvent{
+		event: event,
+	}
+}
+
+func (e *observedEvent) Process(ctx context.Context) (domain.Account, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "event.process")
+	defer span.End()
+
+	acc, err := e.event.Process(ctx)
+
+	var args []interface{}
+	if l, ok := e.event.(loggable); ok {
+		args = l.logArgs()
+	}
+	span.SetAttributes(spanAttributes(args)...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	log := logger.FromContext(ctx)
+	if err != nil {
+		class := custom_errors.Class(err)
+		args = append(args, "error", err, "error_class", class)
+		// the known errors are the ones the caller can fix, so they are just warnings
+		if class != "internal" {
+			log.Warn("event rejected", append(args, "outcome", "rejected")...)
+		} else {
+			log.Error("event failed", append(args, "outcome", "failed")...)
+		}
+		return acc, err
+	}
+	log.Info("event processed", append(args, "outcome", "processed", "balance", acc.Balance, "currency", acc.Currency)...)
+	return acc, nil
+}
+
+// spanAttributes turns the key value pairs of a log line into span attributes
+func spanAttributes(args []interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs = append(attrs, attribute.String(fmt.Sprint(args[i]), fmt.Sprint(args[i+1])))
+	}
+	return attrs
+}
+
+// transactionArgs describe the transaction an event processes
+func transactionArgs(event domain.DefaultEvent, tr *domain.Transaction) []interface{} {
+	return []interface{}{"event", event.Type, "account_id", event.AccId, "transaction_id", tr.ID, "amount", tr.Amount}
+}
+
+func (t *createEvent) logArgs() []interface{} {
+	return []interface{}{"event", t.Type, "account_id", t.AccId}
+}
+
+func (t *balanceEvent) logArgs() []interface{} {
+	return []interface{}{"event", t.Type, "account_id", t.AccId}
+}
+
+func (t *statusEvent) logArgs() []interface{} {
+	return []interface{}{"event", t.Type, "account_id", t.AccId, "status", t.Status}
+}
+
+func (t *depositEvent) logArgs() []interface{} {
+	return transactionArgs(t.DefaultEvent, t.tr)
+}
+
+func (t *withdrawEvent) logArgs() []interface{} {
+	return transactionArgs(t.DefaultEvent, t.tr)
+}
+
+func (t *transferEvent) logArgs() []interface{} {
+	args := append(transactionArgs(t.DefaultEvent, t.tr), "destination_id", t.TargetId)
+	if t.tr.DestinationAmount != nil {
+		args = append(args, "destination_amount", *t.tr.DestinationAmount, "destination_currency", t.tr.DestinationAmount.Currency)
+	}
+	return args
+}
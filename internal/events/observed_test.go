@@ -0,0 +1,95 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestObserved(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	tr := &domain.Transaction{ID: uuid.New(), AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}
+	process := func(serviceMock accServiceMock) (map[string]interface{}, error) {
+		exporter.Reset()
+		var out bytes.Buffer
+		ctx := logger.NewContext(context.Background(), logger.New(&out, logger.LevelDebug))
+
+		_, err := Observed(NewDepositEvent(tr, serviceMock)).Process(ctx)
+
+		line := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &line))
+		return line, err
+	}
+
+	t.Run("log processed event", func(t *testing.T) {
+		line, err := process(accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{ID: id, Currency: domain.DefaultCurrency, Balance: domain.NewMoney(100000, domain.DefaultCurrency)}, nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "info", line["level"])
+		assert.Equal(t, "event processed", line["msg"])
+		assert.Equal(t, "deposit", line["event"])
+		assert.Equal(t, tr.AccountID.String(), line["account_id"])
+		assert.Equal(t, tr.ID.String(), line["transaction_id"])
+		assert.Equal(t, 100.0, line["amount"])
+		assert.Equal(t, 1100.0, line["balance"])
+		assert.Equal(t, "processed", line["outcome"])
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "event.process", spans[0].Name)
+		assert.Contains(t, spans[0].Attributes, attribute.String("event", "deposit"))
+		assert.Contains(t, spans[0].Attributes, attribute.String("account_id", tr.AccountID.String()))
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	})
+	t.Run("log rejected event", func(t *testing.T) {
+		line, err := process(accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{}, custom_errors.ErrNotFound
+			},
+		})
+
+		assert.ErrorIs(t, err, custom_errors.ErrNotFound)
+		assert.Equal(t, "warn", line["level"])
+		assert.Equal(t, "rejected", line["outcome"])
+		assert.Equal(t, custom_errors.ErrNotFound.Error(), line["error"])
+	})
+	t.Run("log failed event", func(t *testing.T) {
+		line, err := process(accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{}, errors.New("test error")
+			},
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, "error", line["level"])
+		assert.Equal(t, "failed", line["outcome"])
+		status := exporter.GetSpans()[0].Status
+		assert.Equal(t, codes.Error, status.Code)
+		assert.Equal(t, "test error", status.Description)
+	})
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"reflect"
+)
+
+type statusEvent struct {
+	domain.DefaultEvent
+	Status  domain.AccountStatus
+	service account.Service
+}
+
+// NewStatusEvent freezes, closes or reopens the account. Accounts are only closed once
+// their balance is zero
+func NewStatusEvent(id uuid.UUID, status domain.AccountStatus, service account.Service) domain.Event {
+	var event statusEvent
+	event.AccId = id
+	event.Type = domain.StatusChange
+	event.Status = status
+	event.service = service
+	return &event
+}
+
+func (t *statusEvent) Process(ctx context.Context) (domain.Account, error) {
+	acc, err := t.service.ReadForUpdate(ctx, t.AccId)
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	if reflect.DeepEqual(acc, domain.Account{}) {
+		return domain.Account{}, custom_errors.ErrNotFound
+	}
+
+	if err = acc.CheckStatusChange(t.Status); err != nil {
+		return domain.Account{}, err
+	}
+
+	return t.service.Record(ctx, acc, domain.AccountEvent{
+		Type:   domain.StatusChange,
+		Amount: domain.NewMoney(0, acc.Currency),
+		Status: t.Status,
+	})
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStatusProcess(t *testing.T) {
+	account := func(balance int64, status domain.AccountStatus) accServiceMock {
+		return accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(balance, domain.DefaultCurrency),
+					Status:   status,
+				}, nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
+			},
+		}
+	}
+
+	t.Run("status process freeze", func(t *testing.T) {
+		acc, err := NewStatusEvent(uuid.New(), domain.AccountFrozen, account(100000, domain.AccountActive)).Process(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccountFrozen, acc.Status)
+		assert.Equal(t, domain.NewMoney(100000, domain.DefaultCurrency), acc.Balance)
+	})
+	t.Run("status process reopen", func(t *testing.T) {
+		acc, err := NewStatusEvent(uuid.New(), domain.AccountActive, account(0, domain.AccountClosed)).Process(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccountActive, acc.Status)
+	})
+	t.Run("status process close empty account", func(t *testing.T) {
+		acc, err := NewStatusEvent(uuid.New(), domain.AccountClosed, account(0, domain.AccountFrozen)).Process(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccountClosed, acc.Status)
+	})
+	t.Run("status process close account with money", func(t *testing.T) {
+		_, err := NewStatusEvent(uuid.New(), domain.AccountClosed, account(100, domain.AccountActive)).Process(context.Background())
+
+		assert.Equal(t, custom_errors.ErrAccountNotEmpty, err)
+	})
+	t.Run("status process invalid transition", func(t *testing.T) {
+		_, err := NewStatusEvent(uuid.New(), domain.AccountFrozen, account(0, domain.AccountClosed)).Process(context.Background())
+
+		assert.True(t, errors.Is(err, custom_errors.ErrInvalidStatusTransition))
+	})
+	t.Run("status process not found", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{}, nil
+			},
+		}
+
+		_, err := NewStatusEvent(uuid.New(), domain.AccountFrozen, serviceMock).Process(context.Background())
+
+		assert.Equal(t, custom_errors.ErrNotFound, err)
+	})
+	t.Run("status process record error", func(t *testing.T) {
+		serviceMock := account(0, domain.AccountActive)
+		serviceMock.record = func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+			return domain.Account{}, errors.New("test error")
+		}
+
+		_, err := NewStatusEvent(uuid.New(), domain.AccountFrozen, serviceMock).Process(context.Background())
+
+		assert.EqualError(t, err, "test error")
+	})
+}
@@ -1,6 +1,8 @@
 package events
 
 import (
+	"bytes"
+	"context"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/account"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
@@ -11,52 +13,133 @@ import (
 type transferEvent struct {
 	domain.DefaultEvent
 	TargetId uuid.UUID
-	Amount   float64
+	tr       *domain.Transaction
 	service  account.Service
+	rates    domain.FXRateProvider
+	// sweep moves the money out of a frozen source account too
+	sweep bool
 }
 
-func NewTransferEvent(id uuid.UUID, targetId uuid.UUID, amt float64, service account.Service) domain.Event {
+// NewTransferEvent moves the amount of the transaction to its destination account. When the
+// accounts have different currencies the amount is converted with the provider rate, and
+// the rate and the credited amount are recorded on the transaction
+func NewTransferEvent(tr *domain.Transaction, service account.Service, rates domain.FXRateProvider) domain.Event {
 	var event transferEvent
-	event.AccId = id
+	event.AccId = tr.AccountID
 	event.Type = domain.Transfer
-	event.Amount = amt
-	event.TargetId = targetId
+	event.TargetId = *tr.DestinationID
+	event.tr = tr
 	event.service = service
+	event.rates = rates
 	return &event
 }
 
-func (t *transferEvent) Process() (domain.Account, error) {
-	acc, err := t.service.Read(t.AccId)
+// NewSweepEvent moves the amount of the transaction to its destination account like a
+// transfer, while closing the source account. The source account can be frozen, as frozen
+// accounts can be closed, but the destination account has to be active
+func NewSweepEvent(tr *domain.Transaction, service account.Service, rates domain.FXRateProvider) domain.Event {
+	event := NewTransferEvent(tr, service, rates).(*transferEvent)
+	event.sweep = true
+	return event
+}
+
+func (t *transferEvent) Process(ctx context.Context) (domain.Account, error) {
+	if t.AccId == t.TargetId {
+		return domain.Account{}, custom_errors.ErrInvalidTransactionDestination
+	}
+
+	acc, destAcc, err := t.lockAccounts(ctx)
 	if err != nil {
 		return domain.Account{}, err
 	}
 
-	if reflect.DeepEqual(acc, domain.Account{}) {
-		return domain.Account{}, custom_errors.ErrNotFound
+	// money can not move out of the source account nor into the destination account
+	// unless both of them are active, but for the sweep out of a frozen account
+	if err = acc.CheckActive(); err != nil && !(t.sweep && acc.Status == domain.AccountFrozen) {
+		return domain.Account{}, err
 	}
-
-	destAcc, err := t.service.Read(t.TargetId)
-	if err != nil {
+	if err = destAcc.CheckActive(); err != nil {
 		return domain.Account{}, err
 	}
 
-	if reflect.DeepEqual(destAcc, domain.Account{}) {
-		return domain.Account{}, custom_errors.ErrNotFound
+	if err = t.tr.ResolveAmount(acc); err != nil {
+		return domain.Account{}, err
 	}
 
-	if acc.Balance < t.Amount {
+	if acc.Balance.LessThan(t.tr.Amount) {
 		return domain.Account{}, custom_errors.ErrInsuficientBalance
 	}
 
-	acc.Balance -= t.Amount
-	destAcc.Balance += t.Amount
-	if err = t.service.Update(acc); err != nil {
+	if err = t.convert(destAcc); err != nil {
 		return domain.Account{}, err
 	}
 
-	if err = t.service.Update(destAcc); err != nil {
+	acc, err = t.service.Record(ctx, acc, domain.AccountEvent{
+		Type:           domain.TransferOut,
+		Amount:         t.tr.Amount,
+		CounterpartyID: &t.TargetId,
+	})
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	_, err = t.service.Record(ctx, destAcc, domain.AccountEvent{
+		Type:           domain.TransferIn,
+		Amount:         *t.tr.DestinationAmount,
+		CounterpartyID: &t.AccId,
+	})
+	if err != nil {
 		return domain.Account{}, err
 	}
 
 	return acc, nil
 }
+
+// lockAccounts reads the source and destination accounts for update. The rows are
+// always locked in the same order, whatever the transfer direction, so two opposite
+// transfers can not deadlock each other
+func (t *transferEvent) lockAccounts(ctx context.Context) (domain.Account, domain.Account, error) {
+	first, second := t.AccId, t.TargetId
+	if bytes.Compare(second[:], first[:]) < 0 {
+		first, second = second, first
+	}
+
+	locked := make(map[uuid.UUID]domain.Account, 2)
+	for _, id := range []uuid.UUID{first, second} {
+		acc, err := t.service.ReadForUpdate(ctx, id)
+		if err != nil {
+			return domain.Account{}, domain.Account{}, err
+		}
+
+		if reflect.DeepEqual(acc, domain.Account{}) {
+			return domain.Account{}, domain.Account{}, custom_errors.ErrNotFound
+		}
+		locked[id] = acc
+	}
+
+	return locked[t.AccId], locked[t.TargetId], nil
+}
+
+// convert sets the amount credited to the destination account, in its currency
+func (t *transferEvent) convert(destAcc domain.Account) error {
+	rate := domain.OneRate()
+	if destAcc.Currency != t.tr.Currency {
+		var err error
+		rate, err = t.rates.Rate(t.tr.Currency, destAcc.Currency)
+		if err != nil {
+			return err
+		}
+	}
+
+	amount, err := rate.Convert(t.tr.Amount, destAcc.Currency)
+	if err != nil {
+		return err
+	}
+	if !amount.IsPositive() {
+		return custom_errors.ErrInvalidAmount
+	}
+	t.tr.Rate = &rate
+	t.tr.DestinationAmount = &amount
+	t.tr.DestinationCurrency = destAcc.Currency
+	return nil
+}
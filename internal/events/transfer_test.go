@@ -1,46 +1,72 @@
 package events
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/fx"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
+var rates = mustStaticRates(map[string]string{"USD/EUR": "0.92", "USD/JPY": "149.5"})
+
+func mustStaticRates(values map[string]string) domain.FXRateProvider {
+	parsed := make(map[string]domain.Rate, len(values))
+	for pair, value := range values {
+		rate, err := domain.ParseRate(value)
+		if err != nil {
+			panic(err)
+		}
+		parsed[pair] = rate
+	}
+	p, err := fx.NewStaticProvider(parsed)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func newTransfer(from, to uuid.UUID, amt domain.Money) *domain.Transaction {
+	return &domain.Transaction{AccountID: from, DestinationID: &to, Type: domain.Transfer, Amount: amt}
+}
+
 func TestTransferProcess(t *testing.T) {
 	t.Run("transfer process success", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), uuid.New(), 100.00, serviceMock)
+		transfer := NewTransferEvent(newTransfer(uuid.New(), uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
 
-		acc, err := transfer.Process()
+		acc, err := transfer.Process(context.Background())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, acc)
-		assert.Equal(t, 900.00, acc.Balance)
+		assert.Equal(t, domain.NewMoney(90000, domain.DefaultCurrency), acc.Balance)
 	})
 	t.Run("transfer process not found", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{}, custom_errors.ErrNotFound
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), uuid.New(), 100.00, serviceMock)
+		transfer := NewTransferEvent(newTransfer(uuid.New(), uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
 
-		acc, err := transfer.Process()
+		acc, err := transfer.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Equal(t, err, custom_errors.ErrNotFound)
@@ -48,14 +74,14 @@ func TestTransferProcess(t *testing.T) {
 	})
 	t.Run("transfer process empty account", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{}, nil
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), uuid.New(), 100.00, serviceMock)
+		transfer := NewTransferEvent(newTransfer(uuid.New(), uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
 
-		acc, err := transfer.Process()
+		acc, err := transfer.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.NotNil(t, acc)
@@ -65,20 +91,21 @@ func TestTransferProcess(t *testing.T) {
 	t.Run("transfer process not found destination account", func(t *testing.T) {
 		destination := uuid.New()
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				if id == destination {
 					return domain.Account{}, custom_errors.ErrNotFound
 				}
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), destination, 100.00, serviceMock)
+		transfer := NewTransferEvent(newTransfer(uuid.New(), destination, domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
 
-		acc, err := transfer.Process()
+		acc, err := transfer.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Equal(t, err, custom_errors.ErrNotFound)
@@ -87,20 +114,21 @@ func TestTransferProcess(t *testing.T) {
 	t.Run("transfer process empty destination account", func(t *testing.T) {
 		destination := uuid.New()
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				if id == destination {
 					return domain.Account{}, nil
 				}
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), destination, 100.00, serviceMock)
+		transfer := NewTransferEvent(newTransfer(uuid.New(), destination, domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
 
-		acc, err := transfer.Process()
+		acc, err := transfer.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.NotNil(t, acc)
@@ -109,63 +137,248 @@ func TestTransferProcess(t *testing.T) {
 	})
 	t.Run("transfer process negative balance", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 0.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(0, domain.DefaultCurrency),
 				}, nil
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), uuid.New(), 100.00, serviceMock)
+		transfer := NewTransferEvent(newTransfer(uuid.New(), uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
 
-		_, err := transfer.Process()
+		_, err := transfer.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Equal(t, custom_errors.ErrInsuficientBalance, err)
 	})
-	t.Run("transfer process update error", func(t *testing.T) {
+	t.Run("transfer process frozen destination account", func(t *testing.T) {
+		destination := uuid.New()
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				status := domain.AccountActive
+				if id == destination {
+					status = domain.AccountFrozen
+				}
+				return domain.Account{
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
+					Status:   status,
+				}, nil
+			},
+		}
+
+		transfer := NewTransferEvent(newTransfer(uuid.New(), destination, domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
+
+		_, err := transfer.Process(context.Background())
+
+		assert.Equal(t, custom_errors.ErrAccountFrozen, err)
+	})
+	t.Run("transfer process closed source account", func(t *testing.T) {
+		source := uuid.New()
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				status := domain.AccountActive
+				if id == source {
+					status = domain.AccountClosed
+				}
+				return domain.Account{
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
+					Status:   status,
+				}, nil
+			},
+		}
+
+		transfer := NewTransferEvent(newTransfer(source, uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
+
+		_, err := transfer.Process(context.Background())
+
+		assert.Equal(t, custom_errors.ErrAccountClosed, err)
+	})
+	t.Run("sweep process frozen source account", func(t *testing.T) {
+		source := uuid.New()
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				status := domain.AccountActive
+				if id == source {
+					status = domain.AccountFrozen
+				}
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
+					Status:   status,
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return errors.New("test error")
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), uuid.New(), 100.00, serviceMock)
+		_, err := NewTransferEvent(newTransfer(source, uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates).Process(context.Background())
+		assert.Equal(t, custom_errors.ErrAccountFrozen, err)
 
-		_, err := transfer.Process()
+		acc, err := NewSweepEvent(newTransfer(source, uuid.New(), domain.NewMoney(100000, domain.DefaultCurrency)), serviceMock, rates).Process(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, acc.Balance.IsZero())
+	})
+	t.Run("sweep process closed source account", func(t *testing.T) {
+		source := uuid.New()
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				status := domain.AccountActive
+				if id == source {
+					status = domain.AccountClosed
+				}
+				return domain.Account{ID: id, Currency: domain.DefaultCurrency, Balance: domain.NewMoney(100000, domain.DefaultCurrency), Status: status}, nil
+			},
+		}
+
+		_, err := NewSweepEvent(newTransfer(source, uuid.New(), domain.NewMoney(100000, domain.DefaultCurrency)), serviceMock, rates).Process(context.Background())
+
+		assert.Equal(t, custom_errors.ErrAccountClosed, err)
+	})
+	t.Run("transfer process same source and destination", func(t *testing.T) {
+		id := uuid.New()
+		transfer := NewTransferEvent(newTransfer(id, id, domain.NewMoney(10000, domain.DefaultCurrency)), accServiceMock{}, rates)
+
+		_, err := transfer.Process(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, custom_errors.ErrInvalidTransactionDestination, err)
+	})
+	t.Run("transfer process record error", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
+				}, nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				return domain.Account{}, errors.New("test error")
+			},
+		}
+
+		transfer := NewTransferEvent(newTransfer(uuid.New(), uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
+
+		_, err := transfer.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
 	})
-	t.Run("transfer process update error destination account", func(t *testing.T) {
+	t.Run("transfer process record error destination account", func(t *testing.T) {
 		destination := uuid.New()
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
 				if account.ID == destination {
-					return errors.New("test error")
+					return domain.Account{}, errors.New("test error")
 				}
-				return nil
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
-		transfer := NewTransferEvent(uuid.New(), destination, 100.00, serviceMock)
+		transfer := NewTransferEvent(newTransfer(uuid.New(), destination, domain.NewMoney(10000, domain.DefaultCurrency)), serviceMock, rates)
 
-		_, err := transfer.Process()
+		_, err := transfer.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
 	})
+	t.Run("transfer process between currencies", func(t *testing.T) {
+		source, destination := uuid.New(), uuid.New()
+		currencies := map[uuid.UUID]string{source: "USD", destination: "JPY"}
+		credited := domain.Money{}
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{
+					ID:       id,
+					Currency: currencies[id],
+					Balance:  domain.NewMoney(100000, currencies[id]),
+				}, nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				if account.ID == destination {
+					credited = event.Amount
+				}
+				account.Apply(event)
+				return account, nil
+			},
+		}
+
+		// 12.34 USD at 149.5 is 1844.83 JPY, rounded to 1845
+		tr := newTransfer(source, destination, domain.NewMoney(1234, "USD"))
+		acc, err := NewTransferEvent(tr, serviceMock, rates).Process(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(100000-1234, "USD"), acc.Balance)
+		assert.Equal(t, domain.NewMoney(1845, "JPY"), credited)
+		assert.Equal(t, "USD", tr.Currency)
+		assert.Equal(t, "149.5", tr.Rate.String())
+		assert.Equal(t, domain.NewMoney(1845, "JPY"), *tr.DestinationAmount)
+		assert.Equal(t, "JPY", tr.DestinationCurrency)
+	})
+	t.Run("transfer process same currency records the unit rate", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{ID: id, Currency: "EUR", Balance: domain.NewMoney(100000, "EUR")}, nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
+			},
+		}
+
+		tr := newTransfer(uuid.New(), uuid.New(), domain.NewMoney(10000, domain.DefaultCurrency))
+		_, err := NewTransferEvent(tr, serviceMock, rates).Process(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(10000, "EUR"), tr.Amount)
+		assert.Equal(t, "1", tr.Rate.String())
+		assert.Equal(t, domain.NewMoney(10000, "EUR"), *tr.DestinationAmount)
+	})
+	t.Run("transfer process rate not found", func(t *testing.T) {
+		source, destination := uuid.New(), uuid.New()
+		currencies := map[uuid.UUID]string{source: "EUR", destination: "JPY"}
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{ID: id, Currency: currencies[id], Balance: domain.NewMoney(100000, currencies[id])}, nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				t.Fatal("transfer recorded without an exchange rate")
+				return account, nil
+			},
+		}
+
+		_, err := NewTransferEvent(newTransfer(source, destination, domain.NewMoney(100, "EUR")), serviceMock, rates).Process(context.Background())
+
+		assert.ErrorIs(t, err, custom_errors.ErrExchangeRateNotFound)
+	})
+	t.Run("transfer process currency mismatch", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{ID: id, Currency: "USD", Balance: domain.NewMoney(100000, "USD")}, nil
+			},
+		}
+
+		tr := newTransfer(uuid.New(), uuid.New(), domain.NewMoney(100, "EUR"))
+		tr.Currency = "EUR"
+		_, err := NewTransferEvent(tr, serviceMock, rates).Process(context.Background())
+
+		assert.ErrorIs(t, err, custom_errors.ErrCurrencyMismatch)
+	})
 }
@@ -1,7 +1,7 @@
 package events
 
 import (
-	"github.com/google/uuid"
+	"context"
 	"github.com/lucaspichi06/xepelin-bank/internal/account"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
@@ -10,21 +10,21 @@ import (
 
 type withdrawEvent struct {
 	domain.DefaultEvent
-	Amount  float64
+	tr      *domain.Transaction
 	service account.Service
 }
 
-func NewWithdrawEvent(id uuid.UUID, amt float64, service account.Service) domain.Event {
+func NewWithdrawEvent(tr *domain.Transaction, service account.Service) domain.Event {
 	var event withdrawEvent
-	event.AccId = id
+	event.AccId = tr.AccountID
 	event.Type = domain.WithDraw
-	event.Amount = amt
+	event.tr = tr
 	event.service = service
 	return &event
 }
 
-func (t *withdrawEvent) Process() (domain.Account, error) {
-	acc, err := t.service.Read(t.AccId)
+func (t *withdrawEvent) Process(ctx context.Context) (domain.Account, error) {
+	acc, err := t.service.ReadForUpdate(ctx, t.AccId)
 	if err != nil {
 		return domain.Account{}, err
 	}
@@ -33,10 +33,20 @@ func (t *withdrawEvent) Process() (domain.Account, error) {
 		return domain.Account{}, custom_errors.ErrNotFound
 	}
 
-	if acc.Balance < t.Amount {
+	if err = acc.CheckActive(); err != nil {
+		return domain.Account{}, err
+	}
+
+	if err = t.tr.ResolveAmount(acc); err != nil {
+		return domain.Account{}, err
+	}
+
+	if acc.Balance.LessThan(t.tr.Amount) {
 		return domain.Account{}, custom_errors.ErrInsuficientBalance
 	}
 
-	acc.Balance = acc.Balance - t.Amount
-	return acc, t.service.Update(acc)
+	return t.service.Record(ctx, acc, domain.AccountEvent{
+		Type:   domain.WithDraw,
+		Amount: t.tr.Amount,
+	})
 }
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
@@ -12,35 +13,37 @@ import (
 func TestWithdrawProcess(t *testing.T) {
 	t.Run("withdraw process success", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
-		withdraw := NewWithdrawEvent(uuid.New(), 100.00, serviceMock)
+		withdraw := NewWithdrawEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		acc, err := withdraw.Process()
+		acc, err := withdraw.Process(context.Background())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, acc)
-		assert.Equal(t, 900.00, acc.Balance)
+		assert.Equal(t, domain.NewMoney(90000, domain.DefaultCurrency), acc.Balance)
 	})
 	t.Run("withdraw process not found", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{}, custom_errors.ErrNotFound
 			},
 		}
 
-		withdraw := NewWithdrawEvent(uuid.New(), 100.00, serviceMock)
+		withdraw := NewWithdrawEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		acc, err := withdraw.Process()
+		acc, err := withdraw.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Equal(t, err, custom_errors.ErrNotFound)
@@ -48,53 +51,73 @@ func TestWithdrawProcess(t *testing.T) {
 	})
 	t.Run("withdraw process empty account", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{}, nil
 			},
 		}
 
-		withdraw := NewWithdrawEvent(uuid.New(), 100.00, serviceMock)
+		withdraw := NewWithdrawEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		acc, err := withdraw.Process()
+		acc, err := withdraw.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.NotNil(t, acc)
 		assert.Equal(t, domain.Account{}, acc)
 		assert.Equal(t, err, custom_errors.ErrNotFound)
 	})
+	t.Run("withdraw process frozen account", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
+					Status:   domain.AccountFrozen,
+				}, nil
+			},
+		}
+
+		withdraw := NewWithdrawEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
+
+		_, err := withdraw.Process(context.Background())
+
+		assert.Equal(t, custom_errors.ErrAccountFrozen, err)
+	})
 	t.Run("withdraw process negative balance", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 0.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(0, domain.DefaultCurrency),
 				}, nil
 			},
 		}
 
-		withdraw := NewWithdrawEvent(uuid.New(), 100.00, serviceMock)
+		withdraw := NewWithdrawEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		_, err := withdraw.Process()
+		_, err := withdraw.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Equal(t, custom_errors.ErrInsuficientBalance, err)
 	})
-	t.Run("withdraw process update error", func(t *testing.T) {
+	t.Run("withdraw process record error", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID:      id,
-					Balance: 1000.00,
+					ID:       id,
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return errors.New("test error")
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				return domain.Account{}, errors.New("test error")
 			},
 		}
 
-		withdraw := NewWithdrawEvent(uuid.New(), 100.00, serviceMock)
+		withdraw := NewWithdrawEvent(&domain.Transaction{AccountID: uuid.New(), Amount: domain.NewMoney(10000, domain.DefaultCurrency)}, serviceMock)
 
-		_, err := withdraw.Process()
+		_, err := withdraw.Process(context.Background())
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
@@ -0,0 +1,76 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+type staticProvider struct {
+	rates map[string]domain.Rate
+}
+
+// NewStaticProvider returns a provider of fixed rates, keyed by currency pair such as
+// "USD/EUR". The inverse of every pair is available too, unless it is set explicitly
+func NewStaticProvider(rates map[string]domain.Rate) (domain.FXRateProvider, error) {
+	p := &staticProvider{rates: make(map[string]domain.Rate, 2*len(rates))}
+	for pair, rate := range rates {
+		from, to, err := parsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		if rate.IsZero() {
+			return nil, fmt.Errorf("%w for %s", custom_errors.ErrInvalidExchangeRate, pair)
+		}
+		p.rates[from+"/"+to] = rate
+	}
+	for pair, rate := range rates {
+		from, to, _ := parsePair(pair)
+		if _, ok := p.rates[to+"/"+from]; !ok {
+			p.rates[to+"/"+from] = rate.Inverse()
+		}
+	}
+	return p, nil
+}
+
+// NewFileProvider loads fixed rates from a JSON file mapping currency pairs to rates, e.g.
+// {"USD/EUR": "0.92", "USD/ARS": 350.5}
+func NewFileProvider(path string) (domain.FXRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[string]domain.Rate
+	if err = json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("reading exchange rates from %s: %w", path, err)
+	}
+	return NewStaticProvider(rates)
+}
+
+func (p staticProvider) Rate(from, to string) (domain.Rate, error) {
+	if from == to {
+		return domain.OneRate(), nil
+	}
+	rate, ok := p.rates[from+"/"+to]
+	if !ok {
+		return domain.Rate{}, fmt.Errorf("%w: %s/%s", custom_errors.ErrExchangeRateNotFound, from, to)
+	}
+	return rate, nil
+}
+
+func parsePair(pair string) (string, string, error) {
+	parts := strings.Split(strings.ToUpper(pair), "/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("%w: invalid currency pair %q", custom_errors.ErrInvalidExchangeRate, pair)
+	}
+	for _, currency := range parts {
+		if _, ok := domain.CurrencyExponent(currency); !ok {
+			return "", "", fmt.Errorf("%w: %q in currency pair %q", custom_errors.ErrInvalidCurrency, currency, pair)
+		}
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,69 @@
+package fx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticProvider(t *testing.T) {
+	eur, _ := domain.ParseRate("0.8")
+	p, err := NewStaticProvider(map[string]domain.Rate{"usd/eur": eur})
+	assert.NoError(t, err)
+
+	t.Run("direct rate", func(t *testing.T) {
+		rate, err := p.Rate("USD", "EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, "0.8", rate.String())
+	})
+	t.Run("inverse rate", func(t *testing.T) {
+		rate, err := p.Rate("EUR", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, "1.25", rate.String())
+	})
+	t.Run("same currency", func(t *testing.T) {
+		rate, err := p.Rate("JPY", "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", rate.String())
+	})
+	t.Run("rate not found", func(t *testing.T) {
+		_, err := p.Rate("USD", "JPY")
+		assert.ErrorIs(t, err, custom_errors.ErrExchangeRateNotFound)
+	})
+	t.Run("invalid pairs", func(t *testing.T) {
+		_, err := NewStaticProvider(map[string]domain.Rate{"USDEUR": eur})
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidExchangeRate)
+
+		_, err = NewStaticProvider(map[string]domain.Rate{"USD/XXX": eur})
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidCurrency)
+	})
+}
+
+func TestFileProvider(t *testing.T) {
+	t.Run("load rates", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"USD/EUR": "0.92", "USD/ARS": 350.5}`), 0o600))
+
+		p, err := NewFileProvider(path)
+		assert.NoError(t, err)
+
+		rate, err := p.Rate("USD", "ARS")
+		assert.NoError(t, err)
+		assert.Equal(t, "350.5", rate.String())
+	})
+	t.Run("invalid rate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"USD/EUR": "-1"}`), 0o600))
+
+		_, err := NewFileProvider(path)
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidExchangeRate)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+}
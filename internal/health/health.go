@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// DefaultCheckTimeout is the time every check has to answer
+const DefaultCheckTimeout = 2 * time.Second
+
+// Check reports whether a dependency of the application is usable
+type Check func(ctx context.Context) error
+
+// Report is the status of the application and of each one of its checks
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckReport `json:"checks,omitempty"`
+}
+
+type CheckReport struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Checker tells whether the application can serve requests
+type Checker interface {
+	// Ready runs every check, the application is ready when all of them pass
+	Ready(ctx context.Context) Report
+}
+
+type checker struct {
+	checks  map[string]Check
+	timeout time.Duration
+}
+
+func NewChecker(checks map[string]Check, timeout time.Duration) Checker {
+	return &checker{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Ready runs the checks concurrently, each one with its own timeout
+func (c *checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckReport, len(c.checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *checker) run(ctx context.Context, check Check) CheckReport {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckReport{Status: StatusUp, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	return result
+}
+
+// Database checks the database can be reached
+func Database(db *sql.DB) Check {
+	return db.PingContext
+}
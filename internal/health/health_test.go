@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("ready when every check passes", func(t *testing.T) {
+		report := NewChecker(map[string]Check{"database": up, "migrations": up}, time.Second).Ready(context.Background())
+
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Equal(t, StatusUp, report.Checks["database"].Status)
+		assert.Equal(t, StatusUp, report.Checks["migrations"].Status)
+	})
+	t.Run("not ready when a check fails", func(t *testing.T) {
+		report := NewChecker(map[string]Check{"database": down, "migrations": up}, time.Second).Ready(context.Background())
+
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, CheckReport{Status: StatusDown, Error: "connection refused", DurationMs: report.Checks["database"].DurationMs}, report.Checks["database"])
+		assert.Equal(t, StatusUp, report.Checks["migrations"].Status)
+	})
+	t.Run("not ready when a check times out", func(t *testing.T) {
+		report := NewChecker(map[string]Check{"database": slow}, 10*time.Millisecond).Ready(context.Background())
+
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+	})
+}
+
+func TestDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fail()
+	}
+	defer db.Close()
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	assert.EqualError(t, Database(db)(context.Background()), "connection refused")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// eachBackend runs the test on every storage backend, so they all behave the same. MySQL
+// and PostgreSQL only run when storetest.MySQLEnv and storetest.PostgresEnv are set
+func eachBackend(t *testing.T, test func(t *testing.T, s Store)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemoryStore()) })
+	t.Run("sqlite", func(t *testing.T) { test(t, NewStore(storetest.SQLite(t))) })
+	t.Run("mysql", func(t *testing.T) { test(t, NewStore(storetest.MySQL(t))) })
+	t.Run("postgres", func(t *testing.T) { test(t, NewStore(store.Postgres(storetest.Postgres(t)))) })
+}
+
+func TestStoreContract(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	eachBackend(t, func(t *testing.T, s Store) {
+		record := Record{Key: "key", RequestHash: "hash", CreatedAt: now}
+		_, reserved, err := s.Reserve(ctx, record, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+
+		existing, reserved, err := s.Reserve(ctx, Record{Key: "key", RequestHash: "other", CreatedAt: now}, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, reserved)
+		assert.Equal(t, "hash", existing.RequestHash)
+		assert.False(t, existing.Completed())
+
+		assert.NoError(t, s.Complete(ctx, "key", 201, []byte(`{"ok":true}`)))
+		existing, reserved, err = s.Reserve(ctx, record, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, reserved)
+		assert.Equal(t, Record{Key: "key", RequestHash: "hash", StatusCode: 201, ResponseBody: []byte(`{"ok":true}`), CreatedAt: now}, existing)
+
+		// an expired key is reserved again
+		_, reserved, err = s.Reserve(ctx, Record{Key: "key", RequestHash: "new", CreatedAt: now.Add(2 * time.Hour)}, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+
+		assert.NoError(t, s.Release(ctx, "key"))
+		_, reserved, err = s.Reserve(ctx, record, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+
+		_, _, err = s.Reserve(ctx, Record{Key: "recent", RequestHash: "hash", CreatedAt: now.Add(time.Hour)}, now)
+		assert.NoError(t, err)
+		purged, err := s.Purge(ctx, now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+	})
+}
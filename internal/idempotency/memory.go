@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		records: make(map[string]Record),
+	}
+}
+
+func (s *memoryStore) Reserve(_ context.Context, r Record, expiredBefore time.Time) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.records[r.Key]
+	if ok && !existing.CreatedAt.Before(expiredBefore) {
+		return existing, false, nil
+	}
+	s.records[r.Key] = Record{Key: r.Key, RequestHash: r.RequestHash, ResponseBody: []byte{}, CreatedAt: r.CreatedAt.UTC()}
+	return r, true, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, statusCode int, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok {
+		r.StatusCode, r.ResponseBody = statusCode, append([]byte{}, body...)
+		s.records[key] = r
+	}
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *memoryStore) Purge(_ context.Context, expiredBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for key, r := range s.records {
+		if r.CreatedAt.Before(expiredBefore) {
+			delete(s.records, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,100 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// DefaultRetention is how long a key is remembered when no retention is configured
+const DefaultRetention = 24 * time.Hour
+
+// Record is a request received with an Idempotency-Key header. StatusCode is zero while
+// the request is still being processed
+type Record struct {
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
+
+// Completed reports whether the response of the request was stored
+func (r Record) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// Store keeps the idempotency keys and the responses sent for them
+type Store interface {
+	// Reserve stores the key as in progress. If the key was already reserved it returns the
+	// existing record and false. Records created before expiredBefore are replaced
+	Reserve(ctx context.Context, r Record, expiredBefore time.Time) (Record, bool, error)
+	Complete(ctx context.Context, key string, statusCode int, body []byte) error
+	Release(ctx context.Context, key string) error
+	Purge(ctx context.Context, expiredBefore time.Time) (int64, error)
+}
+
+type sqlStore struct {
+	db store.DBTX
+}
+
+func NewStore(db store.DBTX) Store {
+	return &sqlStore{
+		db: store.Instrument(db, store.SystemOf(db), "idempotency_keys"),
+	}
+}
+
+func (s sqlStore) Reserve(ctx context.Context, r Record, expiredBefore time.Time) (Record, bool, error) {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE idempotency_key = ? AND created_at < ?;", r.Key, expiredBefore.UTC())
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	_, err = s.db.ExecContext(ctx, "INSERT INTO idempotency_keys (idempotency_key, request_hash, status_code, response_body, created_at) VALUES (?, ?, ?, ?, ?);",
+		r.Key, r.RequestHash, 0, []byte{}, r.CreatedAt.UTC())
+	if err == nil {
+		return r, true, nil
+	}
+	if !store.IsDuplicate(err) {
+		return Record{}, false, err
+	}
+
+	existing, err := s.read(ctx, r.Key)
+	if err != nil {
+		return Record{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (s sqlStore) read(ctx context.Context, key string) (Record, error) {
+	var r Record
+	row := s.db.QueryRowContext(ctx, "SELECT idempotency_key, request_hash, status_code, response_body, created_at FROM idempotency_keys WHERE idempotency_key = ?;", key)
+	err := row.Scan(&r.Key, &r.RequestHash, &r.StatusCode, &r.ResponseBody, &r.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// released by the request holding it, so it is in progress again
+		return Record{Key: key}, nil
+	}
+	r.CreatedAt = r.CreatedAt.UTC()
+	return r, err
+}
+
+func (s sqlStore) Complete(ctx context.Context, key string, statusCode int, body []byte) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE idempotency_keys SET status_code = ?, response_body = ? WHERE idempotency_key = ?;", statusCode, body, key)
+	return err
+}
+
+func (s sqlStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE idempotency_key = ?;", key)
+	return err
+}
+
+func (s sqlStore) Purge(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < ?;", expiredBefore.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,134 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+var recordColumns = []string{"idempotency_key", "request_hash", "status_code", "response_body", "created_at"}
+
+func TestReserve(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := Record{Key: "key", RequestHash: "hash", CreatedAt: now}
+
+	t.Run("reserve new key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE FROM idempotency_keys WHERE idempotency_key = \\? AND created_at < \\?").
+			WithArgs("key", now.Add(-time.Hour)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO idempotency_keys").
+			WithArgs("key", "hash", 0, []byte{}, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		res, reserved, err := NewStore(db).Reserve(context.Background(), record, now.Add(-time.Hour))
+
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+		assert.Equal(t, record, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("reserve existing key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO idempotency_keys").
+			WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		mock.ExpectQuery("SELECT idempotency_key, request_hash, status_code, response_body, created_at FROM idempotency_keys").
+			WithArgs("key").
+			WillReturnRows(sqlmock.NewRows(recordColumns).AddRow("key", "hash", 201, []byte(`{"data":{}}`), now))
+
+		res, reserved, err := NewStore(db).Reserve(context.Background(), record, now.Add(-time.Hour))
+
+		assert.NoError(t, err)
+		assert.False(t, reserved)
+		assert.True(t, res.Completed())
+		assert.Equal(t, 201, res.StatusCode)
+		assert.Equal(t, []byte(`{"data":{}}`), res.ResponseBody)
+	})
+	t.Run("reserve key released meanwhile", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO idempotency_keys").
+			WillReturnError(&mysql.MySQLError{Number: 1062})
+		mock.ExpectQuery("SELECT idempotency_key").WillReturnRows(sqlmock.NewRows(recordColumns))
+
+		res, reserved, err := NewStore(db).Reserve(context.Background(), record, now.Add(-time.Hour))
+
+		assert.NoError(t, err)
+		assert.False(t, reserved)
+		assert.False(t, res.Completed())
+	})
+	t.Run("reserve insert error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(errors.New("test error"))
+
+		_, _, err = NewStore(db).Reserve(context.Background(), record, now.Add(-time.Hour))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestComplete(t *testing.T) {
+	t.Run("complete key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("UPDATE idempotency_keys SET status_code = \\?, response_body = \\? WHERE idempotency_key = \\?").
+			WithArgs(201, []byte("body"), "key").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = NewStore(db).Complete(context.Background(), "key", 201, []byte("body"))
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPurge(t *testing.T) {
+	t.Run("purge expired keys", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		before := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectExec("DELETE FROM idempotency_keys WHERE created_at < \\?").
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		purged, err := NewStore(db).Purge(context.Background(), before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), purged)
+	})
+}
@@ -0,0 +1,47 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// eachBackend runs the test on every storage backend, so they all behave the same. MySQL
+// and PostgreSQL only run when storetest.MySQLEnv and storetest.PostgresEnv are set
+func eachBackend(t *testing.T, test func(t *testing.T, r Repository)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemoryRepository()) })
+	t.Run("sqlite", func(t *testing.T) { test(t, NewRepository(storetest.SQLite(t))) })
+	t.Run("mysql", func(t *testing.T) { test(t, NewRepository(storetest.MySQL(t))) })
+	t.Run("postgres", func(t *testing.T) { test(t, NewRepository(store.Postgres(storetest.Postgres(t)))) })
+}
+
+func TestRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+
+	eachBackend(t, func(t *testing.T, r Repository) {
+		totals, err := r.Totals(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, totals)
+
+		entry := domain.JournalEntry{ID: uuid.New(), TransactionID: uuid.New(), Timestamp: time.Now().UTC(), Postings: []domain.Posting{
+			{LedgerAccount: "system:cash:USD", Amount: domain.NewMoney(1000, "USD")},
+			{LedgerAccount: "account:1", Amount: domain.NewMoney(-1000, "USD")},
+			{LedgerAccount: "system:clearing:CLP", Amount: domain.NewMoney(500, "CLP")},
+		}}
+		assert.NoError(t, r.Append(ctx, entry))
+
+		again := entry
+		again.ID = uuid.New()
+		assert.True(t, store.IsDuplicate(r.Append(ctx, again)), "one entry per transaction")
+
+		totals, err = r.Totals(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"USD": 0, "CLP": 500}, totals)
+	})
+}
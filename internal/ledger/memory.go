@@ -0,0 +1,58 @@
+package ledger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+type memoryRepository struct {
+	mu      sync.RWMutex
+	entries map[uuid.UUID]domain.JournalEntry
+	// transactions holds the ids of the transactions with an entry, which are unique
+	transactions map[uuid.UUID]bool
+}
+
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		entries:      make(map[uuid.UUID]domain.JournalEntry),
+		transactions: make(map[uuid.UUID]bool),
+	}
+}
+
+func (r *memoryRepository) Append(ctx context.Context, entry domain.JournalEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[entry.ID]; ok || r.transactions[entry.TransactionID] {
+		return store.ErrDuplicate
+	}
+	entry.Postings = append([]domain.Posting(nil), entry.Postings...)
+	entry.Timestamp = entry.Timestamp.UTC()
+	r.entries[entry.ID] = entry
+	r.transactions[entry.TransactionID] = true
+
+	store.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.entries, entry.ID)
+		delete(r.transactions, entry.TransactionID)
+	})
+	return nil
+}
+
+func (r *memoryRepository) Totals(_ context.Context) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	totals := make(map[string]int64)
+	for _, entry := range r.entries {
+		for _, p := range entry.Postings {
+			totals[p.Amount.Currency] += p.Amount.Amount
+		}
+	}
+	return totals, nil
+}
@@ -0,0 +1,58 @@
+package ledger
+
+import (
+	"context"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// Repository stores the journal entries, which are never updated nor deleted
+type Repository interface {
+	Append(ctx context.Context, entry domain.JournalEntry) error
+	// Totals adds up every posting by currency
+	Totals(ctx context.Context) (map[string]int64, error)
+}
+
+type repository struct {
+	db store.DBTX
+}
+
+func NewRepository(db store.DBTX) Repository {
+	return &repository{
+		db: store.Instrument(db, store.SystemOf(db), "ledger"),
+	}
+}
+
+func (r repository) Append(ctx context.Context, entry domain.JournalEntry) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO journal_entries (id, transaction_id, timestamp) VALUES (?, ?, ?);",
+		entry.ID, entry.TransactionID, entry.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range entry.Postings {
+		if _, err = r.db.ExecContext(ctx, "INSERT INTO postings (entry_id, line, ledger_account, amount, currency) VALUES (?, ?, ?, ?, ?);", entry.ID, i+1, p.LedgerAccount, p.Amount.Amount, p.Amount.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r repository) Totals(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT currency, COALESCE(SUM(amount), 0) FROM postings GROUP BY currency;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]int64)
+	for rows.Next() {
+		var currency string
+		var total int64
+		if err = rows.Scan(&currency, &total); err != nil {
+			return nil, err
+		}
+		totals[currency] = total
+	}
+	return totals, rows.Err()
+}
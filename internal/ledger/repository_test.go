@@ -0,0 +1,122 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAppend(t *testing.T) {
+	entry, _ := domain.NewJournalEntry(domain.Transaction{
+		ID:        uuid.New(),
+		AccountID: uuid.New(),
+		Type:      domain.Deposit,
+		Amount:    domain.NewMoney(1000, "USD"),
+		Timestamp: time.Now(),
+	})
+
+	t.Run("append success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectExec("INSERT INTO journal_entries").WithArgs(entry.ID, entry.TransactionID, entry.Timestamp).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		for i, p := range entry.Postings {
+			mock.ExpectExec("INSERT INTO postings").WithArgs(entry.ID, i+1, p.LedgerAccount, p.Amount.Amount, p.Amount.Currency).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		err = r.Append(context.Background(), entry)
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("append entry error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectExec("INSERT INTO journal_entries").WillReturnError(errors.New("test error"))
+
+		err = r.Append(context.Background(), entry)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("append posting error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectExec("INSERT INTO journal_entries").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO postings").WillReturnError(errors.New("test error"))
+
+		err = r.Append(context.Background(), entry)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
+
+func TestTotals(t *testing.T) {
+	t.Run("totals success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectQuery("SELECT currency, (.+) FROM postings GROUP BY currency").
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("USD", 0).AddRow("EUR", 5))
+
+		totals, err := r.Totals(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"USD": 0, "EUR": 5}, totals)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("totals query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		r := NewRepository(db)
+
+		mock.ExpectQuery("SELECT currency, (.+) FROM postings").WillReturnError(errors.New("test error"))
+
+		_, err = r.Totals(context.Background())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,54 @@
+package ledger
+
+import (
+	"context"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+type Service interface {
+	// Record posts the balanced journal entry of a processed transaction
+	Record(ctx context.Context, tr domain.Transaction) (domain.JournalEntry, error)
+	// Verify checks the invariant of the ledger: all the postings add up to zero in every currency
+	Verify(ctx context.Context) (domain.LedgerVerification, error)
+}
+
+type service struct {
+	r Repository
+}
+
+func NewService(r Repository) Service {
+	return &service{
+		r: r,
+	}
+}
+
+func (s service) Record(ctx context.Context, tr domain.Transaction) (domain.JournalEntry, error) {
+	entry, err := domain.NewJournalEntry(tr)
+	if err != nil {
+		return domain.JournalEntry{}, err
+	}
+	if err = s.r.Append(ctx, entry); err != nil {
+		return domain.JournalEntry{}, err
+	}
+	return entry, nil
+}
+
+func (s service) Verify(ctx context.Context) (domain.LedgerVerification, error) {
+	totals, err := s.r.Totals(ctx)
+	if err != nil {
+		return domain.LedgerVerification{}, err
+	}
+
+	res := domain.LedgerVerification{Balanced: true, Unbalanced: map[string]domain.Money{}}
+	for currency, total := range totals {
+		if total != 0 {
+			res.Balanced = false
+			res.Unbalanced[currency] = domain.NewMoney(total, currency)
+		}
+	}
+	if !res.Balanced {
+		return res, custom_errors.ErrLedgerUnbalanced
+	}
+	return res, nil
+}
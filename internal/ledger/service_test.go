@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type repositoryMock struct {
+	append func(entry domain.JournalEntry) error
+	totals func() (map[string]int64, error)
+}
+
+func (r repositoryMock) Append(ctx context.Context, entry domain.JournalEntry) error {
+	return r.append(entry)
+}
+
+func (r repositoryMock) Totals(ctx context.Context) (map[string]int64, error) {
+	return r.totals()
+}
+
+func TestRecord(t *testing.T) {
+	tr := domain.Transaction{ID: uuid.New(), AccountID: uuid.New(), Type: domain.Deposit, Amount: domain.NewMoney(1000, "USD")}
+
+	t.Run("record success", func(t *testing.T) {
+		var appended domain.JournalEntry
+		s := NewService(repositoryMock{
+			append: func(entry domain.JournalEntry) error {
+				appended = entry
+				return nil
+			},
+		})
+
+		entry, err := s.Record(context.Background(), tr)
+		assert.NoError(t, err)
+		assert.Equal(t, tr.ID, entry.TransactionID)
+		assert.Equal(t, appended, entry)
+	})
+	t.Run("record invalid transaction", func(t *testing.T) {
+		s := NewService(repositoryMock{})
+
+		_, err := s.Record(context.Background(), domain.Transaction{ID: uuid.New(), Type: domain.Transfer, Amount: domain.NewMoney(1000, "USD")})
+		assert.Equal(t, custom_errors.ErrInvalidTransactionDestination, err)
+	})
+	t.Run("record append error", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			append: func(entry domain.JournalEntry) error {
+				return errors.New("test error")
+			},
+		})
+
+		_, err := s.Record(context.Background(), tr)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test error")
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("verify balanced", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			totals: func() (map[string]int64, error) {
+				return map[string]int64{"USD": 0, "EUR": 0}, nil
+			},
+		})
+
+		res, err := s.Verify(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, res.Balanced)
+		assert.Empty(t, res.Unbalanced)
+	})
+	t.Run("verify unbalanced", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			totals: func() (map[string]int64, error) {
+				return map[string]int64{"USD": 0, "EUR": 15}, nil
+			},
+		})
+
+		res, err := s.Verify(context.Background())
+		assert.Equal(t, custom_errors.ErrLedgerUnbalanced, err)
+		assert.False(t, res.Balanced)
+		assert.Equal(t, map[string]domain.Money{"EUR": domain.NewMoney(15, "EUR")}, res.Unbalanced)
+	})
+	t.Run("verify totals error", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			totals: func() (map[string]int64, error) {
+				return nil, errors.New("test error")
+			},
+		})
+
+		_, err := s.Verify(context.Background())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// lockName is the advisory lock held while migrating, so the instances started together
+// apply every migration once
+const lockName = "xepelin-bank.schema_migrations"
+
+const (
+	mysqlNoSuchTable    = 1146
+	postgresNoSuchTable = "42P01"
+)
+
+// postgresLockRetry is how often the migrator tries to take the PostgreSQL advisory lock
+const postgresLockRetry = 250 * time.Millisecond
+
+// Dialect is what the migrator runs differently on every database
+type Dialect struct {
+	createTable string
+	// lock takes the advisory lock of the migrations on the connection, it is nil when the
+	// database has none
+	lock        func(ctx context.Context, conn *sql.Conn, timeout time.Duration) error
+	unlock      func(ctx context.Context, conn *sql.Conn) error
+	noSuchTable func(err error) bool
+	// bind writes the ? placeholders of the statements as the database expects them, it is
+	// nil when the database takes them as they are
+	bind func(query string) string
+}
+
+// MySQL locks the migrations with GET_LOCK, which is released when its connection is closed
+var MySQL = Dialect{
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version BIGINT NOT NULL, " +
+		"name VARCHAR(255) NOT NULL, " +
+		"checksum CHAR(64) NOT NULL, " +
+		"dirty BOOLEAN NOT NULL DEFAULT FALSE, " +
+		"applied_at DATETIME(6) NOT NULL, " +
+		"PRIMARY KEY (version)" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;",
+	lock: func(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+		var acquired sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?);", lockName, int64(timeout.Seconds())).Scan(&acquired); err != nil {
+			return err
+		}
+		if acquired.Int64 != 1 {
+			return fmt.Errorf("the migrations lock was not acquired in %s, another instance is migrating the database", timeout)
+		}
+		return nil
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?);", lockName)
+		return err
+	},
+	noSuchTable: func(err error) bool {
+		var mysqlErr *mysql.MySQLError
+		return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlNoSuchTable
+	},
+}
+
+// SQLite has no advisory locks, its databases are files migrated by a single process
+var SQLite = Dialect{
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version INTEGER NOT NULL PRIMARY KEY, " +
+		"name TEXT NOT NULL, " +
+		"checksum TEXT NOT NULL, " +
+		"dirty BOOLEAN NOT NULL DEFAULT FALSE, " +
+		"applied_at DATETIME NOT NULL" +
+		");",
+	noSuchTable: func(err error) bool {
+		return err != nil && strings.Contains(err.Error(), "no such table")
+	},
+}
+
+// Postgres locks the migrations with a session advisory lock, which is released when its
+// connection is closed. PostgreSQL can not wait for it with a timeout, so it is polled
+var Postgres = Dialect{
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version BIGINT NOT NULL PRIMARY KEY, " +
+		"name VARCHAR(255) NOT NULL, " +
+		"checksum CHAR(64) NOT NULL, " +
+		"dirty BOOLEAN NOT NULL DEFAULT FALSE, " +
+		"applied_at TIMESTAMPTZ NOT NULL" +
+		");",
+	lock: func(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		ticker := time.NewTicker(postgresLockRetry)
+		defer ticker.Stop()
+		for {
+			var acquired bool
+			err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1);", postgresLockKey()).Scan(&acquired)
+			switch {
+			case err == nil && acquired:
+				return nil
+			case ctx.Err() == context.DeadlineExceeded:
+				return fmt.Errorf("the migrations lock was not acquired in %s, another instance is migrating the database", timeout)
+			case err != nil:
+				return err
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1);", postgresLockKey())
+		return err
+	},
+	noSuchTable: func(err error) bool {
+		var postgresErr *pq.Error
+		return errors.As(err, &postgresErr) && postgresErr.Code == postgresNoSuchTable
+	},
+	bind: store.Dollar,
+}
+
+// postgresLockKey is the number PostgreSQL identifies the advisory lock with
+func postgresLockKey() int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(lockName))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,144 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration is a versioned change of the database schema. Down reverts Up, and is empty
+// when the migration can not be rolled back
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up, so the migrations edited after being applied are noticed
+	Checksum string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads the migrations of a directory, sorted by version. Their files are named
+// VERSION_NAME.up.sql and VERSION_NAME.down.sql, e.g. 0002_money_minor_units.up.sql, and
+// every version must have an up file
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: the name must be VERSION_NAME.up.sql or VERSION_NAME.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: the version must be a positive number", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", entry.Name(), version, m.Name)
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d %s: the up file is missing or empty", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// split splits a script into its statements, on the semicolons that are not quoted nor
+// commented. The statements left with nothing but comments are dropped
+func split(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		content    bool
+	)
+	flush := func() {
+		if content {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		content = false
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for ; end < len(script) && script[end] != c; end++ {
+				if script[end] == '\\' && c != '`' {
+					end++
+				}
+			}
+			end = min(end, len(script)-1)
+			current.WriteString(script[i : end+1])
+			content = true
+			i = end
+		case c == '#' || strings.HasPrefix(script[i:], "-- ") || strings.HasPrefix(script[i:], "--\n"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i - 1
+			}
+			current.WriteString(script[i : i+end+1])
+			i += end
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i - 4
+			}
+			current.WriteString(script[i : i+end+4])
+			i += end + 3
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				content = true
+			}
+		}
+	}
+	flush()
+	return statements
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,83 @@
+package migrate
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lucaspichi06/xepelin-bank/migrations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("load migrations", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0002_add_alerts.up.sql":        {Data: []byte("CREATE TABLE alerts (id INT);")},
+			"0001_create_accounts.up.sql":   {Data: []byte("CREATE TABLE accounts (id INT);")},
+			"0001_create_accounts.down.sql": {Data: []byte("DROP TABLE accounts;")},
+			"README.md":                     {Data: []byte("not a migration")},
+		}
+
+		loaded, err := Load(fsys)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "create_accounts", Up: "CREATE TABLE accounts (id INT);", Down: "DROP TABLE accounts;",
+				Checksum: "55a5458fa78e895c06c08e798758aaae1f2bd04b8b4fc93ebf6fa7a1d4158546"},
+			{Version: 2, Name: "add_alerts", Up: "CREATE TABLE alerts (id INT);",
+				Checksum: "3bae90a853e5ba86aea7924ceb0500dabfc7076cd8ce07090143651e815d400f"},
+		}, loaded)
+	})
+	t.Run("reject invalid names", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"create_accounts.sql": {Data: []byte("SELECT 1;")}})
+
+		assert.EqualError(t, err, "migration create_accounts.sql: the name must be VERSION_NAME.up.sql or VERSION_NAME.down.sql")
+	})
+	t.Run("reject duplicated versions", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"0001_create_accounts.up.sql": {Data: []byte("SELECT 1;")},
+			"0001_create_alerts.up.sql":   {Data: []byte("SELECT 1;")},
+		})
+
+		assert.EqualError(t, err, "migration 0001_create_alerts.up.sql: version 1 is already used by create_accounts")
+	})
+	t.Run("reject missing up file", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"0001_create_accounts.down.sql": {Data: []byte("DROP TABLE accounts;")}})
+
+		assert.EqualError(t, err, "migration 1 create_accounts: the up file is missing or empty")
+	})
+	t.Run("load embedded migrations", func(t *testing.T) {
+		for name, fsys := range map[string]fs.FS{"mysql": migrations.MySQL, "postgres": migrations.Postgres, "sqlite": migrations.SQLite} {
+			loaded, err := Load(fsys)
+
+			assert.NoError(t, err, name)
+			assert.NotEmpty(t, loaded, name)
+			for i, m := range loaded {
+				assert.Equal(t, int64(i+1), m.Version, "the versions are consecutive")
+				assert.NotEmpty(t, split(m.Up), m.Name)
+				assert.NotEmpty(t, split(m.Down), m.Name)
+			}
+		}
+	})
+}
+
+func TestSplit(t *testing.T) {
+	script := `-- Creates the accounts; and their alerts.
+
+CREATE TABLE accounts (
+    id INT NOT NULL, -- the id; unique
+    name VARCHAR(45) DEFAULT 'a;b'
+);
+/* a block; comment */
+INSERT INTO accounts (name) VALUES ("it\"s; quoted"), ('it''s');
+UPDATE ` + "`weird;name`" + ` SET a = 1 # trailing; comment
+;
+-- only a comment;
+`
+
+	assert.Equal(t, []string{
+		"-- Creates the accounts; and their alerts.\n\nCREATE TABLE accounts (\n    id INT NOT NULL, -- the id; unique\n    name VARCHAR(45) DEFAULT 'a;b'\n)",
+		"/* a block; comment */\nINSERT INTO accounts (name) VALUES (\"it\\\"s; quoted\"), ('it''s')",
+		"UPDATE `weird;name` SET a = 1 # trailing; comment",
+	}, split(script))
+}
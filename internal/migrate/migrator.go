@@ -0,0 +1,341 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+)
+
+// DefaultLockTimeout is the time an instance waits for another one to finish migrating
+const DefaultLockTimeout = 10 * time.Minute
+
+// Status is the state of a migration in the database
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Dirty tells the migration failed midway, so the database has to be repaired by hand
+	Dirty bool
+	// Modified tells the migration changed after it was applied
+	Modified bool
+	// Unknown tells the migration was applied by a newer version of the server
+	Unknown bool
+}
+
+// Migrator applies the migrations to the database, recording them in the schema_migrations
+// table. MySQL commits every schema change right away, so a migration that fails midway
+// is left dirty instead of being rolled back, on every database
+type Migrator interface {
+	// Up applies the pending migrations in order, returning the ones applied
+	Up(ctx context.Context) ([]Migration, error)
+	// Down rolls back the last n migrations applied, returning the ones rolled back
+	Down(ctx context.Context, n int) ([]Migration, error)
+	// Force records the migrations up to the version as applied and the later ones as not
+	// applied, without running them. It adopts the databases migrated by hand and clears
+	// the dirty migrations once they are repaired
+	Force(ctx context.Context, version int64) error
+	// Status returns the state of every migration, sorted by version
+	Status(ctx context.Context) ([]Status, error)
+	// Verify checks every migration is applied, none of them is dirty and none changed
+	Verify(ctx context.Context) error
+}
+
+type migrator struct {
+	db          *sql.DB
+	dialect     Dialect
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+func NewMigrator(db *sql.DB, dialect Dialect, migrations []Migration, lockTimeout time.Duration) Migrator {
+	return &migrator{
+		db:          db,
+		dialect:     dialect,
+		migrations:  migrations,
+		lockTimeout: lockTimeout,
+	}
+}
+
+type record struct {
+	version   int64
+	name      string
+	checksum  string
+	dirty     bool
+	appliedAt time.Time
+}
+
+func (m *migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, records map[int64]record) error {
+		if err := check(m.statuses(records)); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+func (m *migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if n <= 0 {
+		return nil, errors.New("the number of migrations to roll back has to be positive")
+	}
+
+	var rolledBack []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, records map[int64]record) error {
+		statuses := m.statuses(records)
+		if err := check(statuses); err != nil {
+			return err
+		}
+		for i := len(statuses) - 1; i >= 0 && len(rolledBack) < n; i-- {
+			status := statuses[i]
+			if !status.Applied {
+				continue
+			}
+			if status.Unknown {
+				return fmt.Errorf("migration %d %s was applied by a newer version of the server, which has to roll it back", status.Version, status.Name)
+			}
+			migration := m.migration(status.Version)
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+func (m *migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.migration(version).Version == 0 {
+		return fmt.Errorf("there is no migration %d", version)
+	}
+
+	return m.locked(ctx, func(conn *sql.Conn, records map[int64]record) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations;"); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			appliedAt := time.Now().UTC()
+			if r, ok := records[migration.Version]; ok {
+				appliedAt = r.appliedAt
+			}
+			if _, err := tx.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, FALSE, ?);"),
+				migration.Version, migration.Name, migration.Checksum, appliedAt); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+func (m *migrator) Status(ctx context.Context) ([]Status, error) {
+	records, err := m.read(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	return m.statuses(records), nil
+}
+
+// Verify tolerates the migrations applied by newer versions of the server, so the instances
+// of the previous version keep serving while a new one is rolled out
+func (m *migrator) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if err := check(statuses); err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, fmt.Sprintf("%d %s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("pending migrations %s, they have to be applied with migrate up", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// locked runs fn holding the migrations lock, on the connection that holds it, with the
+// migrations recorded in the database
+func (m *migrator) locked(ctx context.Context, fn func(conn *sql.Conn, records map[int64]record) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect.lock != nil {
+		if err := m.dialect.lock(ctx, conn, m.lockTimeout); err != nil {
+			return err
+		}
+		defer func() {
+			if err := m.dialect.unlock(ctx, conn); err != nil {
+				logger.FromContext(ctx).Warn("the migrations lock could not be released", "error", err)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return err
+	}
+	records, err := m.read(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, records)
+}
+
+// apply runs the statements of the migration, recording it as dirty until all of them succeed
+func (m *migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	start := time.Now()
+	if _, err := conn.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, TRUE, ?);"),
+		migration.Version, migration.Name, migration.Checksum, start.UTC()); err != nil {
+		return err
+	}
+	if err := run(ctx, conn, migration, migration.Up); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, m.bind("UPDATE schema_migrations SET dirty = FALSE WHERE version = ?;"), migration.Version); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Info("migration applied", "version", migration.Version, "name", migration.Name, "duration", time.Since(start))
+	return nil
+}
+
+// revert runs the down statements of the migration, recording it as dirty until all of them
+// succeed, and then forgets it
+func (m *migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if strings.TrimSpace(migration.Down) == "" {
+		return fmt.Errorf("migration %d %s can not be rolled back, it has no down file", migration.Version, migration.Name)
+	}
+
+	start := time.Now()
+	if _, err := conn.ExecContext(ctx, m.bind("UPDATE schema_migrations SET dirty = TRUE WHERE version = ?;"), migration.Version); err != nil {
+		return err
+	}
+	if err := run(ctx, conn, migration, migration.Down); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, m.bind("DELETE FROM schema_migrations WHERE version = ?;"), migration.Version); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Info("migration rolled back", "version", migration.Version, "name", migration.Name, "duration", time.Since(start))
+	return nil
+}
+
+// bind writes the placeholders of the query as the database expects them
+func (m *migrator) bind(query string) string {
+	if m.dialect.bind == nil {
+		return query
+	}
+	return m.dialect.bind(query)
+}
+
+func run(ctx context.Context, conn *sql.Conn, migration Migration, script string) error {
+	for i, statement := range split(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d %s, statement %d: %w", migration.Version, migration.Name, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (m *migrator) migration(version int64) Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return Migration{}
+}
+
+// statuses merges the migrations with the ones recorded in the database
+func (m *migrator) statuses(records map[int64]record) []Status {
+	statuses := make([]Status, 0, len(m.migrations))
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if r, ok := records[migration.Version]; ok {
+			status.Applied, status.AppliedAt, status.Dirty = true, r.appliedAt, r.dirty
+			status.Modified = r.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	for _, r := range records {
+		if !known[r.version] {
+			statuses = append(statuses, Status{Version: r.version, Name: r.name, Applied: true, AppliedAt: r.appliedAt, Dirty: r.dirty, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+// check fails when a migration is dirty or was modified, as the schema is not the one the
+// migrations describe
+func check(statuses []Status) error {
+	for _, status := range statuses {
+		if status.Dirty {
+			return fmt.Errorf("migration %d %s failed midway, the database has to be repaired by hand and its state recorded with migrate force", status.Version, status.Name)
+		}
+		if status.Modified {
+			return fmt.Errorf("migration %d %s was modified after it was applied", status.Version, status.Name)
+		}
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// read returns the migrations recorded in the database, none when the table was not created yet
+func (m *migrator) read(ctx context.Context, db queryer) (map[int64]record, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, dirty, applied_at FROM schema_migrations;")
+	if m.dialect.noSuchTable(err) {
+		return map[int64]record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := map[int64]record{}
+	for rows.Next() {
+		var r record
+		if err := rows.Scan(&r.version, &r.name, &r.checksum, &r.dirty, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		records[r.version] = r
+	}
+	return records, rows.Err()
+}
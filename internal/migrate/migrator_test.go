@@ -0,0 +1,361 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lucaspichi06/xepelin-bank/migrations"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var appliedAt = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testMigrations(t *testing.T) []Migration {
+	migrations, err := Load(fstest.MapFS{
+		"0001_create_accounts.up.sql":   {Data: []byte("CREATE TABLE accounts (id INT);")},
+		"0001_create_accounts.down.sql": {Data: []byte("DROP TABLE accounts;")},
+		"0002_create_alerts.up.sql":     {Data: []byte("CREATE TABLE alerts (id INT);\nCREATE INDEX idx_alerts ON alerts (id);")},
+		"0002_create_alerts.down.sql":   {Data: []byte("DROP TABLE alerts;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations
+}
+
+var recordColumns = []string{"version", "name", "checksum", "dirty", "applied_at"}
+
+// expectLocked expects the lock to be acquired and the migrations table to be read
+func expectLocked(mock sqlmock.Sqlmock, records *sqlmock.Rows) {
+	mock.ExpectQuery("SELECT GET_LOCK").WithArgs(lockName, int64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, name, checksum, dirty, applied_at FROM schema_migrations").WillReturnRows(records)
+}
+
+func expectUnlocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT RELEASE_LOCK").WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestUp(t *testing.T) {
+	migrations := testMigrations(t)
+
+	t.Run("apply pending migrations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		expectLocked(mock, sqlmock.NewRows(recordColumns).AddRow(1, "create_accounts", migrations[0].Checksum, false, appliedAt))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(2, "create_alerts", migrations[1].Checksum, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("CREATE TABLE alerts").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE INDEX idx_alerts").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE schema_migrations SET dirty = FALSE").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectUnlocked(mock)
+
+		applied, err := NewMigrator(db, MySQL, migrations, time.Minute).Up(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, migrations[1:], applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("leave failed migration dirty", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		expectLocked(mock, sqlmock.NewRows(recordColumns).AddRow(1, "create_accounts", migrations[0].Checksum, false, appliedAt))
+		mock.ExpectExec("INSERT INTO schema_migrations").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("CREATE TABLE alerts").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE INDEX idx_alerts").WillReturnError(errors.New("duplicate key name"))
+		expectUnlocked(mock)
+
+		applied, err := NewMigrator(db, MySQL, migrations, time.Minute).Up(context.Background())
+
+		assert.EqualError(t, err, "migration 2 create_alerts, statement 2: duplicate key name")
+		assert.Empty(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("refuse dirty migration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		expectLocked(mock, sqlmock.NewRows(recordColumns).AddRow(1, "create_accounts", migrations[0].Checksum, true, appliedAt))
+		expectUnlocked(mock)
+
+		_, err = NewMigrator(db, MySQL, migrations, time.Minute).Up(context.Background())
+
+		assert.EqualError(t, err, "migration 1 create_accounts failed midway, the database has to be repaired by hand and its state recorded with migrate force")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("refuse modified migration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		expectLocked(mock, sqlmock.NewRows(recordColumns).AddRow(1, "create_accounts", "another checksum", false, appliedAt))
+		expectUnlocked(mock)
+
+		_, err = NewMigrator(db, MySQL, migrations, time.Minute).Up(context.Background())
+
+		assert.EqualError(t, err, "migration 1 create_accounts was modified after it was applied")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("lock taken by another instance", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		mock.ExpectQuery("SELECT GET_LOCK").WithArgs(lockName, int64(60)).
+			WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(0))
+
+		_, err = NewMigrator(db, MySQL, migrations, time.Minute).Up(context.Background())
+
+		assert.EqualError(t, err, "the migrations lock was not acquired in 1m0s, another instance is migrating the database")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDown(t *testing.T) {
+	migrations := testMigrations(t)
+
+	t.Run("roll back last migration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		expectLocked(mock, sqlmock.NewRows(recordColumns).
+			AddRow(1, "create_accounts", migrations[0].Checksum, false, appliedAt).
+			AddRow(2, "create_alerts", migrations[1].Checksum, false, appliedAt))
+		mock.ExpectExec("UPDATE schema_migrations SET dirty = TRUE").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DROP TABLE alerts").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM schema_migrations WHERE version").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectUnlocked(mock)
+
+		rolledBack, err := NewMigrator(db, MySQL, migrations, time.Minute).Down(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, migrations[1:], rolledBack)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("refuse migration of a newer version", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		expectLocked(mock, sqlmock.NewRows(recordColumns).
+			AddRow(1, "create_accounts", migrations[0].Checksum, false, appliedAt).
+			AddRow(2, "create_alerts", migrations[1].Checksum, false, appliedAt).
+			AddRow(3, "create_limits", "checksum", false, appliedAt))
+		expectUnlocked(mock)
+
+		_, err = NewMigrator(db, MySQL, migrations, time.Minute).Down(context.Background(), 1)
+
+		assert.EqualError(t, err, "migration 3 create_limits was applied by a newer version of the server, which has to roll it back")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("refuse migration without down file", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		irreversible := []Migration{{Version: 1, Name: "create_accounts", Up: "CREATE TABLE accounts (id INT);", Checksum: "checksum"}}
+		expectLocked(mock, sqlmock.NewRows(recordColumns).AddRow(1, "create_accounts", "checksum", false, appliedAt))
+		expectUnlocked(mock)
+
+		_, err = NewMigrator(db, MySQL, irreversible, time.Minute).Down(context.Background(), 1)
+
+		assert.EqualError(t, err, "migration 1 create_accounts can not be rolled back, it has no down file")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("refuse non positive count", func(t *testing.T) {
+		_, err := NewMigrator(nil, MySQL, migrations, time.Minute).Down(context.Background(), 0)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestForce(t *testing.T) {
+	migrations := testMigrations(t)
+
+	t.Run("record migrations up to version", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		expectLocked(mock, sqlmock.NewRows(recordColumns).
+			AddRow(1, "create_accounts", "old checksum", false, appliedAt).
+			AddRow(2, "create_alerts", migrations[1].Checksum, true, appliedAt))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM schema_migrations").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(1, "create_accounts", migrations[0].Checksum, appliedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectUnlocked(mock)
+
+		err = NewMigrator(db, MySQL, migrations, time.Minute).Force(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("refuse unknown version", func(t *testing.T) {
+		err := NewMigrator(nil, MySQL, migrations, time.Minute).Force(context.Background(), 3)
+
+		assert.EqualError(t, err, "there is no migration 3")
+	})
+}
+
+func TestVerify(t *testing.T) {
+	migrations := testMigrations(t)
+
+	t.Run("every migration applied", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		mock.ExpectQuery("SELECT version, name, checksum, dirty, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows(recordColumns).
+				AddRow(1, "create_accounts", migrations[0].Checksum, false, appliedAt).
+				AddRow(2, "create_alerts", migrations[1].Checksum, false, appliedAt).
+				AddRow(3, "create_limits", "checksum", false, appliedAt))
+
+		assert.NoError(t, NewMigrator(db, MySQL, migrations, time.Minute).Verify(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("pending migrations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		mock.ExpectQuery("SELECT version, name, checksum, dirty, applied_at FROM schema_migrations").
+			WillReturnError(&mysql.MySQLError{Number: mysqlNoSuchTable, Message: "Table 'my_db.schema_migrations' doesn't exist"})
+
+		err = NewMigrator(db, MySQL, migrations, time.Minute).Verify(context.Background())
+
+		assert.EqualError(t, err, "pending migrations 1 create_accounts, 2 create_alerts, they have to be applied with migrate up")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStatus(t *testing.T) {
+	migrations := testMigrations(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fail()
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT version, name, checksum, dirty, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows(recordColumns).
+			AddRow(3, "create_limits", "checksum", false, appliedAt).
+			AddRow(1, "create_accounts", "old checksum", false, appliedAt))
+
+	statuses, err := NewMigrator(db, MySQL, migrations, time.Minute).Status(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Status{
+		{Version: 1, Name: "create_accounts", Applied: true, AppliedAt: appliedAt, Modified: true},
+		{Version: 2, Name: "create_alerts"},
+		{Version: 3, Name: "create_limits", Applied: true, AppliedAt: appliedAt, Unknown: true},
+	}, statuses)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres(t *testing.T) {
+	migrations := testMigrations(t)
+
+	t.Run("apply pending migrations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		mock.ExpectQuery("SELECT pg_try_advisory_lock\\(\\$1\\)").WithArgs(postgresLockKey()).
+			WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT version, name, checksum, dirty, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows(recordColumns).AddRow(1, "create_accounts", migrations[0].Checksum, false, appliedAt))
+		mock.ExpectExec("INSERT INTO schema_migrations \\(version, name, checksum, dirty, applied_at\\) VALUES \\(\\$1, \\$2, \\$3, TRUE, \\$4\\)").
+			WithArgs(2, "create_alerts", migrations[1].Checksum, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("CREATE TABLE alerts").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE INDEX idx_alerts").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE schema_migrations SET dirty = FALSE WHERE version = \\$1").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("SELECT pg_advisory_unlock\\(\\$1\\)").WithArgs(postgresLockKey()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		applied, err := NewMigrator(db, Postgres, migrations, time.Minute).Up(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, migrations[1:], applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("give up on the lock after the timeout", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(postgresLockKey()).
+			WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(false))
+
+		applied, err := NewMigrator(db, Postgres, migrations, 100*time.Millisecond).Up(context.Background())
+
+		assert.EqualError(t, err, "the migrations lock was not acquired in 100ms, another instance is migrating the database")
+		assert.Empty(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "bank.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	schema, err := Load(migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := NewMigrator(db, SQLite, schema, time.Minute)
+	ctx := context.Background()
+
+	assert.Error(t, migrator.Verify(ctx))
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, schema, applied)
+	assert.NoError(t, migrator.Verify(ctx))
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, s.Name)
+		assert.False(t, s.Dirty, s.Name)
+		assert.False(t, s.AppliedAt.IsZero(), s.Name)
+	}
+
+	rolledBack, err := migrator.Down(ctx, len(schema))
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, len(schema))
+	var tables int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name <> 'schema_migrations';").Scan(&tables))
+	assert.Zero(t, tables)
+}
@@ -0,0 +1,111 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	"github.com/lucaspichi06/xepelin-bank/internal/transaction"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// backend is a storage backend of the alerts and of the transactions they read
+type backend struct {
+	accounts     account.Repository
+	transactions transaction.Repository
+	alerts       Repository
+}
+
+// eachBackend runs the test on every storage backend, so they all behave the same. MySQL
+// and PostgreSQL only run when storetest.MySQLEnv and storetest.PostgresEnv are set
+func eachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	t.Run("memory", func(t *testing.T) {
+		transactions := transaction.NewMemoryRepository()
+		test(t, backend{account.NewMemoryRepository(), transactions, NewMemoryRepository(transactions)})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db := storetest.SQLite(t)
+		test(t, backend{account.NewSQLiteRepository(db), transaction.NewRepository(db), NewRepository(db)})
+	})
+	t.Run("mysql", func(t *testing.T) {
+		db := storetest.MySQL(t)
+		test(t, backend{account.NewRepository(db), transaction.NewRepository(db), NewRepository(db)})
+	})
+	t.Run("postgres", func(t *testing.T) {
+		db := storetest.Postgres(t)
+		test(t, backend{account.NewPostgresRepository(db), transaction.NewPostgresRepository(db), NewRepository(store.Postgres(db))})
+	})
+}
+
+func TestRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	eachBackend(t, func(t *testing.T, b backend) {
+		sender, receiver := uuid.New(), uuid.New()
+		for _, id := range []uuid.UUID{sender, receiver} {
+			assert.NoError(t, b.accounts.Create(ctx, domain.Account{ID: id, Balance: domain.NewMoney(0, "USD")}))
+		}
+
+		t.Run("activity of the transactions sent", func(t *testing.T) {
+			for _, tr := range []domain.Transaction{
+				{ID: uuid.New(), AccountID: sender, Type: domain.Deposit, Amount: domain.NewMoney(1000, "USD"), Timestamp: now.Add(-time.Hour)},
+				{ID: uuid.New(), AccountID: sender, Type: domain.WithDraw, Amount: domain.NewMoney(300, "USD"), Timestamp: now},
+				{ID: uuid.New(), AccountID: sender, DestinationID: &receiver, Type: domain.Transfer, Amount: domain.NewMoney(200, "USD"), Timestamp: now.Add(time.Minute)},
+			} {
+				tr := tr
+				assert.NoError(t, b.transactions.Create(ctx, &tr))
+			}
+
+			activity, err := b.alerts.Activity(ctx, sender, nil, now)
+			assert.NoError(t, err)
+			assert.Equal(t, Activity{Count: 2, Total: domain.NewMoney(500, "USD")}, activity)
+
+			activity, err = b.alerts.Activity(ctx, sender, []domain.EventType{domain.Transfer, domain.Deposit}, now.Add(-2*time.Hour))
+			assert.NoError(t, err)
+			assert.Equal(t, Activity{Count: 2, Total: domain.NewMoney(1200, "USD")}, activity)
+
+			activity, err = b.alerts.Activity(ctx, receiver, nil, now)
+			assert.NoError(t, err)
+			assert.Equal(t, Activity{}, activity, "transfers received are not sent")
+		})
+		t.Run("alerts newest first", func(t *testing.T) {
+			alert := func(rule string, accountID uuid.UUID, createdAt time.Time) domain.Alert {
+				return domain.Alert{ID: uuid.New(), Rule: rule, AccountID: accountID, TransactionID: uuid.New(), Type: domain.Deposit,
+					Amount: domain.NewMoney(100, "USD"), Details: "details", CreatedAt: createdAt}
+			}
+			oldest, middle, newest := alert("large", sender, now), alert("velocity", sender, now.Add(time.Minute)), alert("large", receiver, now.Add(time.Hour))
+			for _, a := range []domain.Alert{middle, newest, oldest} {
+				assert.NoError(t, b.alerts.Save(ctx, a))
+			}
+
+			page, err := b.alerts.List(ctx, domain.AlertFilter{})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Alert{newest, middle, oldest}, page.Alerts)
+			assert.Empty(t, page.NextCursor)
+
+			page, err = b.alerts.List(ctx, domain.AlertFilter{AccountID: &sender, Limit: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Alert{middle}, page.Alerts)
+
+			page, err = b.alerts.List(ctx, domain.AlertFilter{AccountID: &sender, Cursor: page.NextCursor, Limit: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Alert{oldest}, page.Alerts)
+			assert.Empty(t, page.NextCursor)
+
+			from, to := now, now.Add(time.Hour)
+			page, err = b.alerts.List(ctx, domain.AlertFilter{Rule: "large", From: &from, To: &to})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Alert{oldest}, page.Alerts)
+
+			_, err = b.alerts.List(ctx, domain.AlertFilter{Cursor: "bad"})
+			assert.ErrorIs(t, err, custom_errors.ErrInvalidCursor)
+		})
+	})
+}
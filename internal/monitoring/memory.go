@@ -0,0 +1,140 @@
+package monitoring
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// TransactionLister lists the transactions of an account between two times, as the
+// transaction repository does
+type TransactionLister interface {
+	ListBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]domain.Transaction, error)
+}
+
+// endOfTime is after every transaction, so ListBetween reads them all from a time on
+var endOfTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+type memoryRepository struct {
+	mu           sync.RWMutex
+	alerts       []domain.Alert
+	transactions TransactionLister
+}
+
+// NewMemoryRepository keeps the alerts in memory. The activity of the accounts is read
+// from the transactions
+func NewMemoryRepository(transactions TransactionLister) Repository {
+	return &memoryRepository{
+		transactions: transactions,
+	}
+}
+
+func (r *memoryRepository) Activity(ctx context.Context, accountID uuid.UUID, types []domain.EventType, since time.Time) (Activity, error) {
+	transactions, err := r.transactions.ListBetween(ctx, accountID, since, endOfTime)
+	if err != nil {
+		return Activity{}, err
+	}
+
+	var a Activity
+	for _, tr := range transactions {
+		if tr.AccountID != accountID || (len(types) > 0 && !hasType(types, tr.Type)) {
+			continue
+		}
+		a.Count++
+		a.Total.Amount += tr.Amount.Amount
+		if tr.Amount.Currency > a.Total.Currency {
+			a.Total.Currency = tr.Amount.Currency
+		}
+	}
+	return a, nil
+}
+
+func hasType(types []domain.EventType, t domain.EventType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRepository) Save(ctx context.Context, alert domain.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, a := range r.alerts {
+		if a.ID == alert.ID {
+			return store.ErrDuplicate
+		}
+	}
+	alert.CreatedAt = alert.CreatedAt.UTC()
+	r.alerts = append(r.alerts, alert)
+
+	store.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i := range r.alerts {
+			if r.alerts[i].ID == alert.ID {
+				r.alerts = append(r.alerts[:i:i], r.alerts[i+1:]...)
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (r *memoryRepository) List(_ context.Context, filter domain.AlertFilter) (domain.AlertPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	var last *domain.Alert
+	if filter.Cursor != "" {
+		c, err := store.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return domain.AlertPage{}, err
+		}
+		last = &domain.Alert{ID: c.ID, CreatedAt: c.Time}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page := domain.AlertPage{Alerts: []domain.Alert{}}
+	for _, a := range r.alerts {
+		switch {
+		case filter.AccountID != nil && a.AccountID != *filter.AccountID,
+			filter.Rule != "" && a.Rule != filter.Rule,
+			filter.From != nil && a.CreatedAt.Before(*filter.From),
+			filter.To != nil && !a.CreatedAt.Before(*filter.To),
+			last != nil && !sortsBefore(a, *last):
+			continue
+		}
+		page.Alerts = append(page.Alerts, a)
+	}
+	sort.Slice(page.Alerts, func(i, j int) bool { return sortsBefore(page.Alerts[j], page.Alerts[i]) })
+
+	if len(page.Alerts) > limit {
+		page.Alerts = page.Alerts[:limit]
+		page.NextCursor = store.EncodeCursor(page.Alerts[limit-1].CreatedAt, page.Alerts[limit-1].ID)
+	}
+	return page, nil
+}
+
+// sortsBefore reports whether a is older than b, by creation time and then by id as the
+// database sorts them
+func sortsBefore(a, b domain.Alert) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
@@ -0,0 +1,133 @@
+package monitoring
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// Alert pages are limited to these sizes
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
+
+// Repository stores the alerts and reads the account activity the velocity rules need
+type Repository interface {
+	ActivitySource
+	Save(ctx context.Context, alert domain.Alert) error
+	List(ctx context.Context, filter domain.AlertFilter) (domain.AlertPage, error)
+}
+
+type repository struct {
+	db store.DBTX
+}
+
+func NewRepository(db store.DBTX) Repository {
+	return &repository{
+		db: store.Instrument(db, store.SystemOf(db), "alerts"),
+	}
+}
+
+// Activity adds up the transactions sent by the account. Their amounts are in the
+// currency of the account
+func (r repository) Activity(ctx context.Context, accountID uuid.UUID, types []domain.EventType, since time.Time) (Activity, error) {
+	query := "SELECT COUNT(*), COALESCE(SUM(amount), 0), COALESCE(MAX(currency), '') FROM transactions WHERE account_id = ? AND timestamp >= ?"
+	args := []interface{}{accountID, since.UTC()}
+	if len(types) > 0 {
+		placeholders := make([]string, len(types))
+		for i, t := range types {
+			placeholders[i] = "?"
+			args = append(args, t)
+		}
+		query += " AND type IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	var a Activity
+	err := r.db.QueryRowContext(ctx, query+";", args...).Scan(&a.Count, &a.Total.Amount, &a.Total.Currency)
+	return a, err
+}
+
+func (r repository) Save(ctx context.Context, alert domain.Alert) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO alerts (id, rule, account_id, transaction_id, type, amount, currency, details, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);",
+		alert.ID, alert.Rule, alert.AccountID, alert.TransactionID, alert.Type, alert.Amount.Amount, alert.Amount.Currency, alert.Details, alert.CreatedAt)
+	return err
+}
+
+// List returns a page of the alerts, newest first
+func (r repository) List(ctx context.Context, filter domain.AlertFilter) (domain.AlertPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if filter.AccountID != nil {
+		conditions = append(conditions, "account_id = ?")
+		args = append(args, *filter.AccountID)
+	}
+	if filter.Rule != "" {
+		conditions = append(conditions, "rule = ?")
+		args = append(args, filter.Rule)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.Cursor != "" {
+		c, err := store.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return domain.AlertPage{}, err
+		}
+		conditions = append(conditions, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, c.Time, c.Time, c.ID)
+	}
+	// one extra row tells whether there is a next page
+	args = append(args, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT id, rule, account_id, transaction_id, type, amount, currency, details, created_at FROM alerts WHERE "+
+		strings.Join(conditions, " AND ")+" ORDER BY created_at DESC, id DESC LIMIT ?;", args...)
+	if err != nil {
+		return domain.AlertPage{}, err
+	}
+	defer rows.Close()
+
+	page := domain.AlertPage{Alerts: []domain.Alert{}}
+	for rows.Next() {
+		var a domain.Alert
+		if err = scanAlert(rows, &a); err != nil {
+			return domain.AlertPage{}, err
+		}
+		page.Alerts = append(page.Alerts, a)
+	}
+	if err = rows.Err(); err != nil {
+		return domain.AlertPage{}, err
+	}
+
+	if len(page.Alerts) > limit {
+		page.Alerts = page.Alerts[:limit]
+		page.NextCursor = store.EncodeCursor(page.Alerts[limit-1].CreatedAt, page.Alerts[limit-1].ID)
+	}
+	return page, nil
+}
+
+func scanAlert(rows *sql.Rows, a *domain.Alert) error {
+	if err := rows.Scan(&a.ID, &a.Rule, &a.AccountID, &a.TransactionID, &a.Type, &a.Amount.Amount, &a.Amount.Currency, &a.Details, &a.CreatedAt); err != nil {
+		return err
+	}
+	a.CreatedAt = a.CreatedAt.UTC()
+	return nil
+}
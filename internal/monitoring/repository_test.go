@@ -0,0 +1,179 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivity(t *testing.T) {
+	accountID := uuid.New()
+	since := time.Date(2023, 1, 1, 11, 0, 0, 0, time.UTC)
+
+	t.Run("activity of every type", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(SUM\(amount\), 0\), COALESCE\(MAX\(currency\), ''\) FROM transactions WHERE account_id = \? AND timestamp >= \?;`).
+			WithArgs(accountID, since).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "total", "currency"}).AddRow(3, 4500, "USD"))
+
+		a, err := NewRepository(db).Activity(context.Background(), accountID, nil, since)
+		assert.NoError(t, err)
+		assert.Equal(t, Activity{Count: 3, Total: domain.NewMoney(4500, "USD")}, a)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("activity by type", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(`AND type IN \(\?, \?\);`).
+			WithArgs(accountID, since, domain.WithDraw, domain.Transfer).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "total", "currency"}).AddRow(0, 0, ""))
+
+		a, err := NewRepository(db).Activity(context.Background(), accountID, []domain.EventType{domain.WithDraw, domain.Transfer}, since)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, a.Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSaveAlert(t *testing.T) {
+	alert := domain.Alert{ID: uuid.New(), Rule: "large_deposit", AccountID: uuid.New(), TransactionID: uuid.New(), Type: domain.Deposit,
+		Amount: domain.NewMoney(2000000, "USD"), Details: "details", CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("save success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("INSERT INTO alerts").
+			WithArgs(alert.ID, alert.Rule, alert.AccountID, alert.TransactionID, alert.Type, int64(2000000), "USD", alert.Details, alert.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, NewRepository(db).Save(context.Background(), alert))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("save error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("INSERT INTO alerts").WillReturnError(errors.New("test error"))
+
+		assert.Error(t, NewRepository(db).Save(context.Background(), alert))
+	})
+}
+
+func TestListAlertsRepository(t *testing.T) {
+	columns := []string{"id", "rule", "account_id", "transaction_id", "type", "amount", "currency", "details", "created_at"}
+	accountID := uuid.New()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("list filtered", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		id := uuid.New()
+		mock.ExpectQuery(`FROM alerts WHERE 1 = 1 AND account_id = \? AND rule = \? AND created_at >= \? ORDER BY created_at DESC, id DESC LIMIT \?;`).
+			WithArgs(accountID, "large_deposit", from, 11).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(id, "large_deposit", accountID, uuid.New(), "deposit", 2000000, "USD", "details", from))
+
+		page, err := NewRepository(db).List(context.Background(), domain.AlertFilter{AccountID: &accountID, Rule: "large_deposit", From: &from, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, page.Alerts, 1)
+		assert.Equal(t, id, page.Alerts[0].ID)
+		assert.Equal(t, domain.NewMoney(2000000, "USD"), page.Alerts[0].Amount)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list pages with the cursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		first, second := uuid.New(), uuid.New()
+		mock.ExpectQuery(`FROM alerts WHERE 1 = 1 ORDER BY created_at DESC, id DESC LIMIT \?;`).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(first, "large_deposit", accountID, uuid.New(), "deposit", 2000000, "USD", "details", from.Add(time.Hour)).
+				AddRow(second, "large_deposit", accountID, uuid.New(), "deposit", 2000000, "USD", "details", from))
+
+		page, err := NewRepository(db).List(context.Background(), domain.AlertFilter{Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, page.Alerts, 1)
+		assert.Equal(t, first, page.Alerts[0].ID)
+		assert.NotEmpty(t, page.NextCursor)
+
+		mock.ExpectQuery(`FROM alerts WHERE 1 = 1 AND \(created_at < \? OR \(created_at = \? AND id < \?\)\) ORDER BY created_at DESC, id DESC LIMIT \?;`).
+			WithArgs(from.Add(time.Hour), from.Add(time.Hour), first, 2).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(second, "large_deposit", accountID, uuid.New(), "deposit", 2000000, "USD", "details", from))
+
+		page, err = NewRepository(db).List(context.Background(), domain.AlertFilter{Cursor: page.NextCursor, Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, page.Alerts, 1)
+		assert.Equal(t, second, page.Alerts[0].ID)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list invalid cursor", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		_, err = NewRepository(db).List(context.Background(), domain.AlertFilter{Cursor: "bad"})
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidCursor)
+	})
+	t.Run("list default page size", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM alerts WHERE 1 = 1 ORDER BY`).WithArgs(DefaultPageSize + 1).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		page, err := NewRepository(db).List(context.Background(), domain.AlertFilter{})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Alerts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("FROM alerts").WillReturnError(errors.New("test error"))
+
+		_, err = NewRepository(db).List(context.Background(), domain.AlertFilter{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,191 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+)
+
+// Activity is what an account sent in a window of time
+type Activity struct {
+	Count int
+	Total domain.Money
+}
+
+// ActivitySource returns the activity of an account since a moment, for the transaction
+// types given or all of them
+type ActivitySource interface {
+	Activity(ctx context.Context, accountID uuid.UUID, types []domain.EventType, since time.Time) (Activity, error)
+}
+
+// Rule inspects a processed transaction. It returns the details of the alert to raise,
+// or an empty string when the transaction is fine
+type Rule interface {
+	Name() string
+	Evaluate(ctx context.Context, tr domain.Transaction, activity ActivitySource) (string, error)
+}
+
+// ThresholdRule matches the transactions of a type whose amount is greater than the
+// threshold. Only the transactions in the currency of the threshold are compared
+type ThresholdRule struct {
+	RuleName  string
+	Type      domain.EventType
+	Threshold domain.Money
+}
+
+func (r ThresholdRule) Name() string {
+	return r.RuleName
+}
+
+func (r ThresholdRule) Evaluate(ctx context.Context, tr domain.Transaction, _ ActivitySource) (string, error) {
+	if (r.Type != "" && tr.Type != r.Type) || tr.Amount.Currency != r.Threshold.Currency || !r.Threshold.LessThan(tr.Amount) {
+		return "", nil
+	}
+	return fmt.Sprintf("%s of %s %s greater than %s %s", tr.Type, tr.Amount, tr.Amount.Currency, r.Threshold, r.Threshold.Currency), nil
+}
+
+// VelocityRule matches the accounts sending more than MaxCount transactions, or more than
+// MaxAmount in total, within the window. Zero limits are not checked
+type VelocityRule struct {
+	RuleName  string
+	Types     []domain.EventType
+	Window    time.Duration
+	MaxCount  int
+	MaxAmount *domain.Money
+}
+
+func (r VelocityRule) Name() string {
+	return r.RuleName
+}
+
+func (r VelocityRule) Evaluate(ctx context.Context, tr domain.Transaction, activity ActivitySource) (string, error) {
+	if len(r.Types) > 0 && !containsType(r.Types, tr.Type) {
+		return "", nil
+	}
+	if r.MaxAmount != nil && r.MaxAmount.Currency != tr.Amount.Currency {
+		return "", nil
+	}
+
+	a, err := activity.Activity(ctx, tr.AccountID, r.Types, tr.Timestamp.Add(-r.Window))
+	if err != nil {
+		return "", err
+	}
+	if r.MaxCount > 0 && a.Count > r.MaxCount {
+		return fmt.Sprintf("%d transactions in %s, more than %d", a.Count, r.Window, r.MaxCount), nil
+	}
+	if r.MaxAmount != nil && r.MaxAmount.LessThan(a.Total) {
+		return fmt.Sprintf("%s %s sent in %s, more than %s %s", a.Total, a.Total.Currency, r.Window, r.MaxAmount, r.MaxAmount.Currency), nil
+	}
+	return "", nil
+}
+
+func containsType(types []domain.EventType, t domain.EventType) bool {
+	for _, value := range types {
+		if value == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultLargeDeposit is the threshold of the default rules, $10,000
+var DefaultLargeDeposit = domain.NewMoney(1000000, domain.DefaultCurrency)
+
+// DefaultRules alert on the deposits greater than the threshold given
+func DefaultRules(largeDeposit domain.Money) []Rule {
+	return []Rule{
+		ThresholdRule{RuleName: "large_deposit", Type: domain.Deposit, Threshold: largeDeposit},
+	}
+}
+
+// rulesFile is the JSON configuration of the rules. Amounts are decimals in the currency
+// of the rule, USD when it is not set
+type rulesFile struct {
+	Thresholds []struct {
+		Name     string           `json:"name"`
+		Type     domain.EventType `json:"type"`
+		Amount   json.Number      `json:"amount"`
+		Currency string           `json:"currency"`
+	} `json:"thresholds"`
+	Velocity []struct {
+		Name      string             `json:"name"`
+		Types     []domain.EventType `json:"types"`
+		Window    string             `json:"window"`
+		MaxCount  int                `json:"max_count"`
+		MaxAmount json.Number        `json:"max_amount"`
+		Currency  string             `json:"currency"`
+	} `json:"velocity"`
+}
+
+// ParseRules reads the rules from their JSON configuration, e.g.
+// {"thresholds": [{"name": "large_withdraw", "type": "withdraw", "amount": "5000"}],
+// "velocity": [{"name": "burst", "window": "1h", "max_count": 10, "max_amount": "20000"}]}
+func ParseRules(data []byte) ([]Rule, error) {
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid monitoring rules: %w", err)
+	}
+
+	var rules []Rule
+	for _, t := range file.Thresholds {
+		if t.Name == "" || !validType(t.Type) {
+			return nil, fmt.Errorf("invalid monitoring rule %q: a name and a valid type are required", t.Name)
+		}
+		threshold, err := domain.ParseMoney(t.Amount.String(), currencyOrDefault(t.Currency))
+		if err != nil {
+			return nil, fmt.Errorf("invalid monitoring rule %q: %w", t.Name, err)
+		}
+		rules = append(rules, ThresholdRule{RuleName: t.Name, Type: t.Type, Threshold: threshold})
+	}
+	for _, v := range file.Velocity {
+		window, err := time.ParseDuration(v.Window)
+		if v.Name == "" || err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid monitoring rule %q: a name and a positive window are required", v.Name)
+		}
+		for _, t := range v.Types {
+			if t == "" || !validType(t) {
+				return nil, fmt.Errorf("invalid monitoring rule %q: invalid type %q", v.Name, t)
+			}
+		}
+		rule := VelocityRule{RuleName: v.Name, Types: v.Types, Window: window, MaxCount: v.MaxCount}
+		if v.MaxAmount != "" {
+			maxAmount, err := domain.ParseMoney(v.MaxAmount.String(), currencyOrDefault(v.Currency))
+			if err != nil {
+				return nil, fmt.Errorf("invalid monitoring rule %q: %w", v.Name, err)
+			}
+			rule.MaxAmount = &maxAmount
+		}
+		if rule.MaxCount <= 0 && rule.MaxAmount == nil {
+			return nil, fmt.Errorf("invalid monitoring rule %q: max_count or max_amount is required", v.Name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// LoadRules reads the rules from a JSON file
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
+
+// validType accepts the transaction types, and empty for any of them
+func validType(t domain.EventType) bool {
+	return t == "" || t == domain.Deposit || t == domain.WithDraw || t == domain.Transfer
+}
+
+func currencyOrDefault(currency string) string {
+	if currency == "" {
+		return domain.DefaultCurrency
+	}
+	return strings.ToUpper(currency)
+}
@@ -0,0 +1,127 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type activityMock struct {
+	activity func(accountID uuid.UUID, types []domain.EventType, since time.Time) (Activity, error)
+}
+
+func (a activityMock) Activity(ctx context.Context, accountID uuid.UUID, types []domain.EventType, since time.Time) (Activity, error) {
+	return a.activity(accountID, types, since)
+}
+
+func TestThresholdRule(t *testing.T) {
+	rule := ThresholdRule{RuleName: "large_deposit", Type: domain.Deposit, Threshold: domain.NewMoney(1000000, "USD")}
+
+	for _, tc := range []struct {
+		name  string
+		tr    domain.Transaction
+		alert bool
+	}{
+		{"greater than threshold", domain.Transaction{Type: domain.Deposit, Amount: domain.NewMoney(1000001, "USD")}, true},
+		{"equal to threshold", domain.Transaction{Type: domain.Deposit, Amount: domain.NewMoney(1000000, "USD")}, false},
+		{"other type", domain.Transaction{Type: domain.WithDraw, Amount: domain.NewMoney(2000000, "USD")}, false},
+		{"other currency", domain.Transaction{Type: domain.Deposit, Amount: domain.NewMoney(2000000, "EUR")}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			details, err := rule.Evaluate(context.Background(), tc.tr, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.alert, details != "", details)
+		})
+	}
+	t.Run("details", func(t *testing.T) {
+		details, _ := rule.Evaluate(context.Background(), domain.Transaction{Type: domain.Deposit, Amount: domain.NewMoney(1500000, "USD")}, nil)
+		assert.Equal(t, "deposit of 15000.00 USD greater than 10000.00 USD", details)
+	})
+}
+
+func TestVelocityRule(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	maxAmount := domain.NewMoney(500000, "USD")
+	rule := VelocityRule{RuleName: "burst", Types: []domain.EventType{domain.WithDraw, domain.Transfer}, Window: time.Hour, MaxCount: 3, MaxAmount: &maxAmount}
+	tr := domain.Transaction{AccountID: uuid.New(), Type: domain.WithDraw, Amount: domain.NewMoney(100, "USD"), Timestamp: now}
+
+	t.Run("within limits", func(t *testing.T) {
+		details, err := rule.Evaluate(context.Background(), tr, activityMock{
+			activity: func(accountID uuid.UUID, types []domain.EventType, since time.Time) (Activity, error) {
+				assert.Equal(t, tr.AccountID, accountID)
+				assert.Equal(t, rule.Types, types)
+				assert.Equal(t, now.Add(-time.Hour), since)
+				return Activity{Count: 3, Total: domain.NewMoney(500000, "USD")}, nil
+			},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, details)
+	})
+	t.Run("too many transactions", func(t *testing.T) {
+		details, err := rule.Evaluate(context.Background(), tr, activityMock{
+			activity: func(uuid.UUID, []domain.EventType, time.Time) (Activity, error) {
+				return Activity{Count: 4, Total: domain.NewMoney(400, "USD")}, nil
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "4 transactions in 1h0m0s, more than 3", details)
+	})
+	t.Run("too much amount", func(t *testing.T) {
+		details, err := rule.Evaluate(context.Background(), tr, activityMock{
+			activity: func(uuid.UUID, []domain.EventType, time.Time) (Activity, error) {
+				return Activity{Count: 2, Total: domain.NewMoney(500001, "USD")}, nil
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "5000.01 USD sent in 1h0m0s, more than 5000.00 USD", details)
+	})
+	t.Run("not checked type", func(t *testing.T) {
+		details, err := rule.Evaluate(context.Background(), domain.Transaction{Type: domain.Deposit, Amount: domain.NewMoney(100, "USD")}, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, details)
+	})
+	t.Run("activity error", func(t *testing.T) {
+		_, err := rule.Evaluate(context.Background(), tr, activityMock{
+			activity: func(uuid.UUID, []domain.EventType, time.Time) (Activity, error) {
+				return Activity{}, errors.New("test error")
+			},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestParseRules(t *testing.T) {
+	t.Run("parse rules", func(t *testing.T) {
+		rules, err := ParseRules([]byte(`{
+			"thresholds": [{"name": "large_withdraw", "type": "withdraw", "amount": "5000"}, {"name": "large_eur", "amount": 2500.5, "currency": "eur"}],
+			"velocity": [{"name": "burst", "types": ["transfer"], "window": "1h", "max_count": 10, "max_amount": "20000"}]
+		}`))
+		assert.NoError(t, err)
+
+		maxAmount := domain.NewMoney(2000000, "USD")
+		assert.Equal(t, []Rule{
+			ThresholdRule{RuleName: "large_withdraw", Type: domain.WithDraw, Threshold: domain.NewMoney(500000, "USD")},
+			ThresholdRule{RuleName: "large_eur", Threshold: domain.NewMoney(250050, "EUR")},
+			VelocityRule{RuleName: "burst", Types: []domain.EventType{domain.Transfer}, Window: time.Hour, MaxCount: 10, MaxAmount: &maxAmount},
+		}, rules)
+	})
+	t.Run("parse invalid rules", func(t *testing.T) {
+		for _, data := range []string{
+			`not json`,
+			`{"thresholds": [{"type": "deposit", "amount": "10"}]}`,
+			`{"thresholds": [{"name": "large", "type": "refund", "amount": "10"}]}`,
+			`{"thresholds": [{"name": "large", "amount": "10.001"}]}`,
+			`{"velocity": [{"name": "burst", "max_count": 10}]}`,
+			`{"velocity": [{"name": "burst", "window": "1h"}]}`,
+			`{"velocity": [{"name": "burst", "window": "1h", "types": ["refund"], "max_count": 10}]}`,
+		} {
+			_, err := ParseRules([]byte(data))
+			assert.Error(t, err, data)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package monitoring
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+)
+
+type Service interface {
+	// Evaluate runs the rules on a processed transaction, storing the alerts it raises
+	Evaluate(ctx context.Context, tr domain.Transaction) ([]domain.Alert, error)
+	List(ctx context.Context, filter domain.AlertFilter) (domain.AlertPage, error)
+}
+
+type service struct {
+	r     Repository
+	rules []Rule
+}
+
+func NewService(r Repository, rules []Rule) Service {
+	return &service{
+		r:     r,
+		rules: rules,
+	}
+}
+
+func (s service) Evaluate(ctx context.Context, tr domain.Transaction) ([]domain.Alert, error) {
+	var alerts []domain.Alert
+	for _, rule := range s.rules {
+		details, err := rule.Evaluate(ctx, tr, s.r)
+		if err != nil {
+			return alerts, err
+		}
+		if details == "" {
+			continue
+		}
+
+		alert := domain.Alert{
+			ID:            uuid.New(),
+			Rule:          rule.Name(),
+			AccountID:     tr.AccountID,
+			TransactionID: tr.ID,
+			Type:          tr.Type,
+			Amount:        tr.Amount,
+			Details:       details,
+			CreatedAt:     time.Now().UTC().Truncate(time.Microsecond),
+		}
+		if err = s.r.Save(ctx, alert); err != nil {
+			return alerts, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+func (s service) List(ctx context.Context, filter domain.AlertFilter) (domain.AlertPage, error) {
+	return s.r.List(ctx, filter)
+}
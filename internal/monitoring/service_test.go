@@ -0,0 +1,85 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type repositoryMock struct {
+	activityMock
+	save func(alert domain.Alert) error
+	list func(filter domain.AlertFilter) (domain.AlertPage, error)
+}
+
+func (r repositoryMock) Save(ctx context.Context, alert domain.Alert) error {
+	return r.save(alert)
+}
+
+func (r repositoryMock) List(ctx context.Context, filter domain.AlertFilter) (domain.AlertPage, error) {
+	return r.list(filter)
+}
+
+func TestEvaluate(t *testing.T) {
+	tr := domain.Transaction{ID: uuid.New(), AccountID: uuid.New(), Type: domain.Deposit, Amount: domain.NewMoney(2000000, "USD"), Timestamp: time.Now()}
+	rules := []Rule{
+		ThresholdRule{RuleName: "large_deposit", Type: domain.Deposit, Threshold: domain.NewMoney(1000000, "USD")},
+		ThresholdRule{RuleName: "large_withdraw", Type: domain.WithDraw, Threshold: domain.NewMoney(1000000, "USD")},
+	}
+
+	t.Run("evaluate raises alerts", func(t *testing.T) {
+		var saved []domain.Alert
+		s := NewService(repositoryMock{
+			save: func(alert domain.Alert) error {
+				saved = append(saved, alert)
+				return nil
+			},
+		}, rules)
+
+		alerts, err := s.Evaluate(context.Background(), tr)
+		assert.NoError(t, err)
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, saved, alerts)
+		assert.Equal(t, "large_deposit", alerts[0].Rule)
+		assert.Equal(t, tr.AccountID, alerts[0].AccountID)
+		assert.Equal(t, tr.ID, alerts[0].TransactionID)
+		assert.Equal(t, tr.Amount, alerts[0].Amount)
+	})
+	t.Run("evaluate without alerts", func(t *testing.T) {
+		s := NewService(repositoryMock{}, rules)
+
+		alerts, err := s.Evaluate(context.Background(), domain.Transaction{Type: domain.Deposit, Amount: domain.NewMoney(100, "USD")})
+		assert.NoError(t, err)
+		assert.Empty(t, alerts)
+	})
+	t.Run("evaluate save error", func(t *testing.T) {
+		s := NewService(repositoryMock{
+			save: func(alert domain.Alert) error {
+				return errors.New("test error")
+			},
+		}, rules)
+
+		_, err := s.Evaluate(context.Background(), tr)
+		assert.Error(t, err)
+	})
+}
+
+func TestListAlerts(t *testing.T) {
+	filter := domain.AlertFilter{Rule: "large_deposit", Cursor: "abc", Limit: 10}
+	expected := domain.AlertPage{Alerts: []domain.Alert{{ID: uuid.New(), Rule: "large_deposit"}}, NextCursor: "def"}
+	s := NewService(repositoryMock{
+		list: func(f domain.AlertFilter) (domain.AlertPage, error) {
+			assert.Equal(t, filter, f)
+			return expected, nil
+		},
+	}, nil)
+
+	page, err := s.List(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, page)
+}
@@ -0,0 +1,47 @@
+package signing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// eachBackend runs the test on every storage backend, so they all behave the same. MySQL
+// and PostgreSQL only run when storetest.MySQLEnv and storetest.PostgresEnv are set
+func eachBackend(t *testing.T, test func(t *testing.T, s NonceStore)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemoryNonceStore()) })
+	t.Run("sqlite", func(t *testing.T) { test(t, NewNonceStore(storetest.SQLite(t))) })
+	t.Run("mysql", func(t *testing.T) { test(t, NewNonceStore(storetest.MySQL(t))) })
+	t.Run("postgres", func(t *testing.T) { test(t, NewNonceStore(store.Postgres(storetest.Postgres(t)))) })
+}
+
+func TestNonceStoreContract(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	eachBackend(t, func(t *testing.T, s NonceStore) {
+		fresh, err := s.Use(ctx, "key:first", now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, fresh)
+
+		fresh, err = s.Use(ctx, "key:first", now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, fresh)
+
+		fresh, err = s.Use(ctx, "key:second", now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, fresh)
+
+		purged, err := s.Purge(ctx, now.Add(2*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		fresh, err = s.Use(ctx, "key:first", now.Add(3*time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, fresh)
+	})
+}
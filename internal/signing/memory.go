@@ -0,0 +1,44 @@
+package signing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// Use rejects a nonce until it is purged, even after it expires, as the SQL store does
+func (s *memoryNonceStore) Use(_ context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.nonces[nonce]; ok {
+		return false, nil
+	}
+	s.nonces[nonce] = expiresAt
+	return true, nil
+}
+
+func (s *memoryNonceStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for nonce, expiresAt := range s.nonces {
+		if expiresAt.Before(now) {
+			delete(s.nonces, nonce)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,45 @@
+package signing
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+// NonceStore remembers the nonces of the signed requests, so a request can not be replayed
+// while its timestamp is still accepted
+type NonceStore interface {
+	// Use stores the nonce until it expires. It returns false when it was already used
+	Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+type sqlNonceStore struct {
+	db store.DBTX
+}
+
+func NewNonceStore(db store.DBTX) NonceStore {
+	return &sqlNonceStore{
+		db: store.Instrument(db, store.SystemOf(db), "request_nonces"),
+	}
+}
+
+func (s sqlNonceStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO request_nonces (nonce, expires_at) VALUES (?, ?);", nonce, expiresAt.UTC())
+	if err == nil {
+		return true, nil
+	}
+	if store.IsDuplicate(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s sqlNonceStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM request_nonces WHERE expires_at < ?;", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,77 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseNonce(t *testing.T) {
+	expiresAt := time.Date(2023, 1, 1, 0, 5, 0, 0, time.UTC)
+
+	t.Run("use new nonce", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("INSERT INTO request_nonces").WithArgs("key:nonce", expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		fresh, err := NewNonceStore(db).Use(context.Background(), "key:nonce", expiresAt)
+		assert.NoError(t, err)
+		assert.True(t, fresh)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("use replayed nonce", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("INSERT INTO request_nonces").
+			WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+		fresh, err := NewNonceStore(db).Use(context.Background(), "key:nonce", expiresAt)
+		assert.NoError(t, err)
+		assert.False(t, fresh)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("use nonce error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("INSERT INTO request_nonces").WillReturnError(errors.New("test error"))
+
+		_, err = NewNonceStore(db).Use(context.Background(), "key:nonce", expiresAt)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPurgeNonces(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fail()
+	}
+	defer db.Close()
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("DELETE FROM request_nonces WHERE expires_at < \\?").WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	purged, err := NewNonceStore(db).Purge(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,59 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how far the timestamp of a signed request can be from the server
+// clock when no tolerance is configured
+const DefaultTolerance = 5 * time.Minute
+
+// Keys are the active signing secrets by key id. Several keys can be active at once, so
+// a secret can be rotated without downtime: the new key is added, the clients move to it
+// and then the old one is removed
+type Keys map[string][]byte
+
+// ParseKeys reads keys written as comma separated "id:secret" pairs
+func ParseKeys(s string) (Keys, error) {
+	keys := Keys{}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid signing key %q, it must be id:secret", pair)
+		}
+		keys[parts[0]] = []byte(parts[1])
+	}
+	return keys, nil
+}
+
+// StringToSign is the canonical form of a request that is signed: the method, the path
+// with its query, the unix timestamp, the nonce and the hex SHA-256 of the body, one per line
+func StringToSign(method, path string, timestamp int64, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// Sign returns the hex HMAC-SHA256 of the canonical request
+func Sign(secret []byte, method, path string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(StringToSign(method, path, timestamp, nonce, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature in constant time
+func Verify(secret []byte, signature, method, path string, timestamp int64, nonce string, body []byte) bool {
+	expected := Sign(secret, method, path, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
@@ -0,0 +1,44 @@
+package signing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	t.Run("parse keys", func(t *testing.T) {
+		keys, err := ParseKeys("2023-01:first-secret, 2023-02:second:secret")
+		assert.NoError(t, err)
+		assert.Equal(t, Keys{"2023-01": []byte("first-secret"), "2023-02": []byte("second:secret")}, keys)
+	})
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, value := range []string{"secret", "id:", ":secret", "id:secret,"} {
+			_, err := ParseKeys(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestSign(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"amount":10}`)
+
+	assert.Equal(t, "POST\n/transactions\n1672531200\nnonce\na8b88b82fe90a16048eb8851fe382405395cd395dafaa7ca9be90ec00f82a72b",
+		StringToSign("post", "/transactions", 1672531200, "nonce", body))
+
+	signature := Sign(secret, "POST", "/transactions", 1672531200, "nonce", body)
+	assert.Len(t, signature, 64)
+	assert.True(t, Verify(secret, signature, "POST", "/transactions", 1672531200, "nonce", body))
+
+	for name, valid := range map[string]bool{
+		"other secret":    Verify([]byte("other"), signature, "POST", "/transactions", 1672531200, "nonce", body),
+		"other method":    Verify(secret, signature, "PUT", "/transactions", 1672531200, "nonce", body),
+		"other path":      Verify(secret, signature, "POST", "/transactions?x=1", 1672531200, "nonce", body),
+		"other timestamp": Verify(secret, signature, "POST", "/transactions", 1672531201, "nonce", body),
+		"other nonce":     Verify(secret, signature, "POST", "/transactions", 1672531200, "other", body),
+		"other body":      Verify(secret, signature, "POST", "/transactions", 1672531200, "nonce", []byte(`{"amount":100}`)),
+	} {
+		assert.False(t, valid, name)
+	}
+}
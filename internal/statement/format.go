@@ -0,0 +1,180 @@
+package statement
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	OFX  Format = "ofx"
+	JSON Format = "json"
+)
+
+// ParseFormat validates the requested format, json by default
+func ParseFormat(value string) (Format, error) {
+	switch f := Format(strings.ToLower(value)); f {
+	case "":
+		return JSON, nil
+	case CSV, OFX, JSON:
+		return f, nil
+	default:
+		return "", custom_errors.ErrInvalidStatementFormat
+	}
+}
+
+// ContentType is the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case OFX:
+		return "application/x-ofx"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// Write encodes the statement in the format
+func Write(w io.Writer, st domain.Statement, f Format) error {
+	switch f {
+	case CSV:
+		return writeCSV(w, st)
+	case OFX:
+		return writeOFX(w, st)
+	default:
+		return json.NewEncoder(w).Encode(st)
+	}
+}
+
+// writeCSV writes a row per transaction between the opening and the closing balance rows
+func writeCSV(w io.Writer, st domain.Statement) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{
+		{"date", "transaction_id", "type", "counterparty_id", "amount", "balance"},
+		{st.From.Format(time.RFC3339), "", "opening_balance", "", "", st.OpeningBalance.String()},
+	}
+	for _, e := range st.Entries {
+		counterparty := ""
+		if e.CounterpartyID != nil {
+			counterparty = e.CounterpartyID.String()
+		}
+		rows = append(rows, []string{
+			e.Timestamp.UTC().Format(time.RFC3339Nano), e.TransactionID.String(), string(e.Type), counterparty,
+			e.Amount.String(), e.Balance.String(),
+		})
+	}
+	rows = append(rows, []string{st.To.Format(time.RFC3339), "", "closing_balance", "", "", st.ClosingBalance.String()})
+
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// ofxDateLayout is the OFX date time format, always written in UTC
+const ofxDateLayout = "20060102150405.000[0:GMT]"
+
+// bankID identifies this bank in the OFX files
+const bankID = "XEPELIN"
+
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	Status  struct {
+		Code     int    `xml:"SONRS>STATUS>CODE"`
+		Severity string `xml:"SONRS>STATUS>SEVERITY"`
+		DTServer string `xml:"SONRS>DTSERVER"`
+		Language string `xml:"SONRS>LANGUAGE"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Statement ofxStatementResponse `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatementResponse struct {
+	TrnUID   string           `xml:"TRNUID"`
+	Code     int              `xml:"STATUS>CODE"`
+	Severity string           `xml:"STATUS>SEVERITY"`
+	Currency string           `xml:"STMTRS>CURDEF"`
+	BankID   string           `xml:"STMTRS>BANKACCTFROM>BANKID"`
+	AcctID   string           `xml:"STMTRS>BANKACCTFROM>ACCTID"`
+	AcctType string           `xml:"STMTRS>BANKACCTFROM>ACCTTYPE"`
+	DTStart  string           `xml:"STMTRS>BANKTRANLIST>DTSTART"`
+	DTEnd    string           `xml:"STMTRS>BANKTRANLIST>DTEND"`
+	Entries  []ofxTransaction `xml:"STMTRS>BANKTRANLIST>STMTTRN"`
+	Balance  string           `xml:"STMTRS>LEDGERBAL>BALAMT"`
+	BalDate  string           `xml:"STMTRS>LEDGERBAL>DTASOF"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FitID  string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+// writeOFX writes an OFX 2 bank statement. OFX has no opening balance, so only the
+// closing balance is written, as the ledger balance at the end of the period
+func writeOFX(w io.Writer, st domain.Statement) error {
+	var doc ofxDocument
+	doc.Status.Severity = "INFO"
+	doc.Status.DTServer = time.Now().UTC().Format(ofxDateLayout)
+	doc.Status.Language = "ENG"
+	doc.Statement = ofxStatementResponse{
+		TrnUID:   st.AccountID.String(),
+		Severity: "INFO",
+		Currency: st.Currency,
+		BankID:   bankID,
+		AcctID:   st.AccountID.String(),
+		AcctType: "CHECKING",
+		DTStart:  st.From.UTC().Format(ofxDateLayout),
+		DTEnd:    st.To.UTC().Format(ofxDateLayout),
+		Balance:  st.ClosingBalance.String(),
+		BalDate:  st.To.UTC().Format(ofxDateLayout),
+	}
+	for _, e := range st.Entries {
+		t := ofxTransaction{
+			Type:   ofxTransactionType(e),
+			Posted: e.Timestamp.UTC().Format(ofxDateLayout),
+			Amount: e.Amount.String(),
+			FitID:  e.TransactionID.String(),
+			Name:   string(e.Type),
+		}
+		if e.CounterpartyID != nil {
+			t.Memo = e.CounterpartyID.String()
+		}
+		doc.Statement.Entries = append(doc.Statement.Entries, t)
+	}
+
+	header := xml.Header + `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func ofxTransactionType(e domain.StatementEntry) string {
+	switch e.Type {
+	case domain.Deposit:
+		return "DEP"
+	case domain.WithDraw:
+		return "CASH"
+	default:
+		return "XFER"
+	}
+}
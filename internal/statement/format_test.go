@@ -0,0 +1,89 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStatement() domain.Statement {
+	acc := domain.Account{ID: uuid.New(), Name: "test"}
+	other := uuid.New()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	st, _ := domain.NewStatement(acc, from, from.AddDate(0, 1, 0), domain.NewMoney(1000, "USD"), []domain.Transaction{
+		{ID: uuid.New(), AccountID: acc.ID, Type: domain.Deposit, Amount: domain.NewMoney(550, "USD"), Timestamp: from.Add(time.Hour)},
+		{ID: uuid.New(), AccountID: acc.ID, DestinationID: &other, Type: domain.Transfer, Amount: domain.NewMoney(300, "USD"), Timestamp: from.Add(2 * time.Hour)},
+	})
+	return st
+}
+
+func TestParseFormat(t *testing.T) {
+	for value, expected := range map[string]Format{"": JSON, "json": JSON, "CSV": CSV, "ofx": OFX} {
+		f, err := ParseFormat(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, f)
+	}
+
+	_, err := ParseFormat("pdf")
+	assert.ErrorIs(t, err, custom_errors.ErrInvalidStatementFormat)
+}
+
+func TestWriteCSV(t *testing.T) {
+	st := testStatement()
+	var buf bytes.Buffer
+
+	assert.NoError(t, Write(&buf, st, CSV))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 5)
+	assert.Equal(t, []string{"date", "transaction_id", "type", "counterparty_id", "amount", "balance"}, rows[0])
+	assert.Equal(t, []string{"2023-01-01T00:00:00Z", "", "opening_balance", "", "", "10.00"}, rows[1])
+	assert.Equal(t, []string{"2023-01-01T01:00:00Z", st.Entries[0].TransactionID.String(), "deposit", "", "5.50", "15.50"}, rows[2])
+	assert.Equal(t, []string{"2023-01-01T02:00:00Z", st.Entries[1].TransactionID.String(), "transfer", st.Entries[1].CounterpartyID.String(), "-3.00", "12.50"}, rows[3])
+	assert.Equal(t, []string{"2023-02-01T00:00:00Z", "", "closing_balance", "", "", "12.50"}, rows[4])
+}
+
+func TestWriteOFX(t *testing.T) {
+	st := testStatement()
+	var buf bytes.Buffer
+
+	assert.NoError(t, Write(&buf, st, OFX))
+
+	out := buf.String()
+	assert.True(t, strings.Contains(out, `<?OFX OFXHEADER="200" VERSION="220"`))
+
+	var doc ofxDocument
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "USD", doc.Statement.Currency)
+	assert.Equal(t, st.AccountID.String(), doc.Statement.AcctID)
+	assert.Equal(t, "20230101000000.000[0:GMT]", doc.Statement.DTStart)
+	assert.Equal(t, "12.50", doc.Statement.Balance)
+	assert.Len(t, doc.Statement.Entries, 2)
+	assert.Equal(t, "DEP", doc.Statement.Entries[0].Type)
+	assert.Equal(t, "5.50", doc.Statement.Entries[0].Amount)
+	assert.Equal(t, "XFER", doc.Statement.Entries[1].Type)
+	assert.Equal(t, "-3.00", doc.Statement.Entries[1].Amount)
+}
+
+func TestWriteJSON(t *testing.T) {
+	st := testStatement()
+	var buf bytes.Buffer
+
+	assert.NoError(t, Write(&buf, st, JSON))
+
+	res := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	assert.Equal(t, 10.0, res["opening_balance"])
+	assert.Equal(t, 12.5, res["closing_balance"])
+	assert.Len(t, res["entries"], 2)
+}
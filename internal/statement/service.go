@@ -0,0 +1,60 @@
+package statement
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/transaction"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+type Service interface {
+	Build(ctx context.Context, accountID uuid.UUID, from, to time.Time) (domain.Statement, error)
+}
+
+type service struct {
+	uow transaction.UnitOfWork
+}
+
+func NewService(uow transaction.UnitOfWork) Service {
+	return &service{
+		uow: uow,
+	}
+}
+
+// Build returns the statement of the account from the start time, inclusive, to the end
+// time, exclusive. The opening balance is the current balance without the transactions
+// made since the start. The account is locked before its transactions are read, so none
+// can be recorded in between and the balances match the transactions listed, whatever
+// the isolation level of the database
+func (s service) Build(ctx context.Context, accountID uuid.UUID, from, to time.Time) (domain.Statement, error) {
+	if !from.Before(to) {
+		return domain.Statement{}, custom_errors.ErrInvalidStatementPeriod
+	}
+
+	var st domain.Statement
+	err := s.uow.Do(ctx, func(ctx context.Context, tx transaction.Tx) error {
+		acc, err := tx.Accounts.ReadForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		net, err := tx.Transactions.NetChangeSince(ctx, accountID, from)
+		if err != nil {
+			return err
+		}
+		transactions, err := tx.Transactions.ListBetween(ctx, accountID, from, to)
+		if err != nil {
+			return err
+		}
+
+		opening, err := acc.Balance.Sub(domain.NewMoney(net, acc.Balance.Currency))
+		if err != nil {
+			return err
+		}
+		st, err = domain.NewStatement(acc, from.UTC(), to.UTC(), opening, transactions)
+		return err
+	})
+	return st, err
+}
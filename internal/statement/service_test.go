@@ -0,0 +1,104 @@
+package statement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/transaction"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type accServiceMock struct {
+	account.Service
+	readForUpdate func(id uuid.UUID) (domain.Account, error)
+}
+
+func (a accServiceMock) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.readForUpdate(id)
+}
+
+type trRepositoryMock struct {
+	transaction.Repository
+	listBetween    func(accountID uuid.UUID, from, to time.Time) ([]domain.Transaction, error)
+	netChangeSince func(accountID uuid.UUID, since time.Time) (int64, error)
+}
+
+func (t trRepositoryMock) ListBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]domain.Transaction, error) {
+	return t.listBetween(accountID, from, to)
+}
+
+func (t trRepositoryMock) NetChangeSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int64, error) {
+	return t.netChangeSince(accountID, since)
+}
+
+type uowMock struct {
+	accounts     accServiceMock
+	transactions trRepositoryMock
+}
+
+func (u uowMock) Do(ctx context.Context, fn func(ctx context.Context, tx transaction.Tx) error) error {
+	return fn(ctx, transaction.Tx{
+		Accounts:     u.accounts,
+		Transactions: u.transactions,
+	})
+}
+
+func TestBuild(t *testing.T) {
+	id := uuid.New()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	t.Run("build success", func(t *testing.T) {
+		uow := uowMock{
+			accounts: accServiceMock{
+				readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+					return domain.Account{ID: id, Balance: domain.NewMoney(2000, "USD")}, nil
+				},
+			},
+			transactions: trRepositoryMock{
+				netChangeSince: func(accountID uuid.UUID, since time.Time) (int64, error) {
+					assert.Equal(t, from, since)
+					// a deposit in the period and a withdrawal after it
+					return 500 - 100, nil
+				},
+				listBetween: func(accountID uuid.UUID, start, end time.Time) ([]domain.Transaction, error) {
+					assert.Equal(t, from, start)
+					assert.Equal(t, to, end)
+					return []domain.Transaction{
+						{ID: uuid.New(), AccountID: id, Type: domain.Deposit, Amount: domain.NewMoney(500, "USD"), Timestamp: from},
+					}, nil
+				},
+			},
+		}
+
+		st, err := NewService(uow).Build(context.Background(), id, from, to)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.NewMoney(1600, "USD"), st.OpeningBalance)
+		assert.Equal(t, domain.NewMoney(2100, "USD"), st.ClosingBalance)
+		assert.Len(t, st.Entries, 1)
+	})
+	t.Run("build account not found", func(t *testing.T) {
+		uow := uowMock{
+			accounts: accServiceMock{
+				readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+					return domain.Account{}, custom_errors.ErrNotFound
+				},
+			},
+		}
+
+		_, err := NewService(uow).Build(context.Background(), id, from, to)
+
+		assert.ErrorIs(t, err, custom_errors.ErrNotFound)
+	})
+	t.Run("build invalid period", func(t *testing.T) {
+		_, err := NewService(uowMock{}).Build(context.Background(), id, to, from)
+
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidStatementPeriod)
+	})
+}
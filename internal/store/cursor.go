@@ -0,0 +1,44 @@
+package store
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+)
+
+// Cursor is the position of the last row of a page sorted by a timestamp and an id, so
+// the next page starts right after it
+type Cursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// EncodeCursor returns the opaque cursor of the row with the given timestamp and id
+func EncodeCursor(t time.Time, id uuid.UUID) string {
+	raw := t.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor returned by EncodeCursor, failing with ErrInvalidCursor
+func DecodeCursor(value string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, custom_errors.ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return Cursor{}, custom_errors.ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return Cursor{}, custom_errors.ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return Cursor{}, custom_errors.ErrInvalidCursor
+	}
+	return Cursor{Time: t, ID: id}, nil
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("decode encoded cursor", func(t *testing.T) {
+		ts, id := time.Date(2023, 5, 1, 10, 0, 0, 123456789, time.FixedZone("CLT", -4*3600)), uuid.New()
+
+		c, err := DecodeCursor(EncodeCursor(ts, id))
+
+		assert.NoError(t, err)
+		assert.True(t, ts.Equal(c.Time))
+		assert.Equal(t, id, c.ID)
+	})
+	t.Run("reject invalid cursors", func(t *testing.T) {
+		encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+		for _, value := range []string{
+			"not a cursor",
+			encode("2023-05-01T10:00:00Z"),
+			encode("yesterday|" + uuid.NewString()),
+			encode("2023-05-01T10:00:00Z|not-an-id"),
+		} {
+			_, err := DecodeCursor(value)
+			assert.ErrorIs(t, err, custom_errors.ErrInvalidCursor, value)
+		}
+	})
+}
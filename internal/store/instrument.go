@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the statements
+const tracerName = "github.com/lucaspichi06/xepelin-bank/internal/store"
+
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "Latency of the SQL statements run by the repositories.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"repository", "operation"})
+
+// System is the database that runs the statements, as named in the db.system attribute
+// of their spans
+type System string
+
+const (
+	SystemMySQL      System = "mysql"
+	SystemPostgreSQL System = "postgresql"
+	SystemSQLite     System = "sqlite"
+)
+
+// SystemOf is the database db was bound to with Postgres or SQLite, MySQL when it runs
+// the statements as they are
+func SystemOf(db DBTX) System {
+	switch db.(type) {
+	case *postgres:
+		return SystemPostgreSQL
+	case *sqlite:
+		return SystemSQLite
+	default:
+		return SystemMySQL
+	}
+}
+
+type instrumented struct {
+	db         DBTX
+	system     System
+	repository string
+}
+
+// Instrument measures the latency of the statements the repository runs on db, and
+// traces each of them as a span of the context it runs with, tagged with the database
+// system. Queries are measured until their first result is available, prepared
+// statements until they are prepared. The statements run through a prepared *sql.Stmt
+// are not measured, so the repositories run their writes with ExecContext
+func Instrument(db DBTX, system System, repository string) DBTX {
+	return &instrumented{
+		db:         db,
+		system:     system,
+		repository: repository,
+	}
+}
+
+func (i instrumented) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, done := i.start(ctx, "prepare", query)
+	stmt, err := i.db.PrepareContext(ctx, query)
+	done(err)
+	return stmt, err
+}
+
+func (i instrumented) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := i.start(ctx, "exec", query)
+	res, err := i.db.ExecContext(ctx, query, args...)
+	done(err)
+	return res, err
+}
+
+func (i instrumented) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := i.start(ctx, "query", query)
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (i instrumented) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := i.start(ctx, "query", query)
+	row := i.db.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// start starts the span of a statement. The returned func ends it and observes its latency
+func (i instrumented) start(ctx context.Context, operation, query string) (context.Context, func(err error)) {
+	start := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "sql."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", string(i.system)),
+			attribute.String("db.repository", i.repository),
+			attribute.String("db.statement", query),
+		))
+	return ctx, func(err error) {
+		queryDuration.WithLabelValues(i.repository, operation).Observe(time.Since(start).Seconds())
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// observations is the number of statements measured with the label values
+func observations(t *testing.T, labelValues ...string) uint64 {
+	var m dto.Metric
+	if err := queryDuration.WithLabelValues(labelValues...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrument(t *testing.T) {
+	t.Run("measure statements", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("UPDATE accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT id FROM accounts").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("id"))
+
+		execs, queries := observations(t, "test", "exec"), observations(t, "test", "query")
+		instrumented := Instrument(db, SystemMySQL, "test")
+
+		_, err = instrumented.ExecContext(context.Background(), "UPDATE accounts SET name = ?;", "name")
+		assert.NoError(t, err)
+		var id string
+		assert.NoError(t, instrumented.QueryRowContext(context.Background(), "SELECT id FROM accounts;").Scan(&id))
+
+		assert.Equal(t, execs+1, observations(t, "test", "exec"))
+		assert.Equal(t, queries+1, observations(t, "test", "query"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("trace statements", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+		defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("UPDATE accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT id FROM accounts").WillReturnError(errors.New("test error"))
+
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+		instrumented := Instrument(db, SystemPostgreSQL, "test")
+		_, err = instrumented.ExecContext(ctx, "UPDATE accounts SET name = ?;", "name")
+		assert.NoError(t, err)
+		_, err = instrumented.QueryContext(ctx, "SELECT id FROM accounts;")
+		assert.Error(t, err)
+		parent.End()
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 3)
+		assert.Equal(t, "sql.exec", spans[0].Name)
+		assert.Contains(t, spans[0].Attributes, attribute.String("db.statement", "UPDATE accounts SET name = ?;"))
+		assert.Contains(t, spans[0].Attributes, attribute.String("db.repository", "test"))
+		assert.Contains(t, spans[0].Attributes, attribute.String("db.system", "postgresql"))
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+		assert.Equal(t, "sql.query", spans[1].Name)
+		assert.Equal(t, codes.Error, spans[1].Status.Code)
+		assert.Equal(t, "test error", spans[1].Status.Description)
+		for _, span := range spans[:2] {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSystemOf(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fail()
+	}
+	defer db.Close()
+
+	assert.Equal(t, SystemMySQL, SystemOf(db))
+	assert.Equal(t, SystemPostgreSQL, SystemOf(Postgres(db)))
+	assert.Equal(t, SystemSQLite, SystemOf(SQLite(db)))
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory runs the units of work of the in-memory repositories one at a time, undoing the
+// changes of the ones that fail, as the database transactions do. The repositories read
+// outside of a unit of work may see the changes of the one running
+type Memory struct {
+	mu sync.Mutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+type memoryTxKey struct{}
+
+// memoryTx is the undo log of a unit of work
+type memoryTx struct {
+	undo []func()
+}
+
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+}
+
+// Do runs fn alone. The changes made with the context fn is given are undone when fn returns
+// an error or panics, or when the context is done before it finishes
+func (m *Memory) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memoryTx{}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback()
+			panic(p)
+		}
+	}()
+
+	err := fn(context.WithValue(ctx, memoryTxKey{}, tx))
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		tx.rollback()
+	}
+	return err
+}
+
+// OnRollback records how to undo a change made by an in-memory repository, in case the unit
+// of work of the context fails. Outside of a unit of work the changes are final
+func OnRollback(ctx context.Context, undo func()) {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		tx.undo = append(tx.undo, undo)
+	}
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	t.Run("keep changes of succeeded unit of work", func(t *testing.T) {
+		undone := false
+
+		err := NewMemory().Do(context.Background(), func(ctx context.Context) error {
+			OnRollback(ctx, func() { undone = true })
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.False(t, undone)
+	})
+	t.Run("undo changes of failed unit of work in reverse order", func(t *testing.T) {
+		var undone []int
+		failure := errors.New("failure")
+
+		err := NewMemory().Do(context.Background(), func(ctx context.Context) error {
+			OnRollback(ctx, func() { undone = append(undone, 1) })
+			OnRollback(ctx, func() { undone = append(undone, 2) })
+			return failure
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, []int{2, 1}, undone)
+	})
+	t.Run("undo changes of cancelled unit of work", func(t *testing.T) {
+		undone := false
+		ctx, cancel := context.WithCancel(context.Background())
+
+		err := NewMemory().Do(ctx, func(ctx context.Context) error {
+			OnRollback(ctx, func() { undone = true })
+			cancel()
+			return nil
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.True(t, undone)
+	})
+	t.Run("undo changes of panicking unit of work", func(t *testing.T) {
+		undone := false
+		memory := NewMemory()
+
+		assert.Panics(t, func() {
+			_ = memory.Do(context.Background(), func(ctx context.Context) error {
+				OnRollback(ctx, func() { undone = true })
+				panic("boom")
+			})
+		})
+		assert.True(t, undone)
+		assert.NoError(t, memory.Do(context.Background(), func(ctx context.Context) error { return nil }), "the lock is released")
+	})
+	t.Run("changes outside of unit of work are final", func(t *testing.T) {
+		assert.NotPanics(t, func() { OnRollback(context.Background(), func() { panic("undone") }) })
+	})
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// postgresUniqueViolation is the PostgreSQL error code of a primary or unique key violation
+const postgresUniqueViolation = "23505"
+
+type postgres struct {
+	db DBTX
+}
+
+// Postgres runs the statements of the repositories on PostgreSQL, which numbers its
+// placeholders as $1, $2... instead of using ? for all of them
+func Postgres(db DBTX) DBTX {
+	return &postgres{
+		db: db,
+	}
+}
+
+func (p postgres) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, Dollar(query))
+}
+
+func (p postgres) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, Dollar(query), args...)
+}
+
+func (p postgres) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, Dollar(query), args...)
+}
+
+func (p postgres) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, Dollar(query), args...)
+}
+
+// Dollar numbers the ? placeholders of the query as $1, $2... leaving alone the ones
+// inside quoted strings and identifiers
+func Dollar(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var (
+		b     strings.Builder
+		n     int
+		quote rune
+	)
+	b.Grow(len(query) + 8)
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDollar(t *testing.T) {
+	t.Run("number placeholders", func(t *testing.T) {
+		assert.Equal(t, "SELECT id FROM accounts WHERE id = $1 AND version > $2;",
+			Dollar("SELECT id FROM accounts WHERE id = ? AND version > ?;"))
+	})
+	t.Run("skip quoted placeholders", func(t *testing.T) {
+		assert.Equal(t, `SELECT '?', "a?b" FROM transactions WHERE type = 'it''s?' AND id = $1;`,
+			Dollar(`SELECT '?', "a?b" FROM transactions WHERE type = 'it''s?' AND id = ?;`))
+	})
+	t.Run("query without placeholders", func(t *testing.T) {
+		assert.Equal(t, "SELECT 1;", Dollar("SELECT 1;"))
+	})
+}
+
+func TestPostgres(t *testing.T) {
+	t.Run("rebind statements", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectExec("UPDATE accounts SET name = $1 WHERE id = $2;").
+			WithArgs("name", "id").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT name FROM accounts WHERE id = $1;").
+			WithArgs("id").
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("name"))
+
+		postgres := Postgres(db)
+		_, err = postgres.ExecContext(context.Background(), "UPDATE accounts SET name = ? WHERE id = ?;", "name", "id")
+		assert.NoError(t, err)
+		var name string
+		assert.NoError(t, postgres.QueryRowContext(context.Background(), "SELECT name FROM accounts WHERE id = ?;", "id").Scan(&name))
+		assert.Equal(t, "name", name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
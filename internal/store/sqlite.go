@@ -0,0 +1,13 @@
+package store
+
+type sqlite struct {
+	DBTX
+}
+
+// SQLite runs the statements of the repositories on SQLite, which takes them as they are.
+// It only tells the instrumentation of the repositories which database runs them
+func SQLite(db DBTX) DBTX {
+	return &sqlite{
+		DBTX: db,
+	}
+}
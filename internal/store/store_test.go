@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTransaction(t *testing.T) {
+	t.Run("rollback cancelled transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		err = WithTransaction(ctx, db, func(tx *sql.Tx) error {
+			cancel()
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestIsDuplicate(t *testing.T) {
+	assert.True(t, IsDuplicate(&mysql.MySQLError{Number: 1062}))
+	assert.False(t, IsDuplicate(&mysql.MySQLError{Number: 1146}))
+	assert.True(t, IsDuplicate(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintPrimaryKey}))
+	assert.True(t, IsDuplicate(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}))
+	assert.False(t, IsDuplicate(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey}))
+	assert.True(t, IsDuplicate(&pq.Error{Code: "23505"}))
+	assert.False(t, IsDuplicate(&pq.Error{Code: "23503"}))
+	assert.True(t, IsDuplicate(fmt.Errorf("wrapped: %w", ErrDuplicate)))
+	assert.False(t, IsDuplicate(errors.New("another error")))
+}
@@ -0,0 +1,91 @@
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/lucaspichi06/xepelin-bank/internal/migrate"
+	"github.com/lucaspichi06/xepelin-bank/migrations"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// MySQLEnv is the environment variable with the DSN of the MySQL database the tests run
+// on. It needs parseTime=true and loc=UTC, and every table in it is emptied
+const MySQLEnv = "MYSQL_TEST_DSN"
+
+// PostgresEnv is the environment variable with the DSN of the PostgreSQL database the
+// tests run on. Every table in it is emptied
+const PostgresEnv = "POSTGRES_TEST_DSN"
+
+// tables are the tables of the schema, the referencing ones first
+var tables = []string{
+	"alerts", "request_nonces", "principal_accounts", "api_keys", "principals", "postings", "journal_entries",
+	"idempotency_keys", "account_snapshots", "account_events", "transactions", "accounts",
+}
+
+// SQLite opens a new SQLite database in a temporary file, with the schema migrated and the
+// settings the server uses
+func SQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bank.db")
+	db, err := sql.Open("sqlite3", "file:"+path+"?_txlock=immediate&_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrateUp(t, db, migrate.SQLite, migrations.SQLite)
+	return db
+}
+
+// MySQL opens the database of MYSQL_TEST_DSN, with the schema migrated and its tables
+// empty. The test is skipped when the variable is not set
+func MySQL(t *testing.T) *sql.DB {
+	t.Helper()
+	return server(t, MySQLEnv, "mysql", migrate.MySQL, migrations.MySQL)
+}
+
+// Postgres opens the database of POSTGRES_TEST_DSN, with the schema migrated and its
+// tables empty. The test is skipped when the variable is not set
+func Postgres(t *testing.T) *sql.DB {
+	t.Helper()
+	return server(t, PostgresEnv, "postgres", migrate.Postgres, migrations.Postgres)
+}
+
+// server opens the database of the DSN in the environment variable, emptying its tables
+func server(t *testing.T, env, driver string, dialect migrate.Dialect, fsys fs.FS) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skip(env + " is not set")
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrateUp(t, db, dialect, fsys)
+	for _, table := range tables {
+		if _, err := db.Exec("DELETE FROM " + table + ";"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func migrateUp(t *testing.T, db *sql.DB, dialect migrate.Dialect, fsys fs.FS) {
+	t.Helper()
+	schema, err := migrate.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.NewMigrator(db, dialect, schema, time.Minute).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
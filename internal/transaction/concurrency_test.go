@@ -0,0 +1,226 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/ledger"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockingStore emulates row-level locks: a row read for update stays locked until
+// the unit of work that locked it finishes, and changes are only visible after commit
+type lockingStore struct {
+	mu     sync.Mutex
+	rows   map[uuid.UUID]domain.Account
+	locks  map[uuid.UUID]*sync.Mutex
+	ledger memoryLedger
+}
+
+func newLockingStore(accounts ...domain.Account) *lockingStore {
+	s := &lockingStore{
+		rows:  make(map[uuid.UUID]domain.Account),
+		locks: make(map[uuid.UUID]*sync.Mutex),
+	}
+	for _, acc := range accounts {
+		s.rows[acc.ID] = acc
+		s.locks[acc.ID] = &sync.Mutex{}
+	}
+	return s
+}
+
+func (s *lockingStore) balance(id uuid.UUID) domain.Money {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rows[id].Balance
+}
+
+func (s *lockingStore) Do(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	accounts := &lockingAccounts{store: s, pending: make(map[uuid.UUID]domain.Account)}
+	defer func() {
+		for _, id := range accounts.held {
+			s.locks[id].Unlock()
+		}
+	}()
+
+	err := fn(ctx, Tx{
+		Accounts: accounts,
+		Transactions: trRepositoryMock{
+			create: func(tr *domain.Transaction) error {
+				return nil
+			},
+		},
+		Ledger: ledger.NewService(&s.ledger),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, acc := range accounts.pending {
+		s.rows[id] = acc
+	}
+	return nil
+}
+
+type lockingAccounts struct {
+	store   *lockingStore
+	held    []uuid.UUID
+	pending map[uuid.UUID]domain.Account
+}
+
+func (a *lockingAccounts) Create(ctx context.Context, account domain.Account) error {
+	return errors.New("not supported")
+}
+
+func (a *lockingAccounts) Read(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+	return a.store.rows[id], nil
+}
+
+func (a *lockingAccounts) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	lock, ok := a.store.locks[id]
+	if !ok {
+		return domain.Account{}, custom_errors.ErrNotFound
+	}
+	lock.Lock()
+	a.held = append(a.held, id)
+	return a.Read(ctx, id)
+}
+
+func (a *lockingAccounts) Record(ctx context.Context, account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+	// widen the window between the read and the commit so lost updates show up
+	time.Sleep(time.Millisecond)
+	account.Apply(event)
+	a.pending[account.ID] = account
+	return account, nil
+}
+
+func (a *lockingAccounts) Rebuild(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return domain.Account{}, errors.New("not supported")
+}
+
+func (a *lockingAccounts) Snapshot(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return domain.Account{}, errors.New("not supported")
+}
+
+func (a *lockingAccounts) List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	return domain.AccountPage{}, errors.New("not supported")
+}
+
+func TestConcurrentTransactions(t *testing.T) {
+	t.Run("concurrent withdrawals never overdraw the account", func(t *testing.T) {
+		id := uuid.New()
+		store := newLockingStore(domain.Account{ID: id, Name: "test", Currency: domain.DefaultCurrency, Balance: domain.NewMoney(100000, domain.DefaultCurrency)})
+		trService := NewService(store, noRates)
+
+		const workers = 100
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- trService.Create(context.Background(), &domain.Transaction{
+					AccountID: id,
+					Type:      domain.WithDraw,
+					Amount:    domain.NewMoney(1500, domain.DefaultCurrency),
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assert.Equal(t, custom_errors.ErrInsuficientBalance, err)
+		}
+
+		assert.Equal(t, 66, succeeded)
+		assert.Equal(t, domain.NewMoney(1000, domain.DefaultCurrency), store.balance(id))
+	})
+	t.Run("concurrent deposits are not lost", func(t *testing.T) {
+		id := uuid.New()
+		store := newLockingStore(domain.Account{ID: id, Name: "test", Currency: domain.DefaultCurrency, Balance: domain.NewMoney(0, domain.DefaultCurrency)})
+		trService := NewService(store, noRates)
+
+		const workers = 100
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, trService.Create(context.Background(), &domain.Transaction{
+					AccountID: id,
+					Type:      domain.Deposit,
+					Amount:    domain.NewMoney(1000, domain.DefaultCurrency),
+				}))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, domain.NewMoney(100000, domain.DefaultCurrency), store.balance(id))
+		assert.Len(t, store.ledger.entries, workers)
+		verification, err := ledger.NewService(&store.ledger).Verify(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, verification.Balanced)
+	})
+	t.Run("opposite transfers do not deadlock", func(t *testing.T) {
+		a, b := uuid.New(), uuid.New()
+		store := newLockingStore(
+			domain.Account{ID: a, Name: "a", Currency: domain.DefaultCurrency, Balance: domain.NewMoney(100000, domain.DefaultCurrency)},
+			domain.Account{ID: b, Name: "b", Currency: domain.DefaultCurrency, Balance: domain.NewMoney(100000, domain.DefaultCurrency)},
+		)
+		trService := NewService(store, noRates)
+
+		const workers = 50
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, trService.Create(context.Background(), &domain.Transaction{
+					AccountID:     a,
+					DestinationID: &b,
+					Type:          domain.Transfer,
+					Amount:        domain.NewMoney(1000, domain.DefaultCurrency),
+				}))
+			}()
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, trService.Create(context.Background(), &domain.Transaction{
+					AccountID:     b,
+					DestinationID: &a,
+					Type:          domain.Transfer,
+					Amount:        domain.NewMoney(1000, domain.DefaultCurrency),
+				}))
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("transfers deadlocked")
+		}
+
+		assert.Equal(t, domain.NewMoney(100000, domain.DefaultCurrency), store.balance(a))
+		assert.Equal(t, domain.NewMoney(100000, domain.DefaultCurrency), store.balance(b))
+	})
+}
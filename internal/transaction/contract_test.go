@@ -0,0 +1,183 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"github.com/lucaspichi06/xepelin-bank/internal/store/storetest"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// eachBackend runs the test on every storage backend, so they all behave the same. MySQL
+// and PostgreSQL only run when storetest.MySQLEnv and storetest.PostgresEnv are set
+func eachBackend(t *testing.T, test func(t *testing.T, accounts account.Repository, transactions Repository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, account.NewMemoryRepository(), NewMemoryRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db := storetest.SQLite(t)
+		test(t, account.NewSQLiteRepository(db), NewRepository(db))
+	})
+	t.Run("mysql", func(t *testing.T) {
+		db := storetest.MySQL(t)
+		test(t, account.NewRepository(db), NewRepository(db))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		db := storetest.Postgres(t)
+		test(t, account.NewPostgresRepository(db), NewPostgresRepository(db))
+	})
+}
+
+func TestRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	eachBackend(t, func(t *testing.T, accounts account.Repository, transactions Repository) {
+		usd, clp := uuid.New(), uuid.New()
+		assert.NoError(t, accounts.Create(ctx, domain.Account{ID: usd, Balance: domain.NewMoney(0, "USD")}))
+		assert.NoError(t, accounts.Create(ctx, domain.Account{ID: clp, Balance: domain.NewMoney(0, "CLP")}))
+
+		rate, err := domain.ParseRate("900.5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		converted := domain.NewMoney(90050, "CLP")
+		deposit := domain.Transaction{ID: uuid.New(), AccountID: usd, Type: domain.Deposit, Amount: domain.NewMoney(1000, "USD"), Currency: "USD", Timestamp: start}
+		withdraw := domain.Transaction{ID: uuid.New(), AccountID: usd, Type: domain.WithDraw, Amount: domain.NewMoney(300, "USD"), Currency: "USD", Timestamp: start.Add(time.Minute)}
+		transfer := domain.Transaction{ID: uuid.New(), AccountID: usd, DestinationID: &clp, Type: domain.Transfer, Amount: domain.NewMoney(100, "USD"), Currency: "USD",
+			Rate: &rate, DestinationAmount: &converted, DestinationCurrency: "CLP", Timestamp: start.Add(2 * time.Minute)}
+		// sorted by id when they have the same timestamp
+		sameTime := domain.Transaction{ID: uuid.New(), AccountID: clp, Type: domain.Deposit, Amount: domain.NewMoney(5000, "CLP"), Currency: "CLP", Timestamp: start.Add(2 * time.Minute)}
+		for _, tr := range []domain.Transaction{deposit, withdraw, transfer, sameTime} {
+			tr := tr
+			assert.NoError(t, transactions.Create(ctx, &tr))
+		}
+		assert.True(t, store.IsDuplicate(transactions.Create(ctx, &deposit)))
+
+		t.Run("list pages newest first", func(t *testing.T) {
+			page, err := transactions.List(ctx, domain.TransactionFilter{AccountID: usd, Limit: 2})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Transaction{transfer, withdraw}, page.Transactions)
+			assert.NotEmpty(t, page.NextCursor)
+
+			page, err = transactions.List(ctx, domain.TransactionFilter{AccountID: usd, Limit: 2, Cursor: page.NextCursor})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Transaction{deposit}, page.Transactions)
+			assert.Empty(t, page.NextCursor)
+		})
+		t.Run("list transfers received", func(t *testing.T) {
+			page, err := transactions.List(ctx, domain.TransactionFilter{AccountID: clp})
+			assert.NoError(t, err)
+			first, second := transfer, sameTime
+			if sameTime.ID.String() > transfer.ID.String() {
+				first, second = sameTime, transfer
+			}
+			assert.Equal(t, []domain.Transaction{first, second}, page.Transactions)
+		})
+		t.Run("list filtered", func(t *testing.T) {
+			from, to := start.Add(time.Minute), start.Add(3*time.Minute)
+			low, high := domain.NewMoney(100, "USD"), domain.NewMoney(300, "USD")
+			page, err := transactions.List(ctx, domain.TransactionFilter{
+				AccountID: usd, Types: []domain.EventType{domain.WithDraw, domain.Transfer}, MinAmount: &low, MaxAmount: &high, From: &from, To: &to,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Transaction{transfer, withdraw}, page.Transactions)
+
+			// the amount received is compared in the currency of the destination
+			low = domain.NewMoney(90000, "CLP")
+			page, err = transactions.List(ctx, domain.TransactionFilter{AccountID: clp, Types: []domain.EventType{domain.Transfer}, MinAmount: &low})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Transaction{transfer}, page.Transactions)
+
+			page, err = transactions.List(ctx, domain.TransactionFilter{AccountID: uuid.New()})
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Transaction{}, page.Transactions)
+		})
+		t.Run("list between oldest first", func(t *testing.T) {
+			between, err := transactions.ListBetween(ctx, usd, start, start.Add(2*time.Minute))
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Transaction{deposit, withdraw}, between)
+		})
+		t.Run("net change", func(t *testing.T) {
+			net, err := transactions.NetChangeSince(ctx, usd, start)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1000-300-100), net)
+
+			net, err = transactions.NetChangeSince(ctx, clp, start.Add(time.Hour))
+			assert.NoError(t, err)
+			assert.Zero(t, net)
+
+			net, err = transactions.NetChangeSince(ctx, clp, start)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(90050+5000), net)
+		})
+	})
+}
+
+func TestUnitOfWorkContract(t *testing.T) {
+	ctx := context.Background()
+	backends := map[string]func(t *testing.T) UnitOfWork{
+		"memory": func(t *testing.T) UnitOfWork {
+			return NewMemoryUnitOfWork(store.NewMemory(), Tx{
+				Accounts:     account.NewService(account.NewMemoryRepository(), account.NewMemoryEventStore(), account.NewMemorySnapshotStore(), account.DefaultSnapshotEvery),
+				Transactions: NewMemoryRepository(),
+			})
+		},
+		"sqlite": func(t *testing.T) UnitOfWork {
+			return NewSQLiteUnitOfWork(storetest.SQLite(t))
+		},
+		"mysql": func(t *testing.T) UnitOfWork {
+			return NewUnitOfWork(storetest.MySQL(t))
+		},
+		"postgres": func(t *testing.T) UnitOfWork {
+			return NewPostgresUnitOfWork(storetest.Postgres(t))
+		},
+	}
+
+	for name, newUnitOfWork := range backends {
+		t.Run(name, func(t *testing.T) {
+			uow := newUnitOfWork(t)
+			committed, rolledBack := uuid.New(), uuid.New()
+
+			err := uow.Do(ctx, func(ctx context.Context, tx Tx) error {
+				return tx.Accounts.Create(ctx, domain.Account{ID: committed, Name: "committed", Balance: domain.NewMoney(100, "USD")})
+			})
+			assert.NoError(t, err)
+
+			failure := errors.New("failure")
+			err = uow.Do(ctx, func(ctx context.Context, tx Tx) error {
+				if err := tx.Accounts.Create(ctx, domain.Account{ID: rolledBack, Name: "rolled back", Balance: domain.NewMoney(100, "USD")}); err != nil {
+					return err
+				}
+				acc, err := tx.Accounts.ReadForUpdate(ctx, committed)
+				if err != nil {
+					return err
+				}
+				if _, err = tx.Accounts.Record(ctx, acc, domain.AccountEvent{Type: domain.Deposit, Amount: domain.NewMoney(50, "USD")}); err != nil {
+					return err
+				}
+				return failure
+			})
+			assert.ErrorIs(t, err, failure)
+
+			err = uow.Do(ctx, func(ctx context.Context, tx Tx) error {
+				acc, err := tx.Accounts.ReadForUpdate(ctx, committed)
+				assert.NoError(t, err)
+				assert.Equal(t, domain.NewMoney(100, "USD"), acc.Balance)
+				assert.Equal(t, int64(1), acc.Version)
+
+				_, err = tx.Accounts.Read(ctx, rolledBack)
+				assert.ErrorIs(t, err, custom_errors.ErrNotFound)
+				return nil
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package transaction
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+type memoryRepository struct {
+	mu           sync.RWMutex
+	transactions []domain.Transaction
+	ids          map[uuid.UUID]bool
+}
+
+// NewMemoryRepository keeps the transactions in memory, returning them as they are read
+// back from the database
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		ids: make(map[uuid.UUID]bool),
+	}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, tr *domain.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ids[tr.ID] {
+		return store.ErrDuplicate
+	}
+	record, err := stored(*tr)
+	if err != nil {
+		return err
+	}
+	r.transactions = append(r.transactions, record)
+	r.ids[record.ID] = true
+
+	store.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i := range r.transactions {
+			if r.transactions[i].ID == record.ID {
+				r.transactions = append(r.transactions[:i:i], r.transactions[i+1:]...)
+				break
+			}
+		}
+		delete(r.ids, record.ID)
+	})
+	return nil
+}
+
+// stored copies the transaction with the fields the database keeps
+func stored(tr domain.Transaction) (domain.Transaction, error) {
+	tr.Currency = tr.Amount.Currency
+	tr.DestinationCurrency = ""
+	if tr.DestinationID != nil {
+		id := *tr.DestinationID
+		tr.DestinationID = &id
+	}
+	if tr.DestinationAmount != nil {
+		amount := *tr.DestinationAmount
+		tr.DestinationAmount, tr.DestinationCurrency = &amount, amount.Currency
+	}
+	if tr.Rate != nil {
+		rate, err := domain.ParseRate(tr.Rate.String())
+		if err != nil {
+			return domain.Transaction{}, err
+		}
+		tr.Rate = &rate
+	}
+	tr.Timestamp = tr.Timestamp.UTC()
+	return tr, nil
+}
+
+// involves reports whether the account sent or received the transaction
+func involves(tr domain.Transaction, accountID uuid.UUID) bool {
+	return tr.AccountID == accountID || (tr.DestinationID != nil && *tr.DestinationID == accountID)
+}
+
+// received reports whether the account is the destination of the transaction
+func received(tr domain.Transaction, accountID uuid.UUID) bool {
+	return tr.DestinationID != nil && *tr.DestinationID == accountID
+}
+
+// accountAmountOf is the amount of the transaction in the currency of the account, as
+// accountAmount computes it in SQL
+func accountAmountOf(tr domain.Transaction, accountID uuid.UUID) int64 {
+	if received(tr, accountID) && tr.DestinationAmount != nil {
+		return tr.DestinationAmount.Amount
+	}
+	return tr.Amount.Amount
+}
+
+func (r *memoryRepository) List(_ context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	var last *domain.Transaction
+	if filter.Cursor != "" {
+		c, err := store.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return domain.TransactionPage{}, err
+		}
+		last = &domain.Transaction{ID: c.ID, Timestamp: c.Time}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page := domain.TransactionPage{Transactions: []domain.Transaction{}}
+	for _, tr := range r.transactions {
+		switch {
+		case !involves(tr, filter.AccountID),
+			len(filter.Types) > 0 && !hasType(filter.Types, tr.Type),
+			filter.MinAmount != nil && accountAmountOf(tr, filter.AccountID) < filter.MinAmount.Amount,
+			filter.MaxAmount != nil && accountAmountOf(tr, filter.AccountID) > filter.MaxAmount.Amount,
+			filter.From != nil && tr.Timestamp.Before(*filter.From),
+			filter.To != nil && !tr.Timestamp.Before(*filter.To),
+			last != nil && !sortsBefore(tr, *last):
+			continue
+		}
+		page.Transactions = append(page.Transactions, tr)
+	}
+	sort.Slice(page.Transactions, func(i, j int) bool { return sortsBefore(page.Transactions[j], page.Transactions[i]) })
+
+	if len(page.Transactions) > limit {
+		page.Transactions = page.Transactions[:limit]
+		page.NextCursor = store.EncodeCursor(page.Transactions[limit-1].Timestamp, page.Transactions[limit-1].ID)
+	}
+	return page, nil
+}
+
+func (r *memoryRepository) ListBetween(_ context.Context, accountID uuid.UUID, from, to time.Time) ([]domain.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transactions := []domain.Transaction{}
+	for _, tr := range r.transactions {
+		if involves(tr, accountID) && !tr.Timestamp.Before(from) && tr.Timestamp.Before(to) {
+			transactions = append(transactions, tr)
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool { return sortsBefore(transactions[i], transactions[j]) })
+	return transactions, nil
+}
+
+func (r *memoryRepository) NetChangeSince(_ context.Context, accountID uuid.UUID, since time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var net int64
+	for _, tr := range r.transactions {
+		if !involves(tr, accountID) || tr.Timestamp.Before(since) {
+			continue
+		}
+		switch {
+		case tr.Type == domain.Deposit:
+			net += tr.Amount.Amount
+		case received(tr, accountID):
+			net += accountAmountOf(tr, accountID)
+		default:
+			net -= tr.Amount.Amount
+		}
+	}
+	return net, nil
+}
+
+// sortsBefore reports whether a is older than b, by timestamp and then by id as the
+// database sorts them
+func sortsBefore(a, b domain.Transaction) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+func hasType(types []domain.EventType, t domain.EventType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
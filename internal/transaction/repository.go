@@ -1,32 +1,70 @@
 package transaction
 
 import (
+	"context"
 	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
 )
 
+const selectTransactions = "SELECT id, account_id, destination_id, type, amount, currency, destination_amount, destination_currency, rate, timestamp FROM transactions "
+
+// accountAmount is the amount of a transaction in the currency of the account filtered,
+// which for transfers received is the converted amount
+const accountAmount = "(CASE WHEN destination_id = ? THEN COALESCE(destination_amount, amount) ELSE amount END)"
+
 type Repository interface {
-	Create(tr *domain.Transaction) error
+	Create(ctx context.Context, tr *domain.Transaction) error
+	List(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error)
+	ListBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]domain.Transaction, error)
+	NetChangeSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int64, error)
 }
 
 type repository struct {
-	db *sql.DB
+	db store.DBTX
 }
 
-func NewRepository(db *sql.DB) Repository {
+func NewRepository(db store.DBTX) Repository {
 	return &repository{
-		db: db,
+		db: store.Instrument(db, store.SystemMySQL, "transactions"),
 	}
 }
 
-func (r repository) Create(tr *domain.Transaction) error {
+// NewSQLiteRepository works on SQLite, which runs the same statements
+func NewSQLiteRepository(db store.DBTX) Repository {
+	return &repository{
+		db: store.Instrument(db, store.SystemSQLite, "transactions"),
+	}
+}
 
-	query := "INSERT INTO transactions (id, account_id, destination_id, type, amount, timestamp) VALUES (?, ?, ?, ?, ?, ?);"
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
+// NewPostgresRepository works on PostgreSQL, numbering the placeholders of the statements
+func NewPostgresRepository(db store.DBTX) Repository {
+	return &repository{
+		db: store.Instrument(store.Postgres(db), store.SystemPostgreSQL, "transactions"),
 	}
-	res, err := stmt.Exec(tr.ID, tr.AccountID, tr.DestinationID, tr.Type, tr.Amount, tr.Timestamp)
+}
+
+func (r repository) Create(ctx context.Context, tr *domain.Transaction) error {
+	var destinationAmount, destinationCurrency, rate interface{}
+	if tr.DestinationAmount != nil {
+		destinationAmount, destinationCurrency = tr.DestinationAmount.Amount, tr.DestinationAmount.Currency
+	}
+	if tr.Rate != nil {
+		rate = tr.Rate.String()
+	}
+
+	query := "INSERT INTO transactions (id, account_id, destination_id, type, amount, currency, destination_amount, destination_currency, rate, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	res, err := r.db.ExecContext(ctx, query, tr.ID, tr.AccountID, tr.DestinationID, tr.Type, tr.Amount.Amount, tr.Amount.Currency,
+		destinationAmount, destinationCurrency, rate, tr.Timestamp)
 	if err != nil {
 		return err
 	}
@@ -37,3 +75,122 @@ func (r repository) Create(tr *domain.Transaction) error {
 
 	return nil
 }
+
+// List returns a page of the transactions of an account, newest first, including the
+// transfers it received
+func (r repository) List(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	conditions := []string{"(account_id = ? OR destination_id = ?)"}
+	args := []interface{}{filter.AccountID, filter.AccountID}
+	if len(filter.Types) > 0 {
+		placeholders := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			placeholders[i] = "?"
+			args = append(args, t)
+		}
+		conditions = append(conditions, "type IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, accountAmount+" >= ?")
+		args = append(args, filter.AccountID, filter.MinAmount.Amount)
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, accountAmount+" <= ?")
+		args = append(args, filter.AccountID, filter.MaxAmount.Amount)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.Cursor != "" {
+		c, err := store.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return domain.TransactionPage{}, err
+		}
+		conditions = append(conditions, "(timestamp < ? OR (timestamp = ? AND id < ?))")
+		args = append(args, c.Time, c.Time, c.ID)
+	}
+	// one extra row tells whether there is a next page
+	args = append(args, limit+1)
+
+	query := selectTransactions + "WHERE " + strings.Join(conditions, " AND ") + " ORDER BY timestamp DESC, id DESC LIMIT ?;"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return domain.TransactionPage{}, err
+	}
+	defer rows.Close()
+
+	page := domain.TransactionPage{}
+	page.Transactions, err = scanTransactions(rows)
+	if err != nil {
+		return domain.TransactionPage{}, err
+	}
+
+	if len(page.Transactions) > limit {
+		page.Transactions = page.Transactions[:limit]
+		page.NextCursor = store.EncodeCursor(page.Transactions[limit-1].Timestamp, page.Transactions[limit-1].ID)
+	}
+	return page, nil
+}
+
+// ListBetween returns the transactions of an account from the start time, inclusive, to
+// the end time, exclusive, oldest first
+func (r repository) ListBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]domain.Transaction, error) {
+	query := selectTransactions + "WHERE (account_id = ? OR destination_id = ?) AND timestamp >= ? AND timestamp < ? ORDER BY timestamp, id;"
+	rows, err := r.db.QueryContext(ctx, query, accountID, accountID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// NetChangeSince returns how much the transactions made since the given time changed the
+// balance of the account, in minor units
+func (r repository) NetChangeSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int64, error) {
+	query := "SELECT COALESCE(SUM(CASE WHEN type = 'deposit' THEN amount WHEN destination_id = ? THEN COALESCE(destination_amount, amount) ELSE -amount END), 0) " +
+		"FROM transactions WHERE (account_id = ? OR destination_id = ?) AND timestamp >= ?;"
+	var net int64
+	err := r.db.QueryRowContext(ctx, query, accountID, accountID, accountID, since.UTC()).Scan(&net)
+	return net, err
+}
+
+func scanTransactions(rows *sql.Rows) ([]domain.Transaction, error) {
+	transactions := []domain.Transaction{}
+	for rows.Next() {
+		var tr domain.Transaction
+		var destinationAmount sql.NullInt64
+		var destinationCurrency, rate sql.NullString
+		err := rows.Scan(&tr.ID, &tr.AccountID, &tr.DestinationID, &tr.Type, &tr.Amount.Amount, &tr.Amount.Currency,
+			&destinationAmount, &destinationCurrency, &rate, &tr.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		tr.Currency, tr.Timestamp = tr.Amount.Currency, tr.Timestamp.UTC()
+		if destinationAmount.Valid {
+			amount := domain.NewMoney(destinationAmount.Int64, destinationCurrency.String)
+			tr.DestinationAmount, tr.DestinationCurrency = &amount, destinationCurrency.String
+		}
+		if rate.Valid {
+			parsed, err := domain.ParseRate(rate.String)
+			if err != nil {
+				return nil, err
+			}
+			tr.Rate = &parsed
+		}
+		transactions = append(transactions, tr)
+	}
+	return transactions, rows.Err()
+}
@@ -1,10 +1,14 @@
 package transaction
 
 import (
+	"context"
 	_ "database/sql"
 	"errors"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
@@ -21,9 +25,9 @@ func TestCreateTransaction(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().WithArgs(
-			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
-			sqlmock.AnyArg(), sqlmock.AnyArg(),
+		mock.ExpectExec("INSERT INTO transactions").WithArgs(
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 
 		tr := domain.Transaction{
@@ -32,13 +36,13 @@ func TestCreateTransaction(t *testing.T) {
 			Type:      domain.Deposit,
 		}
 
-		err = repo.Create(&tr)
+		err = repo.Create(context.Background(), &tr)
 		assert.NoError(t, err)
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
-	t.Run("create transaction prepare error", func(t *testing.T) {
+	t.Run("create transaction exec error", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
@@ -47,8 +51,10 @@ func TestCreateTransaction(t *testing.T) {
 
 		repo := NewRepository(db)
 
-		mock.ExpectPrepare("INSERT INTO transactions").
-			WillReturnError(errors.New("test error"))
+		mock.ExpectExec("INSERT INTO transactions").WithArgs(
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+		).WillReturnError(errors.New("test error"))
 
 		tr := domain.Transaction{
 			ID:        uuid.New(),
@@ -56,38 +62,227 @@ func TestCreateTransaction(t *testing.T) {
 			Type:      domain.Deposit,
 		}
 
-		err = repo.Create(&tr)
+		err = repo.Create(context.Background(), &tr)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "test error")
 
 		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
 	})
-	t.Run("create transaction exec error", func(t *testing.T) {
+}
+
+var transactionColumns = []string{"id", "account_id", "destination_id", "type", "amount", "currency", "destination_amount", "destination_currency", "rate", "timestamp"}
+
+func TestListTransactions(t *testing.T) {
+	accountID := uuid.New()
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	row := func(rows *sqlmock.Rows, i int) *sqlmock.Rows {
+		return rows.AddRow(uuid.New(), accountID, nil, domain.Deposit, int64(100*(i+1)), "USD", nil, nil, nil, base.Add(-time.Duration(i)*time.Minute))
+	}
+
+	t.Run("list first page with next cursor", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		if err != nil {
 			t.Fail()
 		}
 		defer db.Close()
 
-		repo := NewRepository(db)
+		rows := sqlmock.NewRows(transactionColumns)
+		for i := 0; i < 3; i++ {
+			row(rows, i)
+		}
+		mock.ExpectQuery(`SELECT id, account_id, destination_id, type, amount, currency, destination_amount, destination_currency, rate, timestamp FROM transactions `+
+			`WHERE \(account_id = \? OR destination_id = \?\) ORDER BY timestamp DESC, id DESC LIMIT \?;`).
+			WithArgs(accountID, accountID, 3).
+			WillReturnRows(rows)
 
-		mock.ExpectPrepare("INSERT INTO transactions").ExpectExec().WithArgs(
-			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
-			sqlmock.AnyArg(), sqlmock.AnyArg(),
-		).WillReturnError(errors.New("test error"))
+		page, err := NewRepository(db).List(context.Background(), domain.TransactionFilter{AccountID: accountID, Limit: 2})
 
-		tr := domain.Transaction{
-			ID:        uuid.New(),
-			AccountID: uuid.New(),
-			Type:      domain.Deposit,
+		assert.NoError(t, err)
+		assert.Len(t, page.Transactions, 2)
+		assert.Equal(t, int64(200), page.Transactions[1].Amount.Amount)
+		assert.NotEmpty(t, page.NextCursor)
+
+		c, err := store.DecodeCursor(page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, page.Transactions[1].ID, c.ID)
+		assert.True(t, page.Transactions[1].Timestamp.Equal(c.Time))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list last page with all filters", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		last := domain.Transaction{ID: uuid.New(), Timestamp: base}
+		from, to := base.Add(-24*time.Hour), base
+		min, max := domain.NewMoney(100, "USD"), domain.NewMoney(1000, "USD")
+
+		mock.ExpectQuery(`WHERE \(account_id = \? OR destination_id = \?\) AND type IN \(\?, \?\) `+
+			`AND \(CASE WHEN destination_id = \? THEN COALESCE\(destination_amount, amount\) ELSE amount END\) >= \? `+
+			`AND \(CASE WHEN destination_id = \? THEN COALESCE\(destination_amount, amount\) ELSE amount END\) <= \? AND timestamp >= \? AND timestamp < \? `+
+			`AND \(timestamp < \? OR \(timestamp = \? AND id < \?\)\) ORDER BY timestamp DESC, id DESC LIMIT \?;`).
+			WithArgs(accountID, accountID, domain.Deposit, domain.Transfer, accountID, int64(100), accountID, int64(1000), from, to,
+				base, base, last.ID, DefaultPageSize+1).
+			WillReturnRows(row(sqlmock.NewRows(transactionColumns), 0))
+
+		page, err := NewRepository(db).List(context.Background(), domain.TransactionFilter{
+			AccountID: accountID,
+			Types:     []domain.EventType{domain.Deposit, domain.Transfer},
+			MinAmount: &min,
+			MaxAmount: &max,
+			From:      &from,
+			To:        &to,
+			Cursor:    store.EncodeCursor(last.Timestamp, last.ID),
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Transactions, 1)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list last page with all filters on postgres", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		last := domain.Transaction{ID: uuid.New(), Timestamp: base}
+		from, to := base.Add(-24*time.Hour), base
+		min, max := domain.NewMoney(100, "USD"), domain.NewMoney(1000, "USD")
+
+		mock.ExpectQuery(`WHERE \(account_id = \$1 OR destination_id = \$2\) AND type IN \(\$3, \$4\) `+
+			`AND \(CASE WHEN destination_id = \$5 THEN COALESCE\(destination_amount, amount\) ELSE amount END\) >= \$6 `+
+			`AND \(CASE WHEN destination_id = \$7 THEN COALESCE\(destination_amount, amount\) ELSE amount END\) <= \$8 AND timestamp >= \$9 AND timestamp < \$10 `+
+			`AND \(timestamp < \$11 OR \(timestamp = \$12 AND id < \$13\)\) ORDER BY timestamp DESC, id DESC LIMIT \$14;`).
+			WithArgs(accountID, accountID, domain.Deposit, domain.Transfer, accountID, int64(100), accountID, int64(1000), from, to,
+				base, base, last.ID, DefaultPageSize+1).
+			WillReturnRows(row(sqlmock.NewRows(transactionColumns), 0))
+
+		page, err := NewPostgresRepository(db).List(context.Background(), domain.TransactionFilter{
+			AccountID: accountID,
+			Types:     []domain.EventType{domain.Deposit, domain.Transfer},
+			MinAmount: &min,
+			MaxAmount: &max,
+			From:      &from,
+			To:        &to,
+			Cursor:    store.EncodeCursor(last.Timestamp, last.ID),
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Transactions, 1)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list empty page", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT id, account_id").
+			WithArgs(accountID, accountID, MaxPageSize+1).
+			WillReturnRows(sqlmock.NewRows(transactionColumns))
+
+		page, err := NewRepository(db).List(context.Background(), domain.TransactionFilter{AccountID: accountID, Limit: 1000})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, page.Transactions)
+		assert.Empty(t, page.Transactions)
+	})
+	t.Run("list invalid cursor", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
 		}
+		defer db.Close()
+
+		_, err = NewRepository(db).List(context.Background(), domain.TransactionFilter{AccountID: accountID, Cursor: "not a cursor"})
+
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidCursor)
+	})
+	t.Run("list query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT id, account_id").WillReturnError(errors.New("test error"))
+
+		_, err = NewRepository(db).List(context.Background(), domain.TransactionFilter{AccountID: accountID})
 
-		err = repo.Create(&tr)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "test error")
+	})
+}
+
+func TestListTransactionsBetween(t *testing.T) {
+	accountID := uuid.New()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("list between success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(`WHERE \(account_id = \? OR destination_id = \?\) AND timestamp >= \? AND timestamp < \? ORDER BY timestamp, id;`).
+			WithArgs(accountID, accountID, from, to).
+			WillReturnRows(sqlmock.NewRows(transactionColumns).
+				AddRow(uuid.New(), accountID, nil, domain.Deposit, int64(100), "USD", nil, nil, nil, from).
+				AddRow(uuid.New(), uuid.New(), accountID, domain.Transfer, int64(50), "USD", int64(46), "EUR", "0.92", from.Add(time.Hour)))
+
+		transactions, err := NewRepository(db).ListBetween(context.Background(), accountID, from, to)
+
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 2)
+		assert.Nil(t, transactions[0].Rate)
+		assert.Equal(t, "USD", transactions[0].Currency)
+		assert.Equal(t, domain.Transfer, transactions[1].Type)
+		assert.Equal(t, domain.NewMoney(46, "EUR"), *transactions[1].DestinationAmount)
+		assert.Equal(t, "EUR", transactions[1].DestinationCurrency)
+		assert.Equal(t, "0.92", transactions[1].Rate.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("list between query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT id, account_id").WillReturnError(errors.New("test error"))
+
+		_, err = NewRepository(db).ListBetween(context.Background(), accountID, from, to)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNetChangeSince(t *testing.T) {
+	t.Run("net change success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		accountID := uuid.New()
+		since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(CASE WHEN type = 'deposit' THEN amount WHEN destination_id = \? THEN COALESCE\(destination_amount, amount\) ELSE -amount END\), 0\) `+
+			`FROM transactions WHERE \(account_id = \? OR destination_id = \?\) AND timestamp >= \?;`).
+			WithArgs(accountID, accountID, accountID, since).
+			WillReturnRows(sqlmock.NewRows([]string{"net"}).AddRow(int64(-250)))
+
+		net, err := NewRepository(db).NetChangeSince(context.Background(), accountID, since)
 
-		err = mock.ExpectationsWereMet()
 		assert.NoError(t, err)
+		assert.Equal(t, int64(-250), net)
 	})
 }
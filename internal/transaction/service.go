@@ -1,52 +1,187 @@
 package transaction
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/account"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
 	"github.com/lucaspichi06/xepelin-bank/internal/events"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"time"
 )
 
+var (
+	processedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bank_transactions_processed_total",
+		Help: "Transactions committed, by type.",
+	}, []string{"type"})
+	failedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bank_transactions_failed_total",
+		Help: "Transactions rejected or failed, by type and error class.",
+	}, []string{"type", "error"})
+)
+
 type Service interface {
-	Create(tr *domain.Transaction) error
+	// Create processes the transaction, logging its event with the logger of the context
+	Create(ctx context.Context, tr *domain.Transaction) error
+	List(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error)
+	// UpdateStatus freezes, closes or reopens the account, sweeping its balance to the
+	// destination of the request when it is closed with money left
+	UpdateStatus(ctx context.Context, id uuid.UUID, req domain.AccountStatusRequest) (domain.Account, error)
 }
 
 type service struct {
-	s account.Service
-	r Repository
+	uow   UnitOfWork
+	rates domain.FXRateProvider
 }
 
-func NewService(r Repository, s account.Service) Service {
+func NewService(uow UnitOfWork, rates domain.FXRateProvider) Service {
 	return &service{
-		r: r,
-		s: s,
+		uow:   uow,
+		rates: rates,
 	}
 }
 
-func (s service) Create(tr *domain.Transaction) error {
-	var event domain.Event
+// Create processes the transaction and stores it with its journal entry. The balance
+// changes, the transaction record and the ledger postings are committed together, or not at all
+func (s service) Create(ctx context.Context, tr *domain.Transaction) (err error) {
+	defer func() { observe(tr.Type, err) }()
+
 	switch tr.Type {
-	case domain.Deposit:
-		tr.DestinationID = nil
-		event = events.NewDepositEvent(tr.AccountID, tr.Amount, s.s)
-	case domain.WithDraw:
+	case domain.Deposit, domain.WithDraw:
 		tr.DestinationID = nil
-		event = events.NewWithdrawEvent(tr.AccountID, tr.Amount, s.s)
 	case domain.Transfer:
 		if tr.DestinationID == nil {
 			return custom_errors.ErrInvalidTransactionDestination
 		}
-		event = events.NewTransferEvent(tr.AccountID, *tr.DestinationID, tr.Amount, s.s)
 	default:
 		return custom_errors.ErrInvalidTransactionType
 	}
+	if !tr.HasPositiveAmount() {
+		return custom_errors.ErrInvalidAmount
+	}
+	// the conversion is always computed here, never taken from the request
+	tr.Rate, tr.DestinationAmount, tr.DestinationCurrency = nil, nil, ""
 	tr.ID = uuid.New()
-	tr.Timestamp = time.Now().Format(time.RFC850)
+	// the column keeps microseconds, so the pagination cursors match the stored value
+	tr.Timestamp = time.Now().UTC().Truncate(time.Microsecond)
+
+	return s.uow.Do(ctx, func(ctx context.Context, tx Tx) error {
+		if _, err := events.Observed(newEvent(tr, tx.Accounts, s.rates)).Process(ctx); err != nil {
+			return err
+		}
+		if err := tx.Transactions.Create(ctx, tr); err != nil {
+			return err
+		}
+		_, err := tx.Ledger.Record(ctx, *tr)
+		return err
+	})
+}
+
+// List returns a page of the transactions history of an existing account
+func (s service) List(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	var page domain.TransactionPage
+	err := s.uow.Do(ctx, func(ctx context.Context, tx Tx) error {
+		acc, err := tx.Accounts.Read(ctx, filter.AccountID)
+		if err != nil {
+			return err
+		}
+		// the amounts filtered are in the account currency
+		if filter.MinAmount, err = resolveValue(filter.MinValue, acc.Currency); err != nil {
+			return err
+		}
+		if filter.MaxAmount, err = resolveValue(filter.MaxValue, acc.Currency); err != nil {
+			return err
+		}
+		page, err = tx.Transactions.List(ctx, filter)
+		return err
+	})
+	return page, err
+}
+
+func resolveValue(value domain.Decimal, currency string) (*domain.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := value.Money(currency)
+	if err != nil {
+		return nil, err
+	}
+	return &amount, nil
+}
+
+// UpdateStatus changes the status of the account. The sweep transfer, when there is one,
+// is committed together with the status change, so an account is never closed half swept
+func (s service) UpdateStatus(ctx context.Context, id uuid.UUID, req domain.AccountStatusRequest) (domain.Account, error) {
+	if req.SweepDestinationID != nil && (req.Status != domain.AccountClosed || *req.SweepDestinationID == id) {
+		return domain.Account{}, custom_errors.ErrInvalidSweep
+	}
+
+	var acc domain.Account
+	err := s.uow.Do(ctx, func(ctx context.Context, tx Tx) error {
+		if req.SweepDestinationID != nil {
+			if err := s.sweep(ctx, tx, id, *req.SweepDestinationID); err != nil {
+				return err
+			}
+		}
+		var err error
+		acc, err = events.Observed(events.NewStatusEvent(id, req.Status, tx.Accounts)).Process(ctx)
+		return err
+	})
+	return acc, err
+}
+
+// sweep transfers the whole balance of the account to the destination, doing nothing
+// when there is no money left
+func (s service) sweep(ctx context.Context, tx Tx, id, destination uuid.UUID) (err error) {
+	acc, err := tx.Accounts.ReadForUpdate(ctx, id)
+	if err != nil || !acc.Balance.IsPositive() {
+		return err
+	}
+	defer func() { observe(domain.Transfer, err) }()
 
-	if _, err := event.Process(); err != nil {
+	tr := &domain.Transaction{
+		ID:            uuid.New(),
+		AccountID:     id,
+		DestinationID: &destination,
+		Type:          domain.Transfer,
+		Amount:        acc.Balance,
+		Timestamp:     time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if _, err = events.Observed(events.NewSweepEvent(tr, tx.Accounts, s.rates)).Process(ctx); err != nil {
 		return err
 	}
-	return s.r.Create(tr)
+	if err = tx.Transactions.Create(ctx, tr); err != nil {
+		return err
+	}
+	_, err = tx.Ledger.Record(ctx, *tr)
+	return err
+}
+
+// observe counts the transaction as processed or failed. Unknown types are counted
+// together, so a caller can not create series at will
+func observe(t domain.EventType, err error) {
+	switch t {
+	case domain.Deposit, domain.WithDraw, domain.Transfer:
+	default:
+		t = "unknown"
+	}
+	if err != nil {
+		failedTotal.WithLabelValues(string(t), custom_errors.Class(err)).Inc()
+		return
+	}
+	processedTotal.WithLabelValues(string(t)).Inc()
+}
+
+func newEvent(tr *domain.Transaction, accounts account.Service, rates domain.FXRateProvider) domain.Event {
+	switch tr.Type {
+	case domain.Deposit:
+		return events.NewDepositEvent(tr, accounts)
+	case domain.WithDraw:
+		return events.NewWithdrawEvent(tr, accounts)
+	default:
+		return events.NewTransferEvent(tr, accounts, rates)
+	}
 }
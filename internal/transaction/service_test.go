@@ -1,49 +1,142 @@
 package transaction
 
 import (
+	"context"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/fx"
+	"github.com/lucaspichi06/xepelin-bank/internal/ledger"
 	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
+	"time"
 )
 
+// noRates only converts a currency to itself
+var noRates, _ = fx.NewStaticProvider(nil)
+
 type accServiceMock struct {
-	create func(account domain.Account) error
-	read   func(id uuid.UUID) (domain.Account, error)
-	update func(account domain.Account) error
+	create        func(account domain.Account) error
+	read          func(id uuid.UUID) (domain.Account, error)
+	readForUpdate func(id uuid.UUID) (domain.Account, error)
+	record        func(account domain.Account, event domain.AccountEvent) (domain.Account, error)
+	rebuild       func(id uuid.UUID) (domain.Account, error)
+	snapshot      func(id uuid.UUID) (domain.Account, error)
+	list          func(filter domain.AccountFilter) (domain.AccountPage, error)
 }
 
-func (a accServiceMock) Create(account domain.Account) error {
+func (a accServiceMock) Create(ctx context.Context, account domain.Account) error {
 	return a.create(account)
 }
 
-func (a accServiceMock) Read(id uuid.UUID) (domain.Account, error) {
+func (a accServiceMock) Read(ctx context.Context, id uuid.UUID) (domain.Account, error) {
 	return a.read(id)
 }
 
-func (a accServiceMock) Update(account domain.Account) error {
-	return a.update(account)
+func (a accServiceMock) ReadForUpdate(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.readForUpdate(id)
+}
+
+func (a accServiceMock) Record(ctx context.Context, account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+	return a.record(account, event)
+}
+
+func (a accServiceMock) Rebuild(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.rebuild(id)
+}
+
+func (a accServiceMock) Snapshot(ctx context.Context, id uuid.UUID) (domain.Account, error) {
+	return a.snapshot(id)
+}
+
+func (a accServiceMock) List(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	return a.list(filter)
 }
 
 type trRepositoryMock struct {
 	create func(tr *domain.Transaction) error
+	list   func(filter domain.TransactionFilter) (domain.TransactionPage, error)
 }
 
-func (t trRepositoryMock) Create(tr *domain.Transaction) error {
+func (t trRepositoryMock) Create(ctx context.Context, tr *domain.Transaction) error {
 	return t.create(tr)
 }
 
+func (t trRepositoryMock) List(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	return t.list(filter)
+}
+
+func (t trRepositoryMock) ListBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time) ([]domain.Transaction, error) {
+	return nil, nil
+}
+
+func (t trRepositoryMock) NetChangeSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int64, error) {
+	return 0, nil
+}
+
+// memoryLedger keeps the journal entries in memory
+type memoryLedger struct {
+	mu      sync.Mutex
+	entries []domain.JournalEntry
+	err     error
+}
+
+func (m *memoryLedger) Append(ctx context.Context, entry domain.JournalEntry) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *memoryLedger) Totals(ctx context.Context) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	totals := make(map[string]int64)
+	for _, e := range m.entries {
+		for _, p := range e.Postings {
+			totals[p.Amount.Currency] += p.Amount.Amount
+		}
+	}
+	return totals, nil
+}
+
+type uowMock struct {
+	accounts     accServiceMock
+	transactions trRepositoryMock
+	ledger       *memoryLedger
+}
+
+func (u uowMock) Do(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	entries := u.ledger
+	if entries == nil {
+		entries = &memoryLedger{}
+	}
+	return fn(ctx, Tx{
+		Accounts:     u.accounts,
+		Transactions: u.transactions,
+		Ledger:       ledger.NewService(entries),
+	})
+}
+
 func TestTransactionCreate(t *testing.T) {
 	t.Run("transaction deposit success", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID: uuid.New(),
+					ID:       uuid.New(),
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
@@ -53,24 +146,28 @@ func TestTransactionCreate(t *testing.T) {
 			},
 		}
 
-		trService := NewService(repoMock, serviceMock)
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
 		tr := domain.Transaction{
-			Type: domain.Deposit,
+			Amount: domain.NewMoney(10000, domain.DefaultCurrency),
+			Type:   domain.Deposit,
 		}
 
-		err := trService.Create(&tr)
+		err := trService.Create(context.Background(), &tr)
 
 		assert.NoError(t, err)
 	})
 	t.Run("transaction withdraw success", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID: uuid.New(),
+					ID:       uuid.New(),
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
@@ -80,24 +177,28 @@ func TestTransactionCreate(t *testing.T) {
 			},
 		}
 
-		trService := NewService(repoMock, serviceMock)
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
 		tr := domain.Transaction{
-			Type: domain.WithDraw,
+			Amount: domain.NewMoney(10000, domain.DefaultCurrency),
+			Type:   domain.WithDraw,
 		}
 
-		err := trService.Create(&tr)
+		err := trService.Create(context.Background(), &tr)
 
 		assert.NoError(t, err)
 	})
 	t.Run("transaction transfer success", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID: uuid.New(),
+					ID:       uuid.New(),
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
@@ -107,26 +208,30 @@ func TestTransactionCreate(t *testing.T) {
 			},
 		}
 
-		trService := NewService(repoMock, serviceMock)
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
 		id := uuid.New()
 		tr := domain.Transaction{
+			Amount:        domain.NewMoney(10000, domain.DefaultCurrency),
 			Type:          domain.Transfer,
 			DestinationID: &id,
 		}
 
-		err := trService.Create(&tr)
+		err := trService.Create(context.Background(), &tr)
 
 		assert.NoError(t, err)
 	})
 	t.Run("transaction transfer error - invalid destination id", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID: uuid.New(),
+					ID:       uuid.New(),
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
@@ -136,23 +241,25 @@ func TestTransactionCreate(t *testing.T) {
 			},
 		}
 
-		trService := NewService(repoMock, serviceMock)
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
 		tr := domain.Transaction{
-			Type: domain.Transfer,
+			Amount: domain.NewMoney(10000, domain.DefaultCurrency),
+			Type:   domain.Transfer,
 		}
 
-		err := trService.Create(&tr)
+		err := trService.Create(context.Background(), &tr)
 
 		assert.Error(t, err)
 		assert.Equal(t, custom_errors.ErrInvalidTransactionDestination, err)
 	})
 	t.Run("transaction transfer error - invalid account id", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
@@ -162,27 +269,43 @@ func TestTransactionCreate(t *testing.T) {
 			},
 		}
 
-		trService := NewService(repoMock, serviceMock)
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
 		id := uuid.New()
 		tr := domain.Transaction{
+			Amount:        domain.NewMoney(10000, domain.DefaultCurrency),
 			Type:          domain.Transfer,
 			DestinationID: &id,
 		}
 
-		err := trService.Create(&tr)
+		err := trService.Create(context.Background(), &tr)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "not found")
 	})
+	t.Run("transaction invalid amount error", func(t *testing.T) {
+		trService := NewService(uowMock{}, noRates)
+		tr := domain.Transaction{
+			Amount: domain.NewMoney(-10000, domain.DefaultCurrency),
+			Type:   domain.Deposit,
+		}
+
+		err := trService.Create(context.Background(), &tr)
+
+		assert.Error(t, err)
+		assert.Equal(t, custom_errors.ErrInvalidAmount, err)
+	})
 	t.Run("transaction invalid type error", func(t *testing.T) {
 		serviceMock := accServiceMock{
-			read: func(id uuid.UUID) (domain.Account, error) {
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
 				return domain.Account{
-					ID: uuid.New(),
+					ID:       uuid.New(),
+					Currency: domain.DefaultCurrency,
+					Balance:  domain.NewMoney(100000, domain.DefaultCurrency),
 				}, nil
 			},
-			update: func(account domain.Account) error {
-				return nil
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				return account, nil
 			},
 		}
 
@@ -192,14 +315,306 @@ func TestTransactionCreate(t *testing.T) {
 			},
 		}
 
-		trService := NewService(repoMock, serviceMock)
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
 		tr := domain.Transaction{
-			Type: domain.Create,
+			Amount: domain.NewMoney(10000, domain.DefaultCurrency),
+			Type:   domain.Create,
 		}
 
-		err := trService.Create(&tr)
+		err := trService.Create(context.Background(), &tr)
 
 		assert.Error(t, err)
 		assert.Equal(t, custom_errors.ErrInvalidTransactionType, err)
 	})
 }
+
+func TestTransactionLedger(t *testing.T) {
+	accounts := accServiceMock{
+		readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+			return domain.Account{ID: id, Currency: "USD", Balance: domain.NewMoney(100000, "USD")}, nil
+		},
+		record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+			account.Apply(event)
+			return account, nil
+		},
+	}
+	transactions := trRepositoryMock{
+		create: func(tr *domain.Transaction) error {
+			return nil
+		},
+	}
+
+	t.Run("transactions post balanced journal entries", func(t *testing.T) {
+		entries := &memoryLedger{}
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions, ledger: entries}, noRates)
+		destination := uuid.New()
+
+		for _, tr := range []domain.Transaction{
+			{AccountID: uuid.New(), Type: domain.Deposit, Amount: domain.NewMoney(1000, "USD")},
+			{AccountID: uuid.New(), Type: domain.WithDraw, Amount: domain.NewMoney(300, "USD")},
+			{AccountID: uuid.New(), DestinationID: &destination, Type: domain.Transfer, Amount: domain.NewMoney(200, "USD")},
+		} {
+			tr := tr
+			assert.NoError(t, trService.Create(context.Background(), &tr))
+		}
+
+		assert.Len(t, entries.entries, 3)
+		assert.Len(t, entries.entries[2].Postings, 4)
+		verification, err := ledger.NewService(entries).Verify(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, verification.Balanced)
+	})
+	t.Run("ledger error fails the transaction", func(t *testing.T) {
+		entries := &memoryLedger{err: errors.New("ledger error")}
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions, ledger: entries}, noRates)
+
+		err := trService.Create(context.Background(), &domain.Transaction{AccountID: uuid.New(), Type: domain.Deposit, Amount: domain.NewMoney(1000, "USD")})
+
+		assert.EqualError(t, err, "ledger error")
+	})
+}
+
+func TestTransactionList(t *testing.T) {
+	t.Run("list success", func(t *testing.T) {
+		id := uuid.New()
+		serviceMock := accServiceMock{
+			read: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{ID: id}, nil
+			},
+		}
+		repoMock := trRepositoryMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				assert.Equal(t, id, filter.AccountID)
+				return domain.TransactionPage{
+					Transactions: []domain.Transaction{{ID: uuid.New(), AccountID: id}},
+					NextCursor:   "next",
+				}, nil
+			},
+		}
+
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
+		page, err := trService.List(context.Background(), domain.TransactionFilter{AccountID: id})
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Transactions, 1)
+		assert.Equal(t, "next", page.NextCursor)
+	})
+	t.Run("list account not found", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			read: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{}, custom_errors.ErrNotFound
+			},
+		}
+		repoMock := trRepositoryMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				t.Fatal("transactions listed for a missing account")
+				return domain.TransactionPage{}, nil
+			},
+		}
+
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
+		_, err := trService.List(context.Background(), domain.TransactionFilter{AccountID: uuid.New()})
+
+		assert.ErrorIs(t, err, custom_errors.ErrNotFound)
+	})
+	t.Run("list amounts in the account currency", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			read: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{ID: id, Currency: "KWD"}, nil
+			},
+		}
+		repoMock := trRepositoryMock{
+			list: func(filter domain.TransactionFilter) (domain.TransactionPage, error) {
+				assert.Equal(t, domain.NewMoney(5, "KWD"), *filter.MinAmount)
+				assert.Equal(t, domain.NewMoney(1234, "KWD"), *filter.MaxAmount)
+				return domain.TransactionPage{}, nil
+			},
+		}
+
+		trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
+		_, err := trService.List(context.Background(), domain.TransactionFilter{AccountID: uuid.New(), MinValue: "0.005", MaxValue: "1.234"})
+
+		assert.NoError(t, err)
+	})
+	t.Run("list amount too precise for the account", func(t *testing.T) {
+		serviceMock := accServiceMock{
+			read: func(id uuid.UUID) (domain.Account, error) {
+				return domain.Account{ID: id, Currency: "USD"}, nil
+			},
+		}
+
+		trService := NewService(uowMock{accounts: serviceMock}, noRates)
+		_, err := trService.List(context.Background(), domain.TransactionFilter{AccountID: uuid.New(), MinValue: "0.005"})
+
+		assert.ErrorIs(t, err, custom_errors.ErrInvalidAmountPrecision)
+	})
+}
+
+func TestTransactionMetrics(t *testing.T) {
+	serviceMock := accServiceMock{
+		readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+			return domain.Account{ID: id, Currency: domain.DefaultCurrency, Balance: domain.NewMoney(1000, domain.DefaultCurrency)}, nil
+		},
+		record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+			account.Apply(event)
+			return account, nil
+		},
+	}
+	repoMock := trRepositoryMock{
+		create: func(tr *domain.Transaction) error {
+			return nil
+		},
+	}
+	trService := NewService(uowMock{accounts: serviceMock, transactions: repoMock}, noRates)
+
+	t.Run("count processed transaction", func(t *testing.T) {
+		before := testutil.ToFloat64(processedTotal.WithLabelValues("deposit"))
+
+		assert.NoError(t, trService.Create(context.Background(), &domain.Transaction{AccountID: uuid.New(), Type: domain.Deposit, Amount: domain.NewMoney(100, "USD")}))
+
+		assert.Equal(t, before+1, testutil.ToFloat64(processedTotal.WithLabelValues("deposit")))
+	})
+	t.Run("count failed transaction", func(t *testing.T) {
+		before := testutil.ToFloat64(failedTotal.WithLabelValues("withdraw", "insufficient_balance"))
+
+		err := trService.Create(context.Background(), &domain.Transaction{AccountID: uuid.New(), Type: domain.WithDraw, Amount: domain.NewMoney(2000, "USD")})
+
+		assert.ErrorIs(t, err, custom_errors.ErrInsuficientBalance)
+		assert.Equal(t, before+1, testutil.ToFloat64(failedTotal.WithLabelValues("withdraw", "insufficient_balance")))
+	})
+	t.Run("count unknown type", func(t *testing.T) {
+		before := testutil.ToFloat64(failedTotal.WithLabelValues("unknown", "invalid_transaction_type"))
+
+		err := trService.Create(context.Background(), &domain.Transaction{AccountID: uuid.New(), Type: "refund", Amount: domain.NewMoney(100, "USD")})
+
+		assert.Error(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(failedTotal.WithLabelValues("unknown", "invalid_transaction_type")))
+	})
+}
+
+func TestUpdateStatus(t *testing.T) {
+	id, destination := uuid.New(), uuid.New()
+	// stored keeps the accounts as the events recorded leave them
+	newAccounts := func(balance int64) (accServiceMock, map[uuid.UUID]domain.Account) {
+		stored := map[uuid.UUID]domain.Account{
+			id:          {ID: id, Currency: "USD", Balance: domain.NewMoney(balance, "USD"), Status: domain.AccountActive},
+			destination: {ID: destination, Currency: "USD", Balance: domain.NewMoney(0, "USD"), Status: domain.AccountActive},
+		}
+		return accServiceMock{
+			readForUpdate: func(id uuid.UUID) (domain.Account, error) {
+				return stored[id], nil
+			},
+			record: func(account domain.Account, event domain.AccountEvent) (domain.Account, error) {
+				account.Apply(event)
+				stored[account.ID] = account
+				return account, nil
+			},
+		}, stored
+	}
+	var created []domain.Transaction
+	transactions := trRepositoryMock{
+		create: func(tr *domain.Transaction) error {
+			created = append(created, *tr)
+			return nil
+		},
+	}
+
+	t.Run("update status freezes the account", func(t *testing.T) {
+		accounts, _ := newAccounts(1000)
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions}, noRates)
+
+		acc, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountFrozen})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccountFrozen, acc.Status)
+		assert.Equal(t, domain.NewMoney(1000, "USD"), acc.Balance)
+	})
+	t.Run("update status closing an account with money fails", func(t *testing.T) {
+		accounts, stored := newAccounts(1000)
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions}, noRates)
+
+		_, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountClosed})
+
+		assert.Equal(t, custom_errors.ErrAccountNotEmpty, err)
+		assert.Equal(t, domain.AccountActive, stored[id].Status)
+	})
+	t.Run("update status sweeps the balance before closing", func(t *testing.T) {
+		created = nil
+		accounts, stored := newAccounts(1000)
+		entries := &memoryLedger{}
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions, ledger: entries}, noRates)
+
+		acc, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountClosed, SweepDestinationID: &destination})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccountClosed, acc.Status)
+		assert.True(t, acc.Balance.IsZero())
+		assert.Equal(t, domain.NewMoney(1000, "USD"), stored[destination].Balance)
+		if assert.Len(t, created, 1) {
+			assert.Equal(t, domain.Transfer, created[0].Type)
+			assert.Equal(t, domain.NewMoney(1000, "USD"), created[0].Amount)
+			assert.Equal(t, &destination, created[0].DestinationID)
+		}
+		assert.Len(t, entries.entries, 1)
+	})
+	t.Run("update status sweeps the balance of a frozen account before closing", func(t *testing.T) {
+		created = nil
+		accounts, stored := newAccounts(1000)
+		acc := stored[id]
+		acc.Status = domain.AccountFrozen
+		stored[id] = acc
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions, ledger: &memoryLedger{}}, noRates)
+
+		acc, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountClosed, SweepDestinationID: &destination})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccountClosed, acc.Status)
+		assert.True(t, acc.Balance.IsZero())
+		assert.Equal(t, domain.NewMoney(1000, "USD"), stored[destination].Balance)
+		assert.Len(t, created, 1)
+	})
+	t.Run("update status does not sweep an empty account", func(t *testing.T) {
+		created = nil
+		accounts, _ := newAccounts(0)
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions}, noRates)
+
+		acc, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountClosed, SweepDestinationID: &destination})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AccountClosed, acc.Status)
+		assert.Empty(t, created)
+	})
+	t.Run("update status invalid sweep", func(t *testing.T) {
+		accounts, _ := newAccounts(1000)
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions}, noRates)
+
+		_, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountFrozen, SweepDestinationID: &destination})
+		assert.Equal(t, custom_errors.ErrInvalidSweep, err)
+
+		_, err = trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountClosed, SweepDestinationID: &id})
+		assert.Equal(t, custom_errors.ErrInvalidSweep, err)
+	})
+	t.Run("update status sweep into a closed account fails", func(t *testing.T) {
+		accounts, stored := newAccounts(1000)
+		dest := stored[destination]
+		dest.Status = domain.AccountClosed
+		stored[destination] = dest
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions}, noRates)
+
+		_, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountClosed, SweepDestinationID: &destination})
+
+		assert.Equal(t, custom_errors.ErrAccountClosed, err)
+	})
+	t.Run("update status sweep into a frozen account fails", func(t *testing.T) {
+		accounts, stored := newAccounts(1000)
+		dest := stored[destination]
+		dest.Status = domain.AccountFrozen
+		stored[destination] = dest
+		trService := NewService(uowMock{accounts: accounts, transactions: transactions}, noRates)
+
+		_, err := trService.UpdateStatus(context.Background(), id, domain.AccountStatusRequest{Status: domain.AccountClosed, SweepDestinationID: &destination})
+
+		assert.Equal(t, custom_errors.ErrAccountFrozen, err)
+		assert.Equal(t, domain.AccountActive, stored[id].Status)
+	})
+}
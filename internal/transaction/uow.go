@@ -0,0 +1,126 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lucaspichi06/xepelin-bank/internal/account"
+	"github.com/lucaspichi06/xepelin-bank/internal/auth"
+	"github.com/lucaspichi06/xepelin-bank/internal/ledger"
+	"github.com/lucaspichi06/xepelin-bank/internal/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the units of work
+const tracerName = "github.com/lucaspichi06/xepelin-bank/internal/transaction"
+
+// Tx groups the services and repositories that take part in a unit of work.
+// Everything reachable from it shares the same database transaction
+type Tx struct {
+	Accounts     account.Service
+	Transactions Repository
+	Ledger       ledger.Service
+	Auth         auth.Service
+}
+
+// UnitOfWork runs a set of operations atomically: either all of them are
+// persisted or none of them are. The operations run with the context fn is given
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+}
+
+type unitOfWork struct {
+	db *sql.DB
+	// accounts and transactions build the repositories of the database
+	accounts     func(db store.DBTX) account.Repository
+	transactions func(db store.DBTX) Repository
+	// bind adapts the statements of the other repositories to the database and tells their
+	// instrumentation which one it is, it is nil on MySQL, which runs them as they are
+	bind func(db store.DBTX) store.DBTX
+}
+
+func NewUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{
+		db:           db,
+		accounts:     account.NewRepository,
+		transactions: NewRepository,
+	}
+}
+
+// NewSQLiteUnitOfWork runs the units of work on SQLite. Its connections have to begin
+// the transactions with BEGIN IMMEDIATE, so two of them never read the same account
+// before updating it
+func NewSQLiteUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{
+		db:           db,
+		accounts:     account.NewSQLiteRepository,
+		transactions: NewSQLiteRepository,
+		bind:         store.SQLite,
+	}
+}
+
+// NewPostgresUnitOfWork runs the units of work on PostgreSQL
+func NewPostgresUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{
+		db:           db,
+		accounts:     account.NewPostgresRepository,
+		transactions: NewPostgresRepository,
+		bind:         store.Postgres,
+	}
+}
+
+// Do runs fn in a database transaction traced as a span of the context. The transaction is
+// rolled back when the context is done before it commits
+func (u unitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "unit_of_work")
+	defer span.End()
+
+	err := store.WithTransaction(ctx, u.db, func(tx *sql.Tx) error {
+		var db store.DBTX = tx
+		if u.bind != nil {
+			db = u.bind(tx)
+		}
+		return fn(ctx, Tx{
+			Accounts:     account.NewService(u.accounts(tx), account.NewEventStore(db), account.NewSnapshotStore(db), account.DefaultSnapshotEvery),
+			Transactions: u.transactions(tx),
+			Ledger:       ledger.NewService(ledger.NewRepository(db)),
+			Auth:         auth.NewService(auth.NewRepository(db), ""),
+		})
+	})
+	recordError(span, err)
+	return err
+}
+
+type memoryUnitOfWork struct {
+	memory *store.Memory
+	tx     Tx
+}
+
+// NewMemoryUnitOfWork runs the units of work on the in-memory repositories of tx, one at
+// a time, undoing the changes of the ones that fail
+func NewMemoryUnitOfWork(memory *store.Memory, tx Tx) UnitOfWork {
+	return &memoryUnitOfWork{
+		memory: memory,
+		tx:     tx,
+	}
+}
+
+func (u memoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "unit_of_work")
+	defer span.End()
+
+	err := u.memory.Do(ctx, func(ctx context.Context) error {
+		return fn(ctx, u.tx)
+	})
+	recordError(span, err)
+	return err
+}
+
+// recordError marks the span of a unit of work as failed with err, if any
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
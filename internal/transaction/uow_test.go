@@ -0,0 +1,346 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+	"time"
+)
+
+const (
+	lockAccountQuery = "SELECT id, name, balance, currency, status, version FROM accounts WHERE id = \\? FOR UPDATE"
+	loadEventsQuery  = "SELECT (.+) FROM account_events WHERE account_id = \\? AND sequence > \\?"
+	snapshotQuery    = "SELECT (.+) FROM account_snapshots WHERE account_id = \\?"
+)
+
+const (
+	stepDebit      = "debit"
+	stepProjection = "projection"
+	stepCredit     = "credit"
+	stepLedger     = "ledger"
+	stepJournal    = "journal"
+	stepCommit     = "commit"
+)
+
+// orderedIDs returns two account ids, the first one being the one locked first
+func orderedIDs() (uuid.UUID, uuid.UUID) {
+	a, b := uuid.New(), uuid.New()
+	if bytes.Compare(a[:], b[:]) > 0 {
+		return b, a
+	}
+	return a, b
+}
+
+// expectReadForUpdate sets the statements that lock an account and rebuild it from
+// a single create event with the given opening balance
+func expectReadForUpdate(mock sqlmock.Sqlmock, id uuid.UUID, name string, balance int64) {
+	mock.ExpectQuery(lockAccountQuery).WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "balance", "currency", "status", "version"}).
+			AddRow(id.String(), name, balance, "USD", "active", 1))
+	mock.ExpectQuery(snapshotQuery).WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "sequence", "name", "balance", "currency", "status"}))
+	mock.ExpectQuery(loadEventsQuery).WithArgs(id.String(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "sequence", "type", "amount", "currency", "name", "counterparty_id", "status", "timestamp"}).
+			AddRow(id.String(), 1, "create", balance, "USD", name, nil, "", time.Now()))
+}
+
+// expectRecord sets the statements that append an event to the account stream and
+// update its projection. It returns false when one of them fails
+func expectRecord(mock sqlmock.Sqlmock, id uuid.UUID, name string, balance int64, appendErr, projectionErr error) bool {
+	appendEvent := mock.ExpectExec("INSERT INTO account_events").
+		WithArgs(id.String(), 2, sqlmock.AnyArg(), sqlmock.AnyArg(), "USD", "", sqlmock.AnyArg(), "", sqlmock.AnyArg())
+	if appendErr != nil {
+		appendEvent.WillReturnError(appendErr)
+		return false
+	}
+	appendEvent.WillReturnResult(sqlmock.NewResult(1, 1))
+
+	projection := mock.ExpectExec("UPDATE accounts").WithArgs(name, balance, domain.AccountActive, 2, id.String())
+	if projectionErr != nil {
+		projection.WillReturnError(projectionErr)
+		return false
+	}
+	projection.WillReturnResult(sqlmock.NewResult(0, 1))
+	return true
+}
+
+// expectJournal sets the statements that record a journal entry with the given
+// number of postings. It returns false when the entry insert fails
+func expectJournal(mock sqlmock.Sqlmock, postings int, entryErr error) bool {
+	entry := mock.ExpectExec("INSERT INTO journal_entries")
+	if entryErr != nil {
+		entry.WillReturnError(entryErr)
+		return false
+	}
+	entry.WillReturnResult(sqlmock.NewResult(0, 1))
+
+	for i := 0; i < postings; i++ {
+		mock.ExpectExec("INSERT INTO postings").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	return true
+}
+
+// expectTransfer sets the statements a transfer runs inside its unit of work,
+// making the given step fail. The source id must sort before the destination id
+func expectTransfer(mock sqlmock.Sqlmock, source, destination uuid.UUID, failAt string) {
+	errorAt := func(step string) error {
+		if failAt == step {
+			return errors.New(step + " error")
+		}
+		return nil
+	}
+
+	mock.ExpectBegin()
+	expectReadForUpdate(mock, source, "source", 100000)
+	expectReadForUpdate(mock, destination, "destination", 0)
+
+	if !expectRecord(mock, source, "source", 90000, errorAt(stepDebit), errorAt(stepProjection)) {
+		mock.ExpectRollback()
+		return
+	}
+	if !expectRecord(mock, destination, "destination", 10000, errorAt(stepCredit), nil) {
+		mock.ExpectRollback()
+		return
+	}
+
+	ledger := mock.ExpectExec("INSERT INTO transactions")
+	if failAt == stepLedger {
+		ledger.WillReturnError(errors.New("ledger error"))
+		mock.ExpectRollback()
+		return
+	}
+	ledger.WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if !expectJournal(mock, 4, errorAt(stepJournal)) {
+		mock.ExpectRollback()
+		return
+	}
+
+	if failAt == stepCommit {
+		mock.ExpectCommit().WillReturnError(errors.New("commit error"))
+		return
+	}
+	mock.ExpectCommit()
+}
+
+func TestUnitOfWorkTransfer(t *testing.T) {
+	t.Run("transfer commits debit, credit and ledger together", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		source, destination := orderedIDs()
+		expectTransfer(mock, source, destination, "")
+
+		trService := NewService(NewUnitOfWork(db), noRates)
+		err = trService.Create(context.Background(), &domain.Transaction{
+			AccountID:     source,
+			DestinationID: &destination,
+			Type:          domain.Transfer,
+			Amount:        domain.NewMoney(10000, domain.DefaultCurrency),
+		})
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	for _, step := range []string{stepDebit, stepProjection, stepCredit, stepLedger, stepJournal, stepCommit} {
+		step := step
+		t.Run("transfer rolls back when the "+step+" fails", func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fail()
+			}
+			defer db.Close()
+
+			source, destination := orderedIDs()
+			expectTransfer(mock, source, destination, step)
+
+			trService := NewService(NewUnitOfWork(db), noRates)
+			err = trService.Create(context.Background(), &domain.Transaction{
+				AccountID:     source,
+				DestinationID: &destination,
+				Type:          domain.Transfer,
+				Amount:        domain.NewMoney(10000, domain.DefaultCurrency),
+			})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), step+" error")
+
+			err = mock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestUnitOfWorkTransferLockOrder(t *testing.T) {
+	t.Run("transfer locks the accounts in id order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		destination, source := orderedIDs()
+		mock.ExpectBegin()
+		expectReadForUpdate(mock, destination, "destination", 0)
+		expectReadForUpdate(mock, source, "source", 100000)
+		expectRecord(mock, source, "source", 90000, nil, nil)
+		expectRecord(mock, destination, "destination", 10000, nil, nil)
+		mock.ExpectExec("INSERT INTO transactions").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectJournal(mock, 4, nil)
+		mock.ExpectCommit()
+
+		trService := NewService(NewUnitOfWork(db), noRates)
+		err = trService.Create(context.Background(), &domain.Transaction{
+			AccountID:     source,
+			DestinationID: &destination,
+			Type:          domain.Transfer,
+			Amount:        domain.NewMoney(10000, domain.DefaultCurrency),
+		})
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
+
+func TestUnitOfWorkDeposit(t *testing.T) {
+	t.Run("deposit rolls back the balance when the ledger insert fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		id := uuid.New()
+		mock.ExpectBegin()
+		expectReadForUpdate(mock, id, "test", 0)
+		expectRecord(mock, id, "test", 10000, nil, nil)
+		mock.ExpectExec("INSERT INTO transactions").
+			WillReturnError(errors.New("ledger error"))
+		mock.ExpectRollback()
+
+		trService := NewService(NewUnitOfWork(db), noRates)
+		err = trService.Create(context.Background(), &domain.Transaction{
+			AccountID: id,
+			Type:      domain.Deposit,
+			Amount:    domain.NewMoney(10000, domain.DefaultCurrency),
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "ledger error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+	t.Run("deposit rolls back when the account can not be read", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockAccountQuery).
+			WillReturnError(errors.New("read error"))
+		mock.ExpectRollback()
+
+		trService := NewService(NewUnitOfWork(db), noRates)
+		err = trService.Create(context.Background(), &domain.Transaction{
+			AccountID: uuid.New(),
+			Type:      domain.Deposit,
+			Amount:    domain.NewMoney(10000, domain.DefaultCurrency),
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "read error")
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+}
+
+func TestUnitOfWorkTx(t *testing.T) {
+	t.Run("unit of work provides every service", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = NewUnitOfWork(db).Do(context.Background(), func(ctx context.Context, tx Tx) error {
+			assert.NotNil(t, tx.Accounts)
+			assert.NotNil(t, tx.Transactions)
+			assert.NotNil(t, tx.Ledger)
+			assert.NotNil(t, tx.Auth)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUnitOfWorkTracing(t *testing.T) {
+	t.Run("deposit is traced under the unit of work", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+		defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fail()
+		}
+		defer db.Close()
+
+		id := uuid.New()
+		mock.ExpectBegin()
+		expectReadForUpdate(mock, id, "test", 0)
+		expectRecord(mock, id, "test", 10000, nil, nil)
+		mock.ExpectExec("INSERT INTO transactions").WillReturnResult(sqlmock.NewResult(1, 1))
+		expectJournal(mock, 2, nil)
+		mock.ExpectCommit()
+
+		ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+		err = NewService(NewUnitOfWork(db), noRates).Create(ctx, &domain.Transaction{
+			AccountID: id,
+			Type:      domain.Deposit,
+			Amount:    domain.NewMoney(10000, domain.DefaultCurrency),
+		})
+		request.End()
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		spans := map[string][]tracetest.SpanStub{}
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = append(spans[span.Name], span)
+		}
+		uow := spans["unit_of_work"][0].SpanContext.SpanID()
+		event := spans["event.process"][0].SpanContext.SpanID()
+		assert.Equal(t, request.SpanContext().SpanID(), spans["unit_of_work"][0].Parent.SpanID())
+		assert.Equal(t, uow, spans["event.process"][0].Parent.SpanID())
+		// the account statements run by the event are its children, the rest belong to the unit of work
+		children := map[trace.SpanID]int{}
+		for _, name := range []string{"sql.query", "sql.prepare", "sql.exec"} {
+			for _, span := range spans[name] {
+				assert.Contains(t, []trace.SpanID{uow, event}, span.Parent.SpanID(), name)
+				assert.Equal(t, request.SpanContext().TraceID(), span.SpanContext.TraceID(), name)
+				children[span.Parent.SpanID()]++
+			}
+		}
+		assert.NotZero(t, children[uow])
+		assert.NotZero(t, children[event])
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// The migrations of every database are named VERSION_NAME.up.sql and VERSION_NAME.down.sql,
+// in the directory of the database
+var (
+	//go:embed mysql/*.sql
+	mysql embed.FS
+	//go:embed sqlite/*.sql
+	sqlite embed.FS
+	//go:embed postgres/*.sql
+	postgres embed.FS
+
+	MySQL    = sub(mysql, "mysql")
+	SQLite   = sub(sqlite, "sqlite")
+	Postgres = sub(postgres, "postgres")
+)
+
+func sub(fsys embed.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
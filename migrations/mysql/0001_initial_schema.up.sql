@@ -0,0 +1,20 @@
+-- Creates the accounts and their transactions, as the first version of the server stored
+-- them: balances and amounts were floats and the timestamps were strings.
+
+CREATE TABLE `accounts` (
+    `id` VARCHAR(36) NOT NULL,
+    `name` varchar(45) DEFAULT NULL,
+    `balance` float DEFAULT NULL,
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `transactions` (
+    `id` VARCHAR(36) NOT NULL,
+    `account_id` VARCHAR(36) NOT NULL,
+    `destination_id` VARCHAR(36) DEFAULT NULL,
+    `type` varchar(45) NOT NULL,
+    `amount` float NOT NULL,
+    `timestamp` varchar(45) NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_transactions_account` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,11 @@
+-- Converts the balances and amounts back into floats, dropping their currencies.
+
+ALTER TABLE `transactions` DROP COLUMN `currency`;
+ALTER TABLE `transactions` MODIFY `amount` DECIMAL(21,2) NOT NULL;
+UPDATE `transactions` SET `amount` = `amount` / 100;
+ALTER TABLE `transactions` MODIFY `amount` float NOT NULL;
+
+ALTER TABLE `accounts` DROP COLUMN `currency`;
+ALTER TABLE `accounts` MODIFY `balance` DECIMAL(21,2) DEFAULT NULL;
+UPDATE `accounts` SET `balance` = `balance` / 100;
+ALTER TABLE `accounts` MODIFY `balance` float DEFAULT NULL;
@@ -0,0 +1,15 @@
+-- Converts the float balances and amounts into integer minor units (cents) and
+-- adds the currency of each amount.
+--
+-- The float values are first rounded to exact decimals, then scaled to cents, so
+-- no float arithmetic takes part in the conversion.
+
+ALTER TABLE `accounts` MODIFY `balance` DECIMAL(21,2) NOT NULL DEFAULT 0;
+UPDATE `accounts` SET `balance` = `balance` * 100;
+ALTER TABLE `accounts` MODIFY `balance` BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE `accounts` ADD `currency` CHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE `transactions` MODIFY `amount` DECIMAL(21,2) NOT NULL;
+UPDATE `transactions` SET `amount` = `amount` * 100;
+ALTER TABLE `transactions` MODIFY `amount` BIGINT NOT NULL;
+ALTER TABLE `transactions` ADD `currency` CHAR(3) NOT NULL DEFAULT 'USD';
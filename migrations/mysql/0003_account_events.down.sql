@@ -0,0 +1,4 @@
+-- The accounts keep the balances projected from their events.
+
+ALTER TABLE `accounts` DROP COLUMN `version`;
+DROP TABLE `account_events`;
@@ -0,0 +1,25 @@
+-- Introduces the append-only account event store. Balances stop being mutated in
+-- place: every change is an event in `account_events` and `accounts` becomes a
+-- projection of those events.
+--
+-- Every existing account gets a create event carrying its current balance as the
+-- opening balance, so replaying its stream gives back the same state.
+
+CREATE TABLE `account_events` (
+    `account_id` VARCHAR(36) NOT NULL,
+    `sequence` BIGINT NOT NULL,
+    `type` varchar(45) NOT NULL,
+    `amount` BIGINT NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `name` varchar(45) NOT NULL DEFAULT '',
+    `counterparty_id` VARCHAR(36) DEFAULT NULL,
+    `timestamp` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`account_id`, `sequence`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `accounts` ADD `version` BIGINT NOT NULL DEFAULT 0;
+
+INSERT INTO `account_events` (`account_id`, `sequence`, `type`, `amount`, `currency`, `name`, `timestamp`)
+SELECT `id`, 1, 'create', `balance`, `currency`, COALESCE(`name`, ''), UTC_TIMESTAMP(6) FROM `accounts`;
+
+UPDATE `accounts` SET `version` = 1;
@@ -0,0 +1,12 @@
+-- Stores periodic snapshots of the event sourced accounts, so loading an account
+-- only replays the events recorded after its latest snapshot.
+
+CREATE TABLE `account_snapshots` (
+    `account_id` VARCHAR(36) NOT NULL,
+    `sequence` BIGINT NOT NULL,
+    `name` varchar(45) NOT NULL DEFAULT '',
+    `balance` BIGINT NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `timestamp` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`account_id`, `sequence`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,16 @@
+-- Stores the transaction timestamps back in the RFC 850 layout. The foreign key is
+-- recreated once the indexes are dropped, as it may be using the account index.
+
+ALTER TABLE `transactions`
+    DROP FOREIGN KEY `fk_transactions_account`,
+    DROP INDEX `idx_transactions_account`,
+    DROP INDEX `idx_transactions_destination`;
+ALTER TABLE `transactions` ADD CONSTRAINT `fk_transactions_account` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`);
+
+ALTER TABLE `transactions` ADD COLUMN `formatted_timestamp` varchar(45) NULL;
+
+UPDATE `transactions`
+SET `formatted_timestamp` = DATE_FORMAT(`timestamp`, '%W, %d-%b-%y %H:%i:%s UTC');
+
+ALTER TABLE `transactions` DROP COLUMN `timestamp`;
+ALTER TABLE `transactions` CHANGE COLUMN `formatted_timestamp` `timestamp` varchar(45) NOT NULL;
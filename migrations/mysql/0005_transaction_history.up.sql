@@ -0,0 +1,14 @@
+-- Stores the transaction timestamps as DATETIME, so the history can be sorted and
+-- filtered by date, and indexes the transactions by account and destination account.
+-- Existing timestamps were written in the RFC 850 layout by the server in UTC.
+
+ALTER TABLE `transactions` ADD COLUMN `created_at` DATETIME(6) NULL;
+
+UPDATE `transactions`
+SET `created_at` = STR_TO_DATE(SUBSTRING_INDEX(`timestamp`, ' ', 3), '%W, %d-%b-%y %H:%i:%s');
+
+ALTER TABLE `transactions` DROP COLUMN `timestamp`;
+ALTER TABLE `transactions` CHANGE COLUMN `created_at` `timestamp` DATETIME(6) NOT NULL;
+
+CREATE INDEX `idx_transactions_account` ON `transactions` (`account_id`, `timestamp`, `id`);
+CREATE INDEX `idx_transactions_destination` ON `transactions` (`destination_id`, `timestamp`, `id`);
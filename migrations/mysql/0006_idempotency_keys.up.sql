@@ -0,0 +1,12 @@
+-- Stores the Idempotency-Key of the transactions requests together with the hash of the
+-- request and the response sent, so retried requests are processed only once.
+
+CREATE TABLE `idempotency_keys` (
+    `idempotency_key` VARCHAR(255) NOT NULL,
+    `request_hash` CHAR(64) NOT NULL,
+    `status_code` INT NOT NULL DEFAULT 0,
+    `response_body` MEDIUMBLOB NOT NULL,
+    `created_at` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`idempotency_key`),
+    KEY `idx_idempotency_keys_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
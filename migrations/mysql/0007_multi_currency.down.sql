@@ -0,0 +1,4 @@
+ALTER TABLE `transactions`
+    DROP COLUMN `rate`,
+    DROP COLUMN `destination_currency`,
+    DROP COLUMN `destination_amount`;
@@ -0,0 +1,12 @@
+-- Records the exchange rate and the amount credited to the destination account of the
+-- transfers, which differ from the amount debited when the accounts have different
+-- currencies. Existing transfers were made in a single currency, at a rate of 1.
+
+ALTER TABLE `transactions`
+    ADD COLUMN `destination_amount` BIGINT DEFAULT NULL AFTER `currency`,
+    ADD COLUMN `destination_currency` CHAR(3) DEFAULT NULL AFTER `destination_amount`,
+    ADD COLUMN `rate` DECIMAL(24,12) DEFAULT NULL AFTER `destination_currency`;
+
+UPDATE `transactions`
+SET `destination_amount` = `amount`, `destination_currency` = `currency`, `rate` = 1
+WHERE `type` = 'transfer';
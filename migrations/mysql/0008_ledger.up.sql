@@ -0,0 +1,70 @@
+-- Adds the double-entry ledger and backfills a journal entry for every existing
+-- transaction, so the ledger agrees with the balances. Deposits debit the cash account
+-- and credit the customer account, withdrawals do the opposite, and transfers go
+-- through the clearing account of each currency.
+
+CREATE TABLE `journal_entries` (
+    `id` VARCHAR(36) NOT NULL,
+    `transaction_id` VARCHAR(36) NOT NULL,
+    `timestamp` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_journal_entries_transaction` (`transaction_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `postings` (
+    `entry_id` VARCHAR(36) NOT NULL,
+    `line` INT NOT NULL,
+    `ledger_account` VARCHAR(64) NOT NULL,
+    `amount` BIGINT NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    PRIMARY KEY (`entry_id`, `line`),
+    KEY `idx_postings_ledger_account` (`ledger_account`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT INTO `journal_entries` (`id`, `transaction_id`, `timestamp`)
+SELECT UUID(), `id`, `timestamp` FROM `transactions`;
+
+-- deposits
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 1, CONCAT('system:cash:', t.`currency`), t.`amount`, t.`currency`
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'deposit';
+
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 2, CONCAT('account:', t.`account_id`), -t.`amount`, t.`currency`
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'deposit';
+
+-- withdrawals
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 1, CONCAT('account:', t.`account_id`), t.`amount`, t.`currency`
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'withdraw';
+
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 2, CONCAT('system:cash:', t.`currency`), -t.`amount`, t.`currency`
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'withdraw';
+
+-- transfers
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 1, CONCAT('account:', t.`account_id`), t.`amount`, t.`currency`
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'transfer';
+
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 2, CONCAT('system:clearing:', t.`currency`), -t.`amount`, t.`currency`
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'transfer';
+
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 3, CONCAT('system:clearing:', COALESCE(t.`destination_currency`, t.`currency`)),
+       COALESCE(t.`destination_amount`, t.`amount`), COALESCE(t.`destination_currency`, t.`currency`)
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'transfer';
+
+INSERT INTO `postings` (`entry_id`, `line`, `ledger_account`, `amount`, `currency`)
+SELECT e.`id`, 4, CONCAT('account:', t.`destination_id`),
+       -COALESCE(t.`destination_amount`, t.`amount`), COALESCE(t.`destination_currency`, t.`currency`)
+FROM `transactions` t JOIN `journal_entries` e ON e.`transaction_id` = t.`id`
+WHERE t.`type` = 'transfer';
@@ -0,0 +1,3 @@
+DROP TABLE `principal_accounts`;
+DROP TABLE `api_keys`;
+DROP TABLE `principals`;
@@ -0,0 +1,29 @@
+-- Adds the API keys. Only the SHA-256 hash of every key is stored, and every key belongs
+-- to a principal with a role. Account owners can only operate on the accounts listed in
+-- principal_accounts. Until keys are issued, the TOKEN environment variable works as an
+-- admin key.
+
+CREATE TABLE `principals` (
+    `id` VARCHAR(36) NOT NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `role` VARCHAR(32) NOT NULL,
+    `created_at` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `api_keys` (
+    `key_hash` CHAR(64) NOT NULL,
+    `principal_id` VARCHAR(36) NOT NULL,
+    `created_at` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`key_hash`),
+    CONSTRAINT `fk_api_keys_principal` FOREIGN KEY (`principal_id`) REFERENCES `principals` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `principal_accounts` (
+    `principal_id` VARCHAR(36) NOT NULL,
+    `account_id` VARCHAR(36) NOT NULL,
+    PRIMARY KEY (`principal_id`, `account_id`),
+    CONSTRAINT `fk_principal_accounts_principal` FOREIGN KEY (`principal_id`) REFERENCES `principals` (`id`),
+    CONSTRAINT `fk_principal_accounts_account` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
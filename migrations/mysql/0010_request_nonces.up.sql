@@ -0,0 +1,9 @@
+-- Remembers the nonces of the signed transaction requests until their timestamp is no
+-- longer accepted, so a signed request can not be replayed.
+
+CREATE TABLE `request_nonces` (
+    `nonce` VARCHAR(255) NOT NULL,
+    `expires_at` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`nonce`),
+    KEY `idx_request_nonces_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
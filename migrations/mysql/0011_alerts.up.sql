@@ -0,0 +1,18 @@
+-- Stores the alerts raised by the transaction monitoring rules, replacing the log of the
+-- large transactions.
+
+CREATE TABLE `alerts` (
+    `id` VARCHAR(36) NOT NULL,
+    `rule` VARCHAR(255) NOT NULL,
+    `account_id` VARCHAR(36) NOT NULL,
+    `transaction_id` VARCHAR(36) NOT NULL,
+    `type` VARCHAR(255) NOT NULL,
+    `amount` BIGINT NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `details` VARCHAR(1024) NOT NULL,
+    `created_at` DATETIME(6) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_alerts_account_id_created_at` (`account_id`, `created_at`),
+    KEY `idx_alerts_rule_created_at` (`rule`, `created_at`),
+    KEY `idx_alerts_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
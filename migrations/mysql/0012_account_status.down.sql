@@ -0,0 +1,3 @@
+ALTER TABLE `account_events` DROP COLUMN `status`;
+ALTER TABLE `account_snapshots` DROP COLUMN `status`;
+ALTER TABLE `accounts` DROP COLUMN `status`;
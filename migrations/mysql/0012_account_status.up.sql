@@ -0,0 +1,6 @@
+-- Adds the status of the accounts, so they can be frozen, closed and reopened. The status
+-- changes are events of the account stream, and the existing accounts are all active.
+
+ALTER TABLE `accounts` ADD `status` VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE `account_snapshots` ADD `status` VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE `account_events` ADD `status` VARCHAR(16) NOT NULL DEFAULT '';
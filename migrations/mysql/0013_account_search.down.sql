@@ -0,0 +1,3 @@
+DROP INDEX `idx_accounts_currency_balance` ON `accounts`;
+DROP INDEX `idx_accounts_balance` ON `accounts`;
+DROP INDEX `idx_accounts_name` ON `accounts`;
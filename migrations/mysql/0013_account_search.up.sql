@@ -0,0 +1,7 @@
+-- Indexes the accounts for their listing: by name, which also serves the name prefix
+-- search as the collation ignores the case, and by balance, alone or within a currency.
+-- The id breaks the ties of the pagination cursors.
+
+CREATE INDEX `idx_accounts_name` ON `accounts` (`name`, `id`);
+CREATE INDEX `idx_accounts_balance` ON `accounts` (`balance`, `id`);
+CREATE INDEX `idx_accounts_currency_balance` ON `accounts` (`currency`, `balance`, `id`);
@@ -0,0 +1 @@
+DROP INDEX `idx_accounts_lower_name` ON `accounts`;
@@ -0,0 +1,6 @@
+-- Indexes the lowered names the accounts are listed by. They are compared byte by byte,
+-- so the order is the same on every database, instead of following the collation of the
+-- table, which ignores the accents as well. The id breaks the ties of the pagination
+-- cursors.
+
+CREATE INDEX `idx_accounts_lower_name` ON `accounts` ((LOWER(`name`) COLLATE utf8mb4_bin), `id`);
@@ -0,0 +1,113 @@
+-- Creates the schema of the PostgreSQL databases, the same the MySQL migrations lead to.
+-- The ids are UUID, the timestamps TIMESTAMPTZ and the exchange rates NUMERIC, so their
+-- decimals are kept exactly.
+
+CREATE TABLE accounts (
+    id UUID NOT NULL PRIMARY KEY,
+    name VARCHAR(45) DEFAULT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    version BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE transactions (
+    id UUID NOT NULL PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts (id),
+    destination_id UUID DEFAULT NULL,
+    type VARCHAR(45) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    destination_amount BIGINT DEFAULT NULL,
+    destination_currency CHAR(3) DEFAULT NULL,
+    rate NUMERIC(24, 12) DEFAULT NULL,
+    timestamp TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_transactions_account ON transactions (account_id, timestamp, id);
+CREATE INDEX idx_transactions_destination ON transactions (destination_id, timestamp, id);
+
+CREATE TABLE account_events (
+    account_id UUID NOT NULL,
+    sequence BIGINT NOT NULL,
+    type VARCHAR(45) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    name VARCHAR(45) NOT NULL DEFAULT '',
+    counterparty_id UUID DEFAULT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (account_id, sequence)
+);
+
+CREATE TABLE account_snapshots (
+    account_id UUID NOT NULL,
+    sequence BIGINT NOT NULL,
+    name VARCHAR(45) NOT NULL DEFAULT '',
+    balance BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (account_id, sequence)
+);
+
+CREATE TABLE idempotency_keys (
+    idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_body BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
+
+CREATE TABLE journal_entries (
+    id UUID NOT NULL PRIMARY KEY,
+    transaction_id UUID NOT NULL UNIQUE,
+    timestamp TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE postings (
+    entry_id UUID NOT NULL,
+    line INTEGER NOT NULL,
+    ledger_account VARCHAR(64) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    PRIMARY KEY (entry_id, line)
+);
+CREATE INDEX idx_postings_ledger_account ON postings (ledger_account);
+
+CREATE TABLE principals (
+    id UUID NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE api_keys (
+    key_hash CHAR(64) NOT NULL PRIMARY KEY,
+    principal_id UUID NOT NULL REFERENCES principals (id),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE principal_accounts (
+    principal_id UUID NOT NULL REFERENCES principals (id),
+    account_id UUID NOT NULL REFERENCES accounts (id),
+    PRIMARY KEY (principal_id, account_id)
+);
+
+CREATE TABLE request_nonces (
+    nonce VARCHAR(255) NOT NULL PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_request_nonces_expires_at ON request_nonces (expires_at);
+
+CREATE TABLE alerts (
+    id UUID NOT NULL PRIMARY KEY,
+    rule VARCHAR(255) NOT NULL,
+    account_id UUID NOT NULL,
+    transaction_id UUID NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    details VARCHAR(1024) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_alerts_account_id_created_at ON alerts (account_id, created_at);
+CREATE INDEX idx_alerts_rule_created_at ON alerts (rule, created_at);
+CREATE INDEX idx_alerts_created_at ON alerts (created_at);
@@ -0,0 +1,4 @@
+DROP INDEX idx_accounts_currency_balance;
+DROP INDEX idx_accounts_balance;
+DROP INDEX idx_accounts_lower_name;
+DROP INDEX idx_accounts_name;
@@ -0,0 +1,9 @@
+-- Indexes the accounts for their listing, by name and by balance, alone or within a
+-- currency. The id breaks the ties of the pagination cursors. The name prefix search
+-- ignores the case, so it is served by the index of the lowered names, whose operator
+-- class matches LIKE patterns whatever the collation of the database.
+
+CREATE INDEX idx_accounts_name ON accounts (name, id);
+CREATE INDEX idx_accounts_lower_name ON accounts (LOWER(name) varchar_pattern_ops);
+CREATE INDEX idx_accounts_balance ON accounts (balance, id);
+CREATE INDEX idx_accounts_currency_balance ON accounts (currency, balance, id);
@@ -0,0 +1,2 @@
+DROP INDEX idx_accounts_lower_name_order;
+CREATE INDEX idx_accounts_name ON accounts (name, id);
@@ -0,0 +1,6 @@
+-- Indexes the lowered names the accounts are listed by instead of the names. They are
+-- compared byte by byte, so the order is the same on every database, whatever the locale
+-- of this one. The id breaks the ties of the pagination cursors.
+
+DROP INDEX idx_accounts_name;
+CREATE INDEX idx_accounts_lower_name_order ON accounts ((LOWER(name)) COLLATE "C", id);
@@ -0,0 +1,12 @@
+DROP TABLE alerts;
+DROP TABLE request_nonces;
+DROP TABLE principal_accounts;
+DROP TABLE api_keys;
+DROP TABLE principals;
+DROP TABLE postings;
+DROP TABLE journal_entries;
+DROP TABLE idempotency_keys;
+DROP TABLE account_snapshots;
+DROP TABLE account_events;
+DROP TABLE transactions;
+DROP TABLE accounts;
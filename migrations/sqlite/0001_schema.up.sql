@@ -0,0 +1,113 @@
+-- Creates the schema of the SQLite databases, the same the MySQL migrations lead to. The
+-- timestamps are DATETIME, so the driver reads them back as times, and the exchange
+-- rates are TEXT, so their decimals are kept exactly.
+
+CREATE TABLE accounts (
+    id TEXT NOT NULL PRIMARY KEY,
+    name TEXT DEFAULT NULL,
+    balance INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT 'USD',
+    version INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE transactions (
+    id TEXT NOT NULL PRIMARY KEY,
+    account_id TEXT NOT NULL REFERENCES accounts (id),
+    destination_id TEXT DEFAULT NULL,
+    type TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'USD',
+    destination_amount INTEGER DEFAULT NULL,
+    destination_currency TEXT DEFAULT NULL,
+    rate TEXT DEFAULT NULL,
+    timestamp DATETIME NOT NULL
+);
+CREATE INDEX idx_transactions_account ON transactions (account_id, timestamp, id);
+CREATE INDEX idx_transactions_destination ON transactions (destination_id, timestamp, id);
+
+CREATE TABLE account_events (
+    account_id TEXT NOT NULL,
+    sequence INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    counterparty_id TEXT DEFAULT NULL,
+    timestamp DATETIME NOT NULL,
+    PRIMARY KEY (account_id, sequence)
+);
+
+CREATE TABLE account_snapshots (
+    account_id TEXT NOT NULL,
+    sequence INTEGER NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    balance INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    timestamp DATETIME NOT NULL,
+    PRIMARY KEY (account_id, sequence)
+);
+
+CREATE TABLE idempotency_keys (
+    idempotency_key TEXT NOT NULL PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_body BLOB NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
+
+CREATE TABLE journal_entries (
+    id TEXT NOT NULL PRIMARY KEY,
+    transaction_id TEXT NOT NULL UNIQUE,
+    timestamp DATETIME NOT NULL
+);
+
+CREATE TABLE postings (
+    entry_id TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    ledger_account TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    PRIMARY KEY (entry_id, line)
+);
+CREATE INDEX idx_postings_ledger_account ON postings (ledger_account);
+
+CREATE TABLE principals (
+    id TEXT NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE api_keys (
+    key_hash TEXT NOT NULL PRIMARY KEY,
+    principal_id TEXT NOT NULL REFERENCES principals (id),
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE principal_accounts (
+    principal_id TEXT NOT NULL REFERENCES principals (id),
+    account_id TEXT NOT NULL REFERENCES accounts (id),
+    PRIMARY KEY (principal_id, account_id)
+);
+
+CREATE TABLE request_nonces (
+    nonce TEXT NOT NULL PRIMARY KEY,
+    expires_at DATETIME NOT NULL
+);
+CREATE INDEX idx_request_nonces_expires_at ON request_nonces (expires_at);
+
+CREATE TABLE alerts (
+    id TEXT NOT NULL PRIMARY KEY,
+    rule TEXT NOT NULL,
+    account_id TEXT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    details TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_alerts_account_id_created_at ON alerts (account_id, created_at);
+CREATE INDEX idx_alerts_rule_created_at ON alerts (rule, created_at);
+CREATE INDEX idx_alerts_created_at ON alerts (created_at);
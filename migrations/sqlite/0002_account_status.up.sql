@@ -0,0 +1,6 @@
+-- Adds the status of the accounts, so they can be frozen, closed and reopened. The status
+-- changes are events of the account stream, and the existing accounts are all active.
+
+ALTER TABLE accounts ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE account_snapshots ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE account_events ADD COLUMN status TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX idx_accounts_currency_balance;
+DROP INDEX idx_accounts_balance;
+DROP INDEX idx_accounts_name;
@@ -0,0 +1,2 @@
+DROP INDEX idx_accounts_lower_name;
+CREATE INDEX idx_accounts_name ON accounts (name, id);
@@ -0,0 +1,5 @@
+-- Indexes the lowered names the accounts are listed by instead of the names. The id
+-- breaks the ties of the pagination cursors.
+
+DROP INDEX idx_accounts_name;
+CREATE INDEX idx_accounts_lower_name ON accounts (LOWER(name), id);
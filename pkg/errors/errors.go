@@ -1,18 +1,101 @@
 package custom_errors
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	// handler errors
-	ErrNotFound    = errors.New("registry not found")
-	ErrInvalidJSON = errors.New("invalid json")
-	ErrInvalidID   = errors.New("invalid param id")
+	ErrNotFound     = errors.New("registry not found")
+	ErrInvalidJSON  = errors.New("invalid json")
+	ErrInvalidID    = errors.New("invalid param id")
+	ErrInvalidQuery = errors.New("invalid query param")
+	ErrTimeout      = errors.New("the request took too long to be processed")
+
+	// auth errors
+	ErrTokenNotFound = errors.New("token not found")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrForbidden     = errors.New("the caller is not allowed to perform this operation")
+	ErrInvalidRole   = errors.New("invalid role, it must be admin, account_owner or auditor")
+
+	// request signing errors
+	ErrMissingSignature = errors.New("the request is not signed")
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrStaleSignature   = errors.New("the request timestamp is outside the accepted window")
+	ErrReplayedRequest  = errors.New("the request nonce was already used")
 
 	// account errors
 	ErrAccountExist       = errors.New("there is already an account with this name")
 	ErrInsuficientBalance = errors.New("insufficient amount in the account balance")
+	ErrAccountFrozen      = errors.New("the account is frozen, no money can move in or out of it")
+	ErrAccountClosed      = errors.New("the account is closed, no money can move in or out of it")
+	ErrAccountNotEmpty    = errors.New("the account balance must be zero to close it, or a sweep destination has to be sent")
+
+	// account status errors
+	ErrInvalidAccountStatus    = errors.New("invalid account status, it must be active, frozen or closed")
+	ErrInvalidStatusTransition = errors.New("the account can not change to this status")
+	ErrInvalidSweep            = errors.New("a sweep destination can only be sent when closing the account, and it must be another account")
 
 	// transaction errors
 	ErrInvalidTransactionType        = errors.New("invalid transaction type")
 	ErrInvalidTransactionDestination = errors.New("invalid transaction destination account")
+	ErrInvalidCursor                 = errors.New("invalid pagination cursor")
+
+	// ledger errors
+	ErrLedgerUnbalanced = errors.New("the ledger postings do not add up to zero")
+
+	// statement errors
+	ErrInvalidStatementPeriod = errors.New("the statement start must be before its end")
+	ErrInvalidStatementFormat = errors.New("invalid statement format, it must be csv, ofx or json")
+
+	// idempotency errors
+	ErrInvalidIdempotencyKey    = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+
+	// money errors
+	ErrInvalidAmount          = errors.New("invalid amount")
+	ErrInvalidAmountPrecision = errors.New("amount has more decimal places than the currency allows")
+	ErrInvalidCurrency        = errors.New("invalid currency")
+	ErrCurrencyMismatch       = errors.New("the amount currency does not match the account currency")
+
+	// fx errors
+	ErrInvalidExchangeRate  = errors.New("invalid exchange rate")
+	ErrExchangeRateNotFound = errors.New("exchange rate not found")
 )
+
+// classes name the errors in metrics and logs. Errors not listed are internal
+var classes = []struct {
+	err   error
+	class string
+}{
+	{ErrNotFound, "not_found"},
+	{ErrInsuficientBalance, "insufficient_balance"},
+	{ErrInvalidTransactionType, "invalid_transaction_type"},
+	{ErrInvalidTransactionDestination, "invalid_transaction_destination"},
+	{ErrInvalidAmount, "invalid_amount"},
+	{ErrInvalidAmountPrecision, "invalid_amount_precision"},
+	{ErrInvalidCurrency, "invalid_currency"},
+	{ErrCurrencyMismatch, "currency_mismatch"},
+	{ErrExchangeRateNotFound, "exchange_rate_not_found"},
+	{ErrAccountExist, "account_exists"},
+	{ErrAccountFrozen, "account_frozen"},
+	{ErrAccountClosed, "account_closed"},
+	{ErrAccountNotEmpty, "account_not_empty"},
+	{ErrInvalidAccountStatus, "invalid_account_status"},
+	{ErrInvalidStatusTransition, "invalid_status_transition"},
+	{ErrInvalidSweep, "invalid_sweep"},
+	{context.DeadlineExceeded, "timeout"},
+}
+
+// Class returns a short name of the error, e.g. insufficient_balance, or internal when
+// it is not one of the known errors
+func Class(err error) string {
+	for _, c := range classes {
+		if errors.Is(err, c.err) {
+			return c.class
+		}
+	}
+	return "internal"
+}
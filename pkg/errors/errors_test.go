@@ -0,0 +1,19 @@
+package custom_errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClass(t *testing.T) {
+	assert.Equal(t, "insufficient_balance", Class(ErrInsuficientBalance))
+	assert.Equal(t, "account_frozen", Class(ErrAccountFrozen))
+	assert.Equal(t, "invalid_status_transition", Class(fmt.Errorf("%w: the account is closed", ErrInvalidStatusTransition)))
+	assert.Equal(t, "not_found", Class(fmt.Errorf("account not found: %w", ErrNotFound)))
+	assert.Equal(t, "timeout", Class(fmt.Errorf("query failed: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "internal", Class(errors.New("test error")))
+}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log line
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{LevelDebug: "debug", LevelInfo: "info", LevelWarn: "warn", LevelError: "error"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel reads a level by its name: debug, info, warn or error
+func ParseLevel(value string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(value, name) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("invalid log level %q, it must be debug, info, warn or error", value)
+}
+
+// Logger writes structured log lines. The args are alternating keys and values, like
+// log/slog: Info("event processed", "account_id", id, "amount", amount)
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+	// With returns a logger that adds the args to every line
+	With(args ...interface{}) Logger
+}
+
+type jsonLogger struct {
+	out   *output
+	level Level
+	attrs []byte
+	now   func() time.Time
+}
+
+// output serializes the writes of a logger and the loggers derived from it
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New returns a logger writing a JSON object per line, with the time, level and message
+// followed by the args, skipping the lines below the level
+func New(w io.Writer, level Level) Logger {
+	return &jsonLogger{
+		out:   &output{w: w},
+		level: level,
+		now:   time.Now,
+	}
+}
+
+func (l *jsonLogger) Debug(msg string, args ...interface{}) {
+	l.log(LevelDebug, msg, args)
+}
+
+func (l *jsonLogger) Info(msg string, args ...interface{}) {
+	l.log(LevelInfo, msg, args)
+}
+
+func (l *jsonLogger) Warn(msg string, args ...interface{}) {
+	l.log(LevelWarn, msg, args)
+}
+
+func (l *jsonLogger) Error(msg string, args ...interface{}) {
+	l.log(LevelError, msg, args)
+}
+
+func (l *jsonLogger) With(args ...interface{}) Logger {
+	var attrs bytes.Buffer
+	attrs.Write(l.attrs)
+	appendAttrs(&attrs, args)
+	return &jsonLogger{
+		out:   l.out,
+		level: l.level,
+		attrs: attrs.Bytes(),
+		now:   l.now,
+	}
+}
+
+func (l *jsonLogger) log(level Level, msg string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	var line bytes.Buffer
+	line.WriteString(`{"time":`)
+	appendValue(&line, l.now().UTC().Format(time.RFC3339Nano))
+	line.WriteString(`,"level":`)
+	appendValue(&line, level.String())
+	line.WriteString(`,"msg":`)
+	appendValue(&line, msg)
+	line.Write(l.attrs)
+	appendAttrs(&line, args)
+	line.WriteString("}\n")
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_, _ = l.out.w.Write(line.Bytes())
+}
+
+// appendAttrs writes the args as JSON fields. A key without value, or a value without a
+// string key, is written with the !BADKEY key
+func appendAttrs(b *bytes.Buffer, args []interface{}) {
+	for len(args) > 0 {
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			b.WriteString(`,"!BADKEY":`)
+			appendValue(b, args[0])
+			args = args[1:]
+			continue
+		}
+		b.WriteByte(',')
+		appendValue(b, key)
+		b.WriteByte(':')
+		appendValue(b, args[1])
+		args = args[2:]
+	}
+}
+
+func appendValue(b *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Duration:
+		value = v.String()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(data)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(os.Stderr, LevelInfo)
+)
+
+// Default returns the logger used when the context does not carry one
+func Default() Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the default logger
+func SetDefault(l Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of the context carrying the logger
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of the context, or the default one
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+			return l
+		}
+	}
+	return Default()
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	newLogger := func(level Level) (*jsonLogger, *bytes.Buffer) {
+		var out bytes.Buffer
+		l := New(&out, level).(*jsonLogger)
+		l.now = func() time.Time { return time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC) }
+		return l, &out
+	}
+
+	t.Run("log json line", func(t *testing.T) {
+		l, out := newLogger(LevelInfo)
+		id := uuid.MustParse("d70d0a95-af7f-4098-8d81-caca1934e94d")
+
+		l.With("request_id", "abc").Info("event processed", "account_id", id, "error", errors.New("test error"), "latency", time.Second)
+
+		assert.Equal(t, `{"time":"2023-01-01T12:00:00Z","level":"info","msg":"event processed","request_id":"abc",`+
+			`"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","error":"test error","latency":"1s"}`+"\n", out.String())
+	})
+	t.Run("log below level", func(t *testing.T) {
+		l, out := newLogger(LevelWarn)
+
+		l.Info("ignored")
+		l.Debug("ignored")
+		l.Error("failed")
+
+		assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+		assert.Contains(t, out.String(), `"level":"error"`)
+	})
+	t.Run("log bad keys", func(t *testing.T) {
+		l, out := newLogger(LevelInfo)
+
+		l.Info("message", 10, "key")
+
+		fields := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &fields))
+		assert.Equal(t, "key", fields["!BADKEY"])
+	})
+	t.Run("with does not change the parent", func(t *testing.T) {
+		l, out := newLogger(LevelInfo)
+
+		l.With("request_id", "abc")
+		l.Info("message")
+
+		assert.NotContains(t, out.String(), "request_id")
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, LevelWarn, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestContext(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, LevelInfo)
+
+	assert.Equal(t, l, FromContext(NewContext(context.Background(), l)))
+	assert.Equal(t, Default(), FromContext(context.Background()))
+}
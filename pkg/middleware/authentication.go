@@ -2,26 +2,115 @@ package middleware
 
 import (
 	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/lucaspichi06/xepelin-bank/internal/auth"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
 	"github.com/lucaspichi06/xepelin-bank/pkg/web"
-	"os"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Authentication manages the security by validating the token
-func Authentication() gin.HandlerFunc {
+// Context keys set by the authentication
+const (
+	PrincipalKey = "principal"
+	// SubjectKey is the sub claim of the bearer token, or the principal id of the API key
+	SubjectKey = "sub"
+	// ScopesKey are the scopes granted to the bearer token
+	ScopesKey = "scope"
+)
+
+// Authentication manages the security by validating the bearer token of the Authorization
+// header, or the API key of the token header when there is none, keeping the principal it
+// belongs to in the context. Bearer tokens are only accepted when a verifier is set
+func Authentication(s auth.Service, tokens auth.TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.GetHeader("TOKEN")
-		if token == "" {
-			web.Failure(c, 401, errors.New("token not found"))
-			c.Abort()
+		if header := c.GetHeader("Authorization"); header != "" && tokens != nil {
+			authenticateBearer(c, tokens, header)
 			return
 		}
-		if token != os.Getenv("TOKEN") {
-			web.Failure(c, 401, errors.New("invalid token"))
+
+		p, err := s.Authenticate(c.Request.Context(), c.GetHeader("TOKEN"))
+		if err != nil {
+			unauthorized(c, err)
+			return
+		}
+
+		c.Set(PrincipalKey, p)
+		c.Set(SubjectKey, p.ID.String())
+		c.Next()
+	}
+}
+
+func authenticateBearer(c *gin.Context, tokens auth.TokenVerifier, header string) {
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || strings.TrimSpace(parts[1]) == "" {
+		unauthorized(c, custom_errors.ErrInvalidToken)
+		return
+	}
+
+	claims, err := tokens.Verify(strings.TrimSpace(parts[1]))
+	if err != nil {
+		unauthorized(c, err)
+		return
+	}
+
+	c.Set(PrincipalKey, claims.Principal())
+	c.Set(SubjectKey, claims.Subject)
+	c.Set(ScopesKey, claims.Scopes)
+	c.Next()
+}
+
+func unauthorized(c *gin.Context, err error) {
+	status := http.StatusUnauthorized
+	if !errors.Is(err, custom_errors.ErrTokenNotFound) && !errors.Is(err, custom_errors.ErrInvalidToken) {
+		status = http.StatusInternalServerError
+	}
+	web.Failure(c, status, err)
+	c.Abort()
+}
+
+// RequireRole rejects the requests of the principals without any of the roles
+func RequireRole(roles ...domain.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := CurrentPrincipal(c)
+		if !ok || !p.HasRole(roles...) {
+			web.Failure(c, http.StatusForbidden, custom_errors.ErrForbidden)
 			c.Abort()
 			return
 		}
 		c.Next()
 	}
 }
+
+// RequireScope rejects the requests whose bearer token was not granted the scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, s := range Scopes(c) {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		web.Failure(c, http.StatusForbidden, custom_errors.ErrForbidden)
+		c.Abort()
+	}
+}
+
+// CurrentPrincipal returns the principal authenticated for the request
+func CurrentPrincipal(c *gin.Context) (domain.Principal, bool) {
+	p, ok := c.Value(PrincipalKey).(domain.Principal)
+	return p, ok
+}
+
+// Subject returns the subject authenticated for the request
+func Subject(c *gin.Context) string {
+	return c.GetString(SubjectKey)
+}
+
+// Scopes returns the scopes granted to the bearer token of the request
+func Scopes(c *gin.Context) []string {
+	return c.GetStringSlice(ScopesKey)
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/auth"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type authRepositoryMock struct {
+	findByKeyHash func(keyHash string) (domain.Principal, error)
+}
+
+func (a authRepositoryMock) Create(ctx context.Context, p domain.Principal, keyHash string) error {
+	return errors.New("not supported")
+}
+
+func (a authRepositoryMock) Grant(ctx context.Context, principalID, accountID uuid.UUID) error {
+	return errors.New("not supported")
+}
+
+func (a authRepositoryMock) FindByKeyHash(ctx context.Context, keyHash string) (domain.Principal, error) {
+	return a.findByKeyHash(keyHash)
+}
+
+type tokenVerifierMock struct {
+	verify func(token string) (auth.Claims, error)
+}
+
+func (v tokenVerifierMock) Verify(token string) (auth.Claims, error) {
+	return v.verify(token)
+}
+
+func TestAuthentication(t *testing.T) {
+	auditor := domain.Principal{ID: uuid.New(), Name: "auditor", Role: domain.RoleAuditor}
+	s := auth.NewService(authRepositoryMock{
+		findByKeyHash: func(keyHash string) (domain.Principal, error) {
+			return domain.Principal{}, custom_errors.ErrNotFound
+		},
+	}, "bootstrap-key")
+
+	send := func(token string, handlers ...gin.HandlerFunc) (*httptest.ResponseRecorder, domain.Principal) {
+		var principal domain.Principal
+		r := gin.Default()
+		handlers = append([]gin.HandlerFunc{Authentication(s, nil)}, handlers...)
+		r.GET("/test", append(handlers, func(c *gin.Context) {
+			principal, _ = CurrentPrincipal(c)
+			c.Status(http.StatusOK)
+		})...)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test", nil)
+		if err != nil {
+			t.Fail()
+		}
+		if token != "" {
+			req.Header.Set("TOKEN", token)
+		}
+		r.ServeHTTP(w, req)
+		return w, principal
+	}
+
+	t.Run("authentication success", func(t *testing.T) {
+		w, p := send("bootstrap-key")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, auth.BootstrapPrincipal, p)
+	})
+	t.Run("authentication without token", func(t *testing.T) {
+		w, _ := send("")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), custom_errors.ErrTokenNotFound.Error())
+	})
+	t.Run("authentication invalid token", func(t *testing.T) {
+		w, _ := send("unknown")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), custom_errors.ErrInvalidToken.Error())
+	})
+	t.Run("require role allowed", func(t *testing.T) {
+		w, _ := send("bootstrap-key", RequireRole(domain.RoleAdmin, domain.RoleAuditor))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+	t.Run("require role forbidden", func(t *testing.T) {
+		w, _ := send("bootstrap-key", func(c *gin.Context) {
+			c.Set(PrincipalKey, auditor)
+		}, RequireRole(domain.RoleAdmin))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestBearerAuthentication(t *testing.T) {
+	accountID := uuid.New()
+	s := auth.NewService(authRepositoryMock{}, "bootstrap-key")
+	tokens := tokenVerifierMock{
+		verify: func(token string) (auth.Claims, error) {
+			if token != "valid" {
+				return auth.Claims{}, custom_errors.ErrInvalidToken
+			}
+			return auth.Claims{
+				Subject:  "user-1",
+				Scopes:   []string{"accounts:read"},
+				Role:     domain.RoleAccountOwner,
+				Accounts: []uuid.UUID{accountID},
+			}, nil
+		},
+	}
+
+	type context struct {
+		principal domain.Principal
+		subject   string
+		scopes    []string
+	}
+	send := func(header http.Header, handlers ...gin.HandlerFunc) (*httptest.ResponseRecorder, context) {
+		var ctx context
+		r := gin.Default()
+		handlers = append([]gin.HandlerFunc{Authentication(s, tokens)}, handlers...)
+		r.GET("/test", append(handlers, func(c *gin.Context) {
+			ctx.principal, _ = CurrentPrincipal(c)
+			ctx.subject = Subject(c)
+			ctx.scopes = Scopes(c)
+			c.Status(http.StatusOK)
+		})...)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test", nil)
+		if err != nil {
+			t.Fail()
+		}
+		req.Header = header
+		r.ServeHTTP(w, req)
+		return w, ctx
+	}
+
+	t.Run("bearer token sets the claims", func(t *testing.T) {
+		w, ctx := send(http.Header{"Authorization": {"Bearer valid"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user-1", ctx.subject)
+		assert.Equal(t, []string{"accounts:read"}, ctx.scopes)
+		assert.Equal(t, domain.RoleAccountOwner, ctx.principal.Role)
+		assert.True(t, ctx.principal.CanOperate(accountID))
+	})
+	t.Run("invalid bearer tokens", func(t *testing.T) {
+		for _, header := range []string{"Bearer invalid", "Basic dXNlcjpwYXNz", "Bearer", "Bearer "} {
+			w, _ := send(http.Header{"Authorization": {header}})
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code, header)
+		}
+	})
+	t.Run("bearer token takes precedence over the api key", func(t *testing.T) {
+		w, _ := send(http.Header{"Authorization": {"Bearer invalid"}, "Token": {"bootstrap-key"}})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("api key still works", func(t *testing.T) {
+		w, ctx := send(http.Header{"Token": {"bootstrap-key"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, auth.BootstrapPrincipal.ID.String(), ctx.subject)
+		assert.Empty(t, ctx.scopes)
+	})
+	t.Run("require scope", func(t *testing.T) {
+		w, _ := send(http.Header{"Authorization": {"Bearer valid"}}, RequireScope("accounts:read"))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w, _ = send(http.Header{"Authorization": {"Bearer valid"}}, RequireScope("transactions:write"))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/idempotency"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// responseRecorder keeps a copy of the response body written by the handlers
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response of a request retried with the same
+// Idempotency-Key header, so it is processed only once. A key reused with a different
+// request is rejected. Keys are kept for the retention window, and requests failing with
+// a server error release their key so they can be retried
+func Idempotency(s idempotency.Store, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidIdempotencyKey)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			web.Failure(c, http.StatusBadRequest, custom_errors.ErrInvalidJSON)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		record, reserved, err := s.Reserve(c.Request.Context(), idempotency.Record{
+			Key:         key,
+			RequestHash: requestHash(c, body),
+			CreatedAt:   now,
+		}, now.Add(-retention))
+		if err != nil {
+			web.Failure(c, http.StatusInternalServerError, err)
+			c.Abort()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != "" && record.RequestHash != requestHash(c, body):
+				web.Failure(c, http.StatusUnprocessableEntity, custom_errors.ErrIdempotencyKeyReused)
+			case !record.Completed():
+				web.Failure(c, http.StatusConflict, custom_errors.ErrIdempotencyKeyInProgress)
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// the response is already written, so the key is stored even if the request timed out
+		ctx := detach(c.Request.Context())
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			err = s.Release(ctx, key)
+		} else {
+			err = s.Complete(ctx, key, c.Writer.Status(), recorder.body.Bytes())
+		}
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Error("idempotency key could not be stored", "idempotency_key", key, "error", err)
+		}
+	}
+}
+
+// requestHash identifies a request by its caller, method, path and body, so a key reused
+// by another principal is rejected instead of replaying a response meant for someone else
+func requestHash(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	if p, ok := CurrentPrincipal(c); ok {
+		h.Write([]byte(p.ID.String() + "\n"))
+	}
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
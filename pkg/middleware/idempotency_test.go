@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/idempotency"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]idempotency.Record{}}
+}
+
+func (m *memoryIdempotencyStore) Reserve(ctx context.Context, r idempotency.Record, expiredBefore time.Time) (idempotency.Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.records[r.Key]; ok && !existing.CreatedAt.Before(expiredBefore) {
+		return existing, false, nil
+	}
+	m.records[r.Key] = r
+	return r, true, nil
+}
+
+func (m *memoryIdempotencyStore) Complete(ctx context.Context, key string, statusCode int, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.records[key]
+	r.StatusCode, r.ResponseBody = statusCode, body
+	m.records[key] = r
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Purge(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotency(t *testing.T) {
+	newRouter := func(s idempotency.Store, retention time.Duration, status *int, calls *int) *gin.Engine {
+		r := gin.Default()
+		r.POST("/test", Idempotency(s, retention), func(c *gin.Context) {
+			*calls++
+			if *status >= http.StatusInternalServerError {
+				web.Failure(c, *status, errors.New("test error"))
+				return
+			}
+			web.Success(c, *status, *calls)
+		})
+		return r
+	}
+	send := func(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fail()
+		}
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("retried request replays the original response", func(t *testing.T) {
+		status, calls := http.StatusCreated, 0
+		r := newRouter(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+		first := send(r, "key", `{"amount":10}`)
+		retry := send(r, "key", `{"amount":10}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	})
+	t.Run("key reused with a different body", func(t *testing.T) {
+		status, calls := http.StatusCreated, 0
+		r := newRouter(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+		send(r, "key", `{"amount":10}`)
+		w := send(r, "key", `{"amount":20}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+	t.Run("key reused by another principal", func(t *testing.T) {
+		s := newMemoryIdempotencyStore()
+		calls := 0
+		newPrincipalRouter := func(p domain.Principal) *gin.Engine {
+			r := gin.Default()
+			r.POST("/test", func(c *gin.Context) { c.Set(PrincipalKey, p) }, Idempotency(s, time.Hour), func(c *gin.Context) {
+				calls++
+				web.Success(c, http.StatusCreated, p.Name)
+			})
+			return r
+		}
+
+		send(newPrincipalRouter(domain.Principal{ID: uuid.New(), Name: "first"}), "key", `{"amount":10}`)
+		w := send(newPrincipalRouter(domain.Principal{ID: uuid.New(), Name: "second"}), "key", `{"amount":10}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.NotContains(t, w.Body.String(), "first")
+	})
+	t.Run("key in progress", func(t *testing.T) {
+		s := newMemoryIdempotencyStore()
+		s.records["key"] = idempotency.Record{Key: "key", RequestHash: "", CreatedAt: time.Now().UTC()}
+		status, calls := http.StatusCreated, 0
+		r := newRouter(s, time.Hour, &status, &calls)
+
+		w := send(r, "key", `{"amount":10}`)
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+	t.Run("server errors release the key", func(t *testing.T) {
+		status, calls := http.StatusInternalServerError, 0
+		r := newRouter(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+		send(r, "key", `{"amount":10}`)
+		status = http.StatusCreated
+		w := send(r, "key", `{"amount":10}`)
+
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+	t.Run("expired key is processed again", func(t *testing.T) {
+		s := newMemoryIdempotencyStore()
+		status, calls := http.StatusCreated, 0
+		r := newRouter(s, time.Hour, &status, &calls)
+
+		send(r, "key", `{"amount":10}`)
+		expired := s.records["key"]
+		expired.CreatedAt = expired.CreatedAt.Add(-2 * time.Hour)
+		s.records["key"] = expired
+		send(r, "key", `{"amount":10}`)
+
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("requests without key are not deduplicated", func(t *testing.T) {
+		status, calls := http.StatusCreated, 0
+		r := newRouter(newMemoryIdempotencyStore(), time.Hour, &status, &calls)
+
+		send(r, "", `{"amount":10}`)
+		send(r, "", `{"amount":10}`)
+
+		assert.Equal(t, 2, calls)
+	})
+}
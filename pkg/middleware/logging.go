@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+)
+
+const (
+	// RequestIDHeader carries the id of the request, received from the caller or generated
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the context key of the request id
+	RequestIDKey = "request_id"
+
+	maxRequestIDLength = 128
+)
+
+// RequestID identifies the request with the X-Request-ID header it was sent with, or a
+// new one when it is missing or invalid, and echoes it in the response. The request
+// context carries a logger that adds the id to every line
+func RequestID(l logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), l.With(RequestIDKey, id)))
+		c.Next()
+	}
+}
+
+// AccessLog logs every request once it is served, with the logger of its context
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		args := []interface{}{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+		}
+		if p, ok := CurrentPrincipal(c); ok {
+			args = append(args, "principal_id", p.ID)
+		}
+
+		l := logger.FromContext(c.Request.Context())
+		switch {
+		case status >= http.StatusInternalServerError:
+			l.Error("request served", args...)
+		case status >= http.StatusBadRequest:
+			l.Warn("request served", args...)
+		default:
+			l.Info("request served", args...)
+		}
+	}
+}
+
+// validRequestID accepts the ids that are safe to log and echo: up to 128 letters,
+// digits and the - _ . : characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	serve := func(requestID string) (*httptest.ResponseRecorder, []map[string]interface{}) {
+		var out bytes.Buffer
+		r := gin.New()
+		r.Use(RequestID(logger.New(&out, logger.LevelInfo)), AccessLog())
+		r.GET("/test", func(c *gin.Context) {
+			logger.FromContext(c.Request.Context()).Info("handled")
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test", nil)
+		if err != nil {
+			t.Fail()
+		}
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		r.ServeHTTP(w, req)
+
+		var lines []map[string]interface{}
+		for _, data := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			line := make(map[string]interface{})
+			assert.NoError(t, json.Unmarshal([]byte(data), &line))
+			lines = append(lines, line)
+		}
+		return w, lines
+	}
+
+	t.Run("honor incoming request id", func(t *testing.T) {
+		w, lines := serve("req-123")
+
+		assert.Equal(t, "req-123", w.Header().Get(RequestIDHeader))
+		assert.Len(t, lines, 2)
+		for _, line := range lines {
+			assert.Equal(t, "req-123", line[RequestIDKey])
+		}
+		assert.Equal(t, "request served", lines[1]["msg"])
+		assert.Equal(t, "/test", lines[1]["route"])
+		assert.Equal(t, 200.0, lines[1]["status"])
+	})
+	t.Run("generate missing request id", func(t *testing.T) {
+		w, lines := serve("")
+
+		_, err := uuid.Parse(w.Header().Get(RequestIDHeader))
+		assert.NoError(t, err)
+		assert.Equal(t, w.Header().Get(RequestIDHeader), lines[0][RequestIDKey])
+	})
+	t.Run("replace invalid request id", func(t *testing.T) {
+		for _, id := range []string{"bad id\n", strings.Repeat("a", 129)} {
+			w, _ := serve(id)
+
+			_, err := uuid.Parse(w.Header().Get(RequestIDHeader))
+			assert.NoError(t, err, id)
+		}
+	})
+}
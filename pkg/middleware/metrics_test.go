@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// observations is the number of requests measured with the label values
+func observations(t *testing.T, labelValues ...string) uint64 {
+	var m dto.Metric
+	if err := requestDuration.WithLabelValues(labelValues...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMetrics(t *testing.T) {
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/test/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	matched, unmatched := observations(t, "GET", "/test/:id", "200"), observations(t, "GET", "unmatched", "404")
+	for _, url := range []string{"/test/1", "/test/2", "/other"} {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, matched+2, observations(t, "GET", "/test/:id", "200"))
+	assert.Equal(t, unmatched+1, observations(t, "GET", "unmatched", "404"))
+}
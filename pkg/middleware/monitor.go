@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/lucaspichi06/xepelin-bank/internal/monitoring"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+)
+
+// TransactionKey is the context key of the transaction processed by the request
+const TransactionKey = "transaction"
+
+// Monitor runs the monitoring rules on the transaction processed by the request. The
+// alerts never change the response: the transaction is already committed, so a failure
+// to evaluate the rules is only logged
+func Monitor(s monitoring.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Writer.Status() != http.StatusCreated {
+			return
+		}
+		tr, ok := c.Value(TransactionKey).(domain.Transaction)
+		if !ok {
+			return
+		}
+
+		l := logger.FromContext(c.Request.Context())
+		alerts, err := s.Evaluate(detach(c.Request.Context()), tr)
+		if err != nil {
+			l.Error("transaction could not be monitored", "transaction_id", tr.ID, "error", err)
+		}
+		for _, a := range alerts {
+			l.Warn("alert raised", "alert_id", a.ID, "rule", a.Rule, "account_id", a.AccountID, "transaction_id", a.TransactionID, "details", a.Details)
+		}
+	}
+}
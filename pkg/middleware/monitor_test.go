@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lucaspichi06/xepelin-bank/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type monitoringServiceMock struct {
+	evaluate func(tr domain.Transaction) ([]domain.Alert, error)
+}
+
+func (m monitoringServiceMock) Evaluate(ctx context.Context, tr domain.Transaction) ([]domain.Alert, error) {
+	return m.evaluate(tr)
+}
+
+func (m monitoringServiceMock) List(ctx context.Context, filter domain.AlertFilter) (domain.AlertPage, error) {
+	return domain.AlertPage{}, nil
+}
+
+func TestMonitor(t *testing.T) {
+	tr := domain.Transaction{ID: uuid.New(), AccountID: uuid.New(), Type: domain.Deposit, Amount: domain.NewMoney(2000000, "USD")}
+	serve := func(s monitoringServiceMock, status int) *httptest.ResponseRecorder {
+		r := gin.Default()
+		r.POST("/test", Monitor(s), func(c *gin.Context) {
+			if status == http.StatusCreated {
+				c.Set(TransactionKey, tr)
+			}
+			c.Status(status)
+		})
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/test", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("monitor processed transaction", func(t *testing.T) {
+		var evaluated domain.Transaction
+		w := serve(monitoringServiceMock{
+			evaluate: func(received domain.Transaction) ([]domain.Alert, error) {
+				evaluated = received
+				return []domain.Alert{{ID: uuid.New(), Rule: "large_deposit"}}, nil
+			},
+		}, http.StatusCreated)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, tr, evaluated)
+	})
+	t.Run("monitor failed request", func(t *testing.T) {
+		w := serve(monitoringServiceMock{
+			evaluate: func(domain.Transaction) ([]domain.Alert, error) {
+				t.Error("a failed request must not be evaluated")
+				return nil, nil
+			},
+		}, http.StatusBadRequest)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("monitor error keeps the response", func(t *testing.T) {
+		w := serve(monitoringServiceMock{
+			evaluate: func(domain.Transaction) ([]domain.Alert, error) {
+				return nil, errors.New("test error")
+			},
+		}, http.StatusCreated)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+}
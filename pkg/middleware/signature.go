@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/signing"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+)
+
+const (
+	SignatureKeyIDHeader     = "X-Signature-Key-Id"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	SignatureHeader          = "X-Signature"
+	maxNonceLength           = 128
+)
+
+// Signature verifies the HMAC-SHA256 signature of the request, computed with the secret of
+// the key id header over the method, path, timestamp, nonce and body. Requests whose
+// timestamp differs from the server clock by more than the tolerance are rejected, and so
+// are the nonces already used by the same key
+func Signature(keys signing.Keys, nonces signing.NonceStore, tolerance time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keyID := c.GetHeader(SignatureKeyIDHeader)
+		nonce := c.GetHeader(SignatureNonceHeader)
+		signature := c.GetHeader(SignatureHeader)
+		rawTimestamp := c.GetHeader(SignatureTimestampHeader)
+		if keyID == "" || nonce == "" || signature == "" || rawTimestamp == "" {
+			rejectSignature(c, http.StatusUnauthorized, custom_errors.ErrMissingSignature)
+			return
+		}
+		secret, ok := keys[keyID]
+		if !ok || len(nonce) > maxNonceLength {
+			rejectSignature(c, http.StatusUnauthorized, custom_errors.ErrInvalidSignature)
+			return
+		}
+		timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+		if err != nil {
+			rejectSignature(c, http.StatusUnauthorized, custom_errors.ErrInvalidSignature)
+			return
+		}
+		signedAt := time.Unix(timestamp, 0).UTC()
+		now := time.Now()
+		if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(tolerance)) {
+			rejectSignature(c, http.StatusUnauthorized, custom_errors.ErrStaleSignature)
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			rejectSignature(c, http.StatusBadRequest, custom_errors.ErrInvalidJSON)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !signing.Verify(secret, signature, c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body) {
+			rejectSignature(c, http.StatusUnauthorized, custom_errors.ErrInvalidSignature)
+			return
+		}
+
+		// the nonce is only checked once the signature is valid, so nobody else can burn it.
+		// It has to be remembered until its timestamp is no longer accepted
+		fresh, err := nonces.Use(c.Request.Context(), keyID+":"+nonce, signedAt.Add(tolerance))
+		if err != nil {
+			rejectSignature(c, http.StatusInternalServerError, err)
+			return
+		}
+		if !fresh {
+			rejectSignature(c, http.StatusUnauthorized, custom_errors.ErrReplayedRequest)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func rejectSignature(c *gin.Context, status int, err error) {
+	web.Failure(c, status, err)
+	c.Abort()
+}
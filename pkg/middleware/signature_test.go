@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/internal/signing"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	err    error
+}
+
+func (m *memoryNonceStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return false, m.err
+	}
+	if _, ok := m.nonces[nonce]; ok {
+		return false, nil
+	}
+	m.nonces[nonce] = expiresAt
+	return true, nil
+}
+
+func (m *memoryNonceStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestSignature(t *testing.T) {
+	keys := signing.Keys{"old": []byte("old-secret"), "new": []byte("new-secret")}
+	body := `{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"deposit","amount":10}`
+
+	type request struct {
+		keyID, secret, nonce, signature, body string
+		timestamp                             int64
+	}
+	signed := func(keyID, secret, nonce string) request {
+		return request{keyID: keyID, secret: secret, nonce: nonce, body: body, timestamp: time.Now().Unix()}
+	}
+	send := func(nonces signing.NonceStore, req request) (*httptest.ResponseRecorder, string) {
+		var received string
+		r := gin.Default()
+		r.POST("/test", Signature(keys, nonces, time.Minute), func(c *gin.Context) {
+			b, _ := c.GetRawData()
+			received = string(b)
+			c.Status(http.StatusCreated)
+		})
+
+		signature := req.signature
+		if signature == "" {
+			signature = signing.Sign([]byte(req.secret), "POST", "/test?source=batch", req.timestamp, req.nonce, []byte(body))
+		}
+		w := httptest.NewRecorder()
+		httpReq, err := http.NewRequest("POST", "/test?source=batch", bytes.NewBufferString(req.body))
+		if err != nil {
+			t.Fail()
+		}
+		httpReq.Header.Set(SignatureKeyIDHeader, req.keyID)
+		httpReq.Header.Set(SignatureTimestampHeader, strconv.FormatInt(req.timestamp, 10))
+		httpReq.Header.Set(SignatureNonceHeader, req.nonce)
+		httpReq.Header.Set(SignatureHeader, signature)
+		r.ServeHTTP(w, httpReq)
+		return w, received
+	}
+	newNonceStore := func() *memoryNonceStore {
+		return &memoryNonceStore{nonces: map[string]time.Time{}}
+	}
+
+	t.Run("signed with any active key", func(t *testing.T) {
+		nonces := newNonceStore()
+		for _, keyID := range []string{"old", "new"} {
+			w, received := send(nonces, signed(keyID, keyID+"-secret", "nonce-"+keyID))
+
+			assert.Equal(t, http.StatusCreated, w.Code, keyID)
+			assert.Equal(t, body, received)
+		}
+	})
+	t.Run("replayed nonce", func(t *testing.T) {
+		nonces := newNonceStore()
+		send(nonces, signed("new", "new-secret", "nonce"))
+		w, _ := send(nonces, signed("new", "new-secret", "nonce"))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), custom_errors.ErrReplayedRequest.Error())
+	})
+	t.Run("stale timestamps", func(t *testing.T) {
+		for _, offset := range []time.Duration{-2 * time.Minute, 2 * time.Minute} {
+			req := signed("new", "new-secret", "nonce")
+			req.timestamp = time.Now().Add(offset).Unix()
+
+			w, _ := send(newNonceStore(), req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Body.String(), custom_errors.ErrStaleSignature.Error())
+		}
+	})
+	t.Run("invalid signatures", func(t *testing.T) {
+		tampered := signed("new", "new-secret", "nonce")
+		tampered.body = `{"account_id":"d70d0a95-af7f-4098-8d81-caca1934e94d","type":"deposit","amount":10000}`
+		malformed := signed("new", "new-secret", "nonce")
+		malformed.signature = "not-hex"
+
+		for name, req := range map[string]request{
+			"wrong secret":  signed("new", "old-secret", "nonce"),
+			"unknown key":   signed("revoked", "revoked-secret", "nonce"),
+			"tampered body": tampered,
+			"malformed":     malformed,
+		} {
+			nonces := newNonceStore()
+			w, _ := send(nonces, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+			assert.Contains(t, w.Body.String(), custom_errors.ErrInvalidSignature.Error(), name)
+			assert.Empty(t, nonces.nonces, name)
+		}
+	})
+	t.Run("missing signature", func(t *testing.T) {
+		req := signed("new", "new-secret", "")
+
+		w, _ := send(newNonceStore(), req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), custom_errors.ErrMissingSignature.Error())
+	})
+	t.Run("nonce store error", func(t *testing.T) {
+		nonces := newNonceStore()
+		nonces.err = errors.New("test error")
+
+		w, _ := send(nonces, signed("new", "new-secret", "nonce"))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
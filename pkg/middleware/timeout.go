@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+)
+
+// DefaultTimeout is the time a request has to be processed when none is configured
+const DefaultTimeout = 10 * time.Second
+
+// Timeout cancels the context of the requests taking longer than d, so the statements they
+// run are cancelled and their transactions rolled back. The request is answered with a 504
+// when the handler did not write a response by then. A zero d disables it
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			web.Failure(c, http.StatusGatewayTimeout, custom_errors.ErrTimeout)
+		}
+	}
+}
+
+// detached keeps the values of a context, like its logger and span, without its deadline
+type detached struct {
+	context.Context
+}
+
+// detach returns a context for the work done once the response is written, which has to
+// finish even if the request timed out
+func detach(ctx context.Context) context.Context {
+	return detached{Context: ctx}
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	serve := func(d time.Duration, h gin.HandlerFunc) *httptest.ResponseRecorder {
+		r := gin.Default()
+		r.GET("/test", Timeout(d), h)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test", nil)
+		if err != nil {
+			t.Fail()
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	// wait blocks like a statement does until the request context is done
+	wait := func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	}
+
+	t.Run("answer slow request with a timeout", func(t *testing.T) {
+		w := serve(10*time.Millisecond, wait)
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		var res web.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, custom_errors.ErrTimeout.Error(), res.Message)
+	})
+	t.Run("map deadline errors to a timeout", func(t *testing.T) {
+		w := serve(10*time.Millisecond, func(c *gin.Context) {
+			wait(c)
+			web.Failure(c, http.StatusInternalServerError, c.Request.Context().Err())
+		})
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	})
+	t.Run("keep response written in time", func(t *testing.T) {
+		w := serve(time.Second, func(c *gin.Context) {
+			_, ok := c.Request.Context().Deadline()
+			assert.True(t, ok)
+			c.String(http.StatusOK, "ok")
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ok", w.Body.String())
+	})
+	t.Run("disabled timeout", func(t *testing.T) {
+		w := serve(0, func(c *gin.Context) {
+			_, ok := c.Request.Context().Deadline()
+			assert.False(t, ok)
+			c.Status(http.StatusNoContent)
+		})
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
+
+func TestDetach(t *testing.T) {
+	type key struct{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Millisecond)
+	cancel()
+
+	detached := detach(ctx)
+	assert.NoError(t, detached.Err())
+	assert.Nil(t, detached.Done())
+	_, ok := detached.Deadline()
+	assert.False(t, ok)
+	assert.Equal(t, "value", detached.Value(key{}))
+}
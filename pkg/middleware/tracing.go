@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tracerName is the instrumentation scope of the spans of the requests
+	tracerName = "github.com/lucaspichi06/xepelin-bank/pkg/middleware"
+	// traceresponseHeader returns the trace context of the request to the caller
+	traceresponseHeader = "traceresponse"
+)
+
+// Tracing traces every request as a span, continuing the trace the caller propagates in
+// its headers, W3C traceparent with the propagator set in main. The trace context of the
+// request is returned in the traceresponse header, and its trace id is added to the log
+// lines of the request
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := otel.Tracer(tracerName).Start(ctx, "HTTP "+c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("http.target", c.Request.URL.Path),
+			))
+		defer span.End()
+
+		sc := span.SpanContext()
+		ctx = logger.NewContext(ctx, logger.FromContext(ctx).With("trace_id", sc.TraceID().String()))
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(traceresponseHeader, fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+	}
+}
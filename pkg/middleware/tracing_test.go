@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	serve := func(traceparent string, status int) *httptest.ResponseRecorder {
+		exporter.Reset()
+		r := gin.New()
+		r.Use(Tracing())
+		r.GET("/test/:id", func(c *gin.Context) {
+			_, span := otel.Tracer("test").Start(c.Request.Context(), "child")
+			span.End()
+			c.Status(status)
+		})
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/test/1", nil)
+		if err != nil {
+			t.Fail()
+		}
+		if traceparent != "" {
+			req.Header.Set("traceparent", traceparent)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("trace new request", func(t *testing.T) {
+		w := serve("", http.StatusOK)
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, "HTTP GET /test/:id", spans[1].Name)
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+		assert.False(t, spans[1].Parent.IsValid())
+		assert.Contains(t, spans[1].Attributes, attribute.Int("http.status_code", 200))
+		assert.Equal(t, codes.Unset, spans[1].Status.Code)
+		sc := spans[1].SpanContext
+		assert.Equal(t, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-01", w.Header().Get(traceresponseHeader))
+	})
+	t.Run("continue incoming trace", func(t *testing.T) {
+		serve("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", http.StatusOK)
+
+		spans := exporter.GetSpans()
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[1].Parent.SpanID().String())
+		assert.True(t, spans[1].Parent.IsRemote())
+	})
+	t.Run("ignore invalid traceparent", func(t *testing.T) {
+		serve("invalid", http.StatusOK)
+
+		assert.False(t, exporter.GetSpans()[1].Parent.IsValid())
+	})
+	t.Run("record server error", func(t *testing.T) {
+		serve("", http.StatusInternalServerError)
+
+		status := exporter.GetSpans()[1].Status
+		assert.Equal(t, codes.Error, status.Code)
+		assert.Equal(t, "HTTP 500", status.Description)
+	})
+}
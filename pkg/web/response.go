@@ -1,9 +1,13 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	custom_errors "github.com/lucaspichi06/xepelin-bank/pkg/errors"
+	"github.com/lucaspichi06/xepelin-bank/pkg/logger"
 )
 
 type ErrorResponse struct {
@@ -22,7 +26,15 @@ func Success(ctx *gin.Context, status int, data interface{}) {
 	})
 }
 
+// Failure writes the error response. Server errors are logged, as their cause is not
+// something the caller can fix. Errors caused by the request deadline are timeouts
 func Failure(ctx *gin.Context, status int, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		status, err = http.StatusGatewayTimeout, custom_errors.ErrTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		logger.FromContext(ctx.Request.Context()).Error("request failed", "status", status, "error", err)
+	}
 	ctx.JSON(status, ErrorResponse{
 		Message: err.Error(),
 		Status:  status,